@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// step is one segment of a compiled JSONPath
+type step struct {
+	key       string
+	index     int
+	wildcard  bool
+	recursive bool
+	isIndex   bool
+}
+
+// Query evaluates a JSONPath against decoded JSON. The supported subset is
+// $ (root), .key, ['key'], [n] (negative counts from the end), .* and [*]
+// (all children) and ..key (recursive descent).
+func Query(path string, doc interface{}) ([]interface{}, error) {
+	steps, err := compile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{doc}
+	for _, s := range steps {
+		var next []interface{}
+		for _, v := range values {
+			if s.recursive {
+				for _, d := range descendants(v) {
+					next = append(next, s.apply(d)...)
+				}
+				continue
+			}
+			next = append(next, s.apply(v)...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// Validate reports whether path is in the supported JSONPath subset
+func Validate(path string) error {
+	_, err := compile(path)
+	return err
+}
+
+// compile parses a JSONPath into steps
+func compile(path string) ([]step, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", path)
+	}
+
+	var steps []step
+	rest := path[1:]
+	for rest != "" {
+		recursive := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "["):
+		default:
+			return nil, fmt.Errorf("JSONPath %q: unexpected %q", path, rest)
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q: unclosed [", path)
+			}
+			s, err := bracket(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("JSONPath %q: %w", path, err)
+			}
+			s.recursive = recursive
+			steps = append(steps, s)
+			rest = rest[end+1:]
+			continue
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		if name == "" {
+			return nil, fmt.Errorf("JSONPath %q: empty key", path)
+		}
+		steps = append(steps, step{key: name, wildcard: name == "*", recursive: recursive})
+		rest = rest[end:]
+	}
+	return steps, nil
+}
+
+// bracket parses the inside of [...]: *, an index or a quoted key
+func bracket(inner string) (step, error) {
+	inner = strings.TrimSpace(inner)
+	switch {
+	case inner == "*":
+		return step{wildcard: true}, nil
+	case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+		return step{key: inner[1 : len(inner)-1]}, nil
+	}
+	i, err := strconv.Atoi(inner)
+	if err != nil {
+		return step{}, fmt.Errorf("unsupported selector [%s]", inner)
+	}
+	return step{index: i, isIndex: true}, nil
+}
+
+// apply selects the children of v matched by the step
+func (s step) apply(v interface{}) []interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			keys := make([]string, 0, len(node))
+			for k := range node {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			out := make([]interface{}, 0, len(keys))
+			for _, k := range keys {
+				out = append(out, node[k])
+			}
+			return out
+		}
+		if child, ok := node[s.key]; ok && !s.isIndex {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if s.wildcard {
+			return node
+		}
+		if s.isIndex {
+			i := s.index
+			if i < 0 {
+				i += len(node)
+			}
+			if i >= 0 && i < len(node) {
+				return []interface{}{node[i]}
+			}
+		}
+	}
+	return nil
+}
+
+// descendants returns v and everything below it, depth first
+func descendants(v interface{}) []interface{} {
+	out := []interface{}{v}
+	switch node := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(node))
+		for k := range node {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, descendants(node[k])...)
+		}
+	case []interface{}:
+		for _, c := range node {
+			out = append(out, descendants(c)...)
+		}
+	}
+	return out
+}
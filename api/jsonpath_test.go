@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const eventsJSON = `{
+	"status": "ok",
+	"count": 3,
+	"events": [
+		{"id": 1, "name": "Arsenal v Chelsea", "odds": {"home": 2.1, "away": 3.4}},
+		{"id": 2, "name": "Inter v Milan", "odds": {"home": 1.9, "away": 4.0}},
+		{"id": 3, "name": "Ajax v PSV", "odds": null}
+	],
+	"meta": {"region": "uk", "tags": ["live", "football"]}
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestQuery(t *testing.T) {
+	doc := decode(t, eventsJSON)
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"$", []interface{}{doc}},
+		{"$.status", []interface{}{"ok"}},
+		{"$['status']", []interface{}{"ok"}},
+		{`$["meta"].region`, []interface{}{"uk"}},
+		{"$.events[0].id", []interface{}{1.0}},
+		{"$.events[-1].name", []interface{}{"Ajax v PSV"}},
+		{"$.events[5]", nil},
+		{"$.events[*].id", []interface{}{1.0, 2.0, 3.0}},
+		{"$.events.*.id", []interface{}{1.0, 2.0, 3.0}},
+		{"$.meta.*", []interface{}{"uk", []interface{}{"live", "football"}}},
+		{"$..home", []interface{}{2.1, 1.9}},
+		{"$..tags[1]", []interface{}{"football"}},
+		{"$.events[2].odds", []interface{}{nil}},
+		{"$.missing", nil},
+		{"$.status.deeper", nil},
+		{"$.events.id", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := Query(tt.path, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query(%s) = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"$.events[0].odds.home", ""},
+		{" $..id ", ""},
+		{"events", `JSONPath "events" must start with $`},
+		{"$events", `JSONPath "$events": unexpected "events"`},
+		{"$.events[0", `JSONPath "$.events[0": unclosed [`},
+		{"$.events[?(@.id>1)]", `JSONPath "$.events[?(@.id>1)]": unsupported selector [?(@.id>1)]`},
+		{"$.events[0:2]", `JSONPath "$.events[0:2]": unsupported selector [0:2]`},
+		{"$.a..", `JSONPath "$.a..": empty key`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := Validate(tt.path)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.err {
+				t.Errorf("Validate(%q) = %q, want %q", tt.path, got, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"diago/config"
+	"diago/report"
+)
+
+// MaxBodySize bounds how much of a probe response is read
+const MaxBodySize = 5 << 20
+
+// Run sends a probe through client, until ctx is done, and checks its
+// assertions. The result carries the response schema so callers can detect
+// field drift.
+func Run(ctx context.Context, client *http.Client, cfg *config.Sportsbook, probe config.APIProbe) report.SelectorResult {
+	r := report.SelectorResult{Label: probe.Label(), Status: "❌"}
+
+	method := strings.ToUpper(probe.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	url := cfg.Expand(probe.URL)
+
+	var body io.Reader
+	if probe.Body != "" {
+		body = strings.NewReader(cfg.Expand(probe.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		r.Message = fmt.Sprintf("failed to create request: %v", err)
+		return r
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range probe.Headers {
+		req.Header.Set(k, cfg.Expand(v))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		r.Message = fmt.Sprintf("request failed: %v", err)
+		return r
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		r.Message = fmt.Sprintf("received HTTP %d for %s %s", resp.StatusCode, method, url)
+		return r
+	}
+
+	// One byte past the limit tells a truncated body from one that fits
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxBodySize+1))
+	if err != nil {
+		r.Message = fmt.Sprintf("failed to read response: %v", err)
+		return r
+	}
+	if len(data) > MaxBodySize {
+		r.Message = fmt.Sprintf("response is larger than %d bytes", MaxBodySize)
+		return r
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		r.Message = fmt.Sprintf("response is not JSON: %v", err)
+		return r
+	}
+
+	r.Schema = Schema(doc)
+
+	var failures []string
+	for _, a := range probe.Assert {
+		failures = append(failures, Check(doc, a)...)
+	}
+	if len(failures) > 0 {
+		r.Message = strings.Join(failures, "; ")
+		return r
+	}
+
+	r.Status = "✅"
+	r.Message = fmt.Sprintf("%s %s: %d assertions passed", method, url, len(probe.Assert))
+	return r
+}
+
+// Check evaluates one assertion and returns a message for every failure
+func Check(doc interface{}, a config.JSONAssertion) []string {
+	values, err := Query(a.Path, doc)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	if a.Exists != nil && !*a.Exists {
+		if len(values) > 0 {
+			return []string{fmt.Sprintf("%s: expected no value, got %d", a.Path, len(values))}
+		}
+		return nil
+	}
+	if len(values) == 0 {
+		return []string{fmt.Sprintf("%s: not found", a.Path)}
+	}
+
+	var failures []string
+	count := len(values)
+	if arr, ok := values[0].([]interface{}); ok && len(values) == 1 {
+		count = len(arr)
+	}
+	if a.MinCount != nil && count < *a.MinCount {
+		failures = append(failures, fmt.Sprintf("%s: expected at least %d, got %d", a.Path, *a.MinCount, count))
+	}
+	if a.MaxCount != nil && count > *a.MaxCount {
+		failures = append(failures, fmt.Sprintf("%s: expected at most %d, got %d", a.Path, *a.MaxCount, count))
+	}
+
+	for i, v := range values {
+		// Keep messages short on large arrays
+		if len(failures) >= 5 {
+			break
+		}
+		at := a.Path
+		if len(values) > 1 {
+			at = fmt.Sprintf("%s #%d", a.Path, i+1)
+		}
+		if a.Type != "" && TypeOf(v) != a.Type {
+			failures = append(failures, fmt.Sprintf("%s: expected %s, got %s", at, a.Type, TypeOf(v)))
+			continue
+		}
+		if a.Min != nil || a.Max != nil {
+			n, ok := v.(float64)
+			switch {
+			case !ok:
+				failures = append(failures, fmt.Sprintf("%s: expected a number for range check, got %s", at, TypeOf(v)))
+			case a.Min != nil && n < *a.Min:
+				failures = append(failures, fmt.Sprintf("%s: %v is below %v", at, n, *a.Min))
+			case a.Max != nil && n > *a.Max:
+				failures = append(failures, fmt.Sprintf("%s: %v is above %v", at, n, *a.Max))
+			}
+		}
+		if a.Equals != "" && fmt.Sprint(v) != a.Equals {
+			failures = append(failures, fmt.Sprintf("%s: %v != %s", at, v, a.Equals))
+		}
+	}
+	return failures
+}
+
+// TypeOf names a decoded JSON value's type: object, array, string, number, boolean or null
+func TypeOf(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
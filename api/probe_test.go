@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"diago/config"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestCheck(t *testing.T) {
+	doc := decode(t, eventsJSON)
+	tests := []struct {
+		name string
+		a    config.JSONAssertion
+		want []string
+	}{
+		{"exists", config.JSONAssertion{Path: "$.status"}, nil},
+		{"not found", config.JSONAssertion{Path: "$.missing"}, []string{"$.missing: not found"}},
+		{"absent", config.JSONAssertion{Path: "$.missing", Exists: ptr(false)}, nil},
+		{"unexpectedly present", config.JSONAssertion{Path: "$.events[*]", Exists: ptr(false)}, []string{"$.events[*]: expected no value, got 3"}},
+		{"array length", config.JSONAssertion{Path: "$.events", MinCount: ptr(3), MaxCount: ptr(3)}, nil},
+		{"too few", config.JSONAssertion{Path: "$.events", MinCount: ptr(5)}, []string{"$.events: expected at least 5, got 3"}},
+		{"too many values", config.JSONAssertion{Path: "$..id", MaxCount: ptr(2)}, []string{"$..id: expected at most 2, got 3"}},
+		{"type", config.JSONAssertion{Path: "$.events[*].name", Type: "string"}, nil},
+		{"wrong type", config.JSONAssertion{Path: "$.count", Type: "string"}, []string{"$.count: expected string, got number"}},
+		{"types per value", config.JSONAssertion{Path: "$.events[*].odds", Type: "object"}, []string{"$.events[*].odds #3: expected object, got null"}},
+		{"range", config.JSONAssertion{Path: "$..home", Min: ptr(1.5), Max: ptr(2.5)}, nil},
+		{"below", config.JSONAssertion{Path: "$..home", Min: ptr(2.0)}, []string{"$..home #2: 1.9 is below 2"}},
+		{"above", config.JSONAssertion{Path: "$..away", Max: ptr(3.5)}, []string{"$..away #2: 4 is above 3.5"}},
+		{"range on text", config.JSONAssertion{Path: "$.status", Min: ptr(1.0)}, []string{"$.status: expected a number for range check, got string"}},
+		{"equals", config.JSONAssertion{Path: "$.meta.region", Equals: "uk"}, nil},
+		{"equals number", config.JSONAssertion{Path: "$.count", Equals: "3"}, nil},
+		{"differs", config.JSONAssertion{Path: "$.meta.region", Equals: "de"}, []string{"$.meta.region: uk != de"}},
+		{"invalid path", config.JSONAssertion{Path: "events"}, []string{`JSONPath "events" must start with $`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(doc, tt.a)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Check = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCapsMessages(t *testing.T) {
+	doc := decode(t, `{"odds": [1, 2, 3, 4, 5, 6, 7, 8]}`)
+	got := Check(doc, config.JSONAssertion{Path: "$.odds[*]", Type: "string"})
+	if len(got) != 5 {
+		t.Errorf("got %d messages, want 5: %q", len(got), got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/events":
+			if req.Header.Get("X-Region") != "uk" {
+				http.Error(w, "missing region", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, eventsJSON)
+		case "/api/html":
+			fmt.Fprint(w, "<html></html>")
+		case "/api/large":
+			fmt.Fprintf(w, `{"pad": "%s"}`, strings.Repeat("x", MaxBodySize))
+		case "/api/slow":
+			select {
+			case <-req.Context().Done():
+			case <-time.After(time.Second):
+				fmt.Fprint(w, "{}")
+			}
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer srv.Close()
+	cfg := &config.Sportsbook{Name: "betway", BaseURL: srv.URL, Region: "uk"}
+	events := []config.JSONAssertion{{Path: "$.events", MinCount: ptr(1)}}
+
+	tests := []struct {
+		name    string
+		probe   config.APIProbe
+		status  string
+		message string
+	}{
+		{"pass", config.APIProbe{Name: "events", URL: "{{base_url}}/api/events", Headers: map[string]string{"X-Region": "{{region}}"}, Assert: events},
+			"✅", "GET " + srv.URL + "/api/events: 1 assertions passed"},
+		{"assertion fails", config.APIProbe{Name: "events", URL: "{{base_url}}/api/events", Headers: map[string]string{"X-Region": "uk"}, Assert: []config.JSONAssertion{{Path: "$.count", Equals: "4"}}},
+			"❌", "$.count: 3 != 4"},
+		{"http error", config.APIProbe{Name: "events", URL: "{{base_url}}/api/events"},
+			"❌", "received HTTP 400 for GET " + srv.URL + "/api/events"},
+		{"not json", config.APIProbe{Name: "html", URL: "{{base_url}}/api/html"},
+			"❌", "response is not JSON: invalid character '<' looking for beginning of value"},
+		{"too large", config.APIProbe{Name: "large", URL: "{{base_url}}/api/large"},
+			"❌", fmt.Sprintf("response is larger than %d bytes", MaxBodySize)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Run(context.Background(), srv.Client(), cfg, tt.probe)
+			if r.Status != tt.status || r.Message != tt.message {
+				t.Errorf("Run = %s %q, want %s %q", r.Status, r.Message, tt.status, tt.message)
+			}
+			if r.Label != tt.probe.Label() {
+				t.Errorf("Label = %q", r.Label)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		r := Run(ctx, srv.Client(), cfg, config.APIProbe{Name: "slow", URL: "{{base_url}}/api/slow"})
+		if r.Status != "❌" || !strings.Contains(r.Message, "context deadline exceeded") {
+			t.Errorf("Run = %s %q, want a cancelled request", r.Status, r.Message)
+		}
+		if time.Since(start) > 500*time.Millisecond {
+			t.Errorf("cancelled probe took %v", time.Since(start))
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"diago/report"
+)
+
+// Schema maps every field path of a JSON document to its type. Array
+// elements collapse into [*] and fields with mixed types list them all,
+// e.g. "$.events[*].score": "null|number".
+func Schema(doc interface{}) map[string]string {
+	types := map[string]map[string]bool{}
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		if types[path] == nil {
+			types[path] = map[string]bool{}
+		}
+		types[path][TypeOf(v)] = true
+
+		switch node := v.(type) {
+		case map[string]interface{}:
+			for k, c := range node {
+				walk(path+"."+k, c)
+			}
+		case []interface{}:
+			for _, c := range node {
+				walk(path+"[*]", c)
+			}
+		}
+	}
+	walk("$", doc)
+
+	schema := map[string]string{}
+	for path, set := range types {
+		var names []string
+		for t := range set {
+			names = append(names, t)
+		}
+		sort.Strings(names)
+		schema[path] = strings.Join(names, "|")
+	}
+	return schema
+}
+
+// CompareSchemas lists the fields added, removed and changed in type since old
+func CompareSchemas(probe string, old, current map[string]string) report.SchemaDrift {
+	d := report.SchemaDrift{Probe: probe}
+	for path, t := range current {
+		prev, ok := old[path]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, path)
+		case prev != t:
+			d.Changed = append(d.Changed, fmt.Sprintf("%s: %s → %s", path, prev, t))
+		}
+	}
+	for path := range old {
+		if _, ok := current[path]; !ok {
+			d.Removed = append(d.Removed, path)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// SchemaPath returns where a probe's last schema is stored
+func SchemaPath(outputDir, bookie, probe string) string {
+	return filepath.Join(outputDir, strings.ToLower(bookie), "api", probe+".schema.json")
+}
+
+// RecordSchema stores a probe's schema and returns the previously stored
+// one, or nil on the first run
+func RecordSchema(path string, schema map[string]string) (map[string]string, error) {
+	var prev map[string]string
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &prev); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return prev, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return prev, err
+	}
+	return prev, os.WriteFile(path, data, 0644)
+}
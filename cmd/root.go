@@ -3,7 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"diago/config"
 	"diago/fetch"
 	"diago/report"
 	"diago/utils"
@@ -27,15 +30,25 @@ var rootCmd = &cobra.Command{
 			os.Exit(0)
 		}
 
-		// Run verification for each bookie
+		// Run verification for each bookie using its generated config
 		var reports []report.BookieReport
 		for _, b := range enabled {
-			r := fetch.VerifyBookieWithConfig(b)
+			cfg, err := config.Load(filepath.Join("EMC", strings.ToLower(b.Name()), "config.yaml"))
+			if err != nil {
+				fmt.Printf("⚠️ Skipping %s, failed to load config: %v\n", b.Name(), err)
+				continue
+			}
+			r := fetch.VerifyBookieWithConfig(cfg.Name, cfg.BaseURL, cfg)
 			reports = append(reports, r)
 		}
 
 		// Save using the report package
-		if err := report.SaveReports(".", reports); err != nil {
+		full := report.FullReport{Summary: reports, Details: reports}
+		if err := report.SaveJSON(full, "report.json"); err != nil {
+			fmt.Println("❌ Error saving reports:", err)
+			os.Exit(1)
+		}
+		if err := report.SaveMarkdown(full, "report.md"); err != nil {
 			fmt.Println("❌ Error saving reports:", err)
 			os.Exit(1)
 		}
@@ -51,4 +64,3 @@ func Execute() {
 		os.Exit(1)
 	}
 }
-
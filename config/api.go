@@ -0,0 +1,56 @@
+package config
+
+import "strings"
+
+// APIProbe is a JSON API request checked alongside the CSS selectors:
+//
+//	api_probes:
+//	  - name: live_events
+//	    url: "{{base_url}}/api/events?live=1&region={{region}}"
+//	    headers: {Accept: application/json}
+//	    assert:
+//	      - path: $.data.events
+//	        type: array
+//	        min_count: 1
+//	      - path: $.data.events[*].odds.home
+//	        type: number
+//	        min: 1.01
+type APIProbe struct {
+	Name     string            `yaml:"name"`
+	Method   string            `yaml:"method,omitempty"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Body     string            `yaml:"body,omitempty"`
+	Severity Severity          `yaml:"severity,omitempty"`
+	Assert   []JSONAssertion   `yaml:"assert,omitempty"`
+}
+
+// JSONAssertion checks the values a JSONPath selects. Every assertion
+// requires at least one value unless Exists is false, which requires none.
+// Type, Min, Max and Equals apply to every selected value; the counts apply
+// to the length of a single selected array, or else to the number of values.
+type JSONAssertion struct {
+	Path     string   `yaml:"path"`
+	Exists   *bool    `yaml:"exists,omitempty"`
+	Type     string   `yaml:"type,omitempty"`
+	MinCount *int     `yaml:"min_count,omitempty"`
+	MaxCount *int     `yaml:"max_count,omitempty"`
+	Min      *float64 `yaml:"min,omitempty"`
+	Max      *float64 `yaml:"max,omitempty"`
+	Equals   string   `yaml:"equals,omitempty"`
+}
+
+// Label is how the probe appears in reports, e.g. "API.live_events"
+func (p APIProbe) Label() string {
+	return "API." + p.Name
+}
+
+// Expand fills the {{base_url}}, {{region}} and {{name}} placeholders of a
+// probe's URL, headers or body
+func (sb *Sportsbook) Expand(s string) string {
+	return strings.NewReplacer(
+		"{{base_url}}", strings.TrimSuffix(sb.BaseURL, "/"),
+		"{{region}}", sb.Region,
+		"{{name}}", sb.Name,
+	).Replace(s)
+}
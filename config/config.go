@@ -1,6 +1,11 @@
 package config
 
-import "gopkg.in/yaml.v3"
+import (
+	"bytes"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
 
 // Sportsbook represents a single bookie's configuration
 type Sportsbook struct {
@@ -11,148 +16,162 @@ type Sportsbook struct {
 	Password        string          `yaml:"password"`
 	Region          string          `yaml:"region"`
 	Selectors       Selectors       `yaml:"selectors"`
-	BetButton       string          `yaml:"bet_button"`
-	BetHistory      string          `yaml:"bet_history"`
+	BetButton       Selector        `yaml:"bet_button"`
+	BetHistory      Selector        `yaml:"bet_history"`
 	Timeout         Timeout         `yaml:"timeout"`
 	Betting         Betting         `yaml:"betting"`
 	UserCredentials UserCredentials `yaml:"user_credentials"`
+
+	// Capabilities marks selector sections (by YAML key, e.g. live_betting)
+	// the bookie does not offer; their selectors are reported as n/a
+	Capabilities map[string]bool `yaml:"capabilities,omitempty"`
+
+	// Frames limits which iframes are fetched and searched
+	Frames Frames `yaml:"frames,omitempty"`
+
+	// APIProbes are JSON endpoints verified alongside the selectors
+	APIProbes []APIProbe `yaml:"api_probes,omitempty"`
+
+	// Devices are profiles such as mobile the bookie is verified as, in
+	// addition to the desktop base config
+	Devices map[string]Device `yaml:"devices,omitempty"`
 }
 
 // Selectors holds CSS selectors for login, event search, and odds
 type Selectors struct {
 	Login struct {
-		UsernameInput string `yaml:"username_input"`
-		PasswordInput string `yaml:"password_input"`
-		LoginButton   string `yaml:"login_button"`
-		
-	        Modal struct {
-	            Enabled     bool   `yaml:"enabled"`
-        	    Selector    string `yaml:"selector"`
-        	    CloseButton string `yaml:"close_button"`
-        	} `yaml:"modal"`
-
-		OtpInput        string `yaml:"otp_input"`
-		OtpSubmitButton string `yaml:"otp_submit_button"`
+		UsernameInput Selector `yaml:"username_input"`
+		PasswordInput Selector `yaml:"password_input"`
+		LoginButton   Selector `yaml:"login_button"`
+
+		Modal struct {
+			Enabled     bool     `yaml:"enabled"`
+			Selector    Selector `yaml:"selector"`
+			CloseButton Selector `yaml:"close_button"`
+		} `yaml:"modal"`
+
+		OtpInput        Selector `yaml:"otp_input"`
+		OtpSubmitButton Selector `yaml:"otp_submit_button"`
 	} `yaml:"login"`
 
-	Dashboard string `yaml:"dashboard"`
+	Dashboard Selector `yaml:"dashboard"`
 
 	UserMenu struct {
-		LogoutButton string `yaml:"logout_button"`
-		AccountLink  string `yaml:"account_link"`
+		LogoutButton Selector `yaml:"logout_button"`
+		AccountLink  Selector `yaml:"account_link"`
 	} `yaml:"user_menu"`
 
 	AccountForm struct {
-		EmailInput string `yaml:"email_input"`
-		SaveButton string `yaml:"save_button"`
+		EmailInput Selector `yaml:"email_input"`
+		SaveButton Selector `yaml:"save_button"`
 	} `yaml:"account_form"`
 
 	Session struct {
-		LogoutButton    string `yaml:"logout_button"`
-		SessionUserInfo string `yaml:"session_user_info"`
+		LogoutButton    Selector `yaml:"logout_button"`
+		SessionUserInfo Selector `yaml:"session_user_info"`
 	} `yaml:"session"`
 
 	EventSearch struct {
-		SportDropdown string `yaml:"sport_dropdown"`
-		DatePicker    string `yaml:"date_picker"`
-		SearchButton  string `yaml:"search_button"`
-		EventResults  string `yaml:"event_results"`
-		EventItem     string `yaml:"event_item"`
-		EventTitle    string `yaml:"event_title"`
-		EventTeam     string `yaml:"event_team"`
+		SportDropdown Selector `yaml:"sport_dropdown"`
+		DatePicker    Selector `yaml:"date_picker"`
+		SearchButton  Selector `yaml:"search_button"`
+		EventResults  Selector `yaml:"event_results"`
+		EventItem     Selector `yaml:"event_item"`
+		EventTitle    Selector `yaml:"event_title"`
+		EventTeam     Selector `yaml:"event_team"`
 	} `yaml:"event_search"`
 
 	OddsSelector struct {
-		Moneyline    string `yaml:"moneyline"`
-		Spread       string `yaml:"spread"`
-		Totals       string `yaml:"totals"`
-		OddsDropdown string `yaml:"odds_dropdown"`
+		Moneyline    Selector `yaml:"moneyline"`
+		Spread       Selector `yaml:"spread"`
+		Totals       Selector `yaml:"totals"`
+		OddsDropdown Selector `yaml:"odds_dropdown"`
 	} `yaml:"odds_selector"`
 
 	BetSlip struct {
-		AddButton       string `yaml:"add_button"`
-		RemoveButton    string `yaml:"remove_button"`
-		StakeInput      string `yaml:"stake_input"`
-		CalculateButton string `yaml:"calculate_button"`
-		ClearButton     string `yaml:"clear_button"`
-		PotentialPayout string `yaml:"potential_payout"`
-		BetSlipItem     string `yaml:"bet_slip_item"`
+		AddButton       Selector `yaml:"add_button"`
+		RemoveButton    Selector `yaml:"remove_button"`
+		StakeInput      Selector `yaml:"stake_input"`
+		CalculateButton Selector `yaml:"calculate_button"`
+		ClearButton     Selector `yaml:"clear_button"`
+		PotentialPayout Selector `yaml:"potential_payout"`
+		BetSlipItem     Selector `yaml:"bet_slip_item"`
 	} `yaml:"bet_slip"`
 
 	LiveBetting struct {
-		LiveBettingButton   string `yaml:"live_betting_button"`
-		OddsChangeIndicator string `yaml:"odds_change_indicator"`
-		LiveEventItem       string `yaml:"live_event_item"`
-		LiveScore           string `yaml:"live_score"`
-		LiveOddSelector     string `yaml:"live_odd_selector"`
-		LiveEvent           string `yaml:"live_event"`
-		InPlayBetButton     string `yaml:"in_play_bet_button"`
+		LiveBettingButton   Selector `yaml:"live_betting_button"`
+		OddsChangeIndicator Selector `yaml:"odds_change_indicator"`
+		LiveEventItem       Selector `yaml:"live_event_item"`
+		LiveScore           Selector `yaml:"live_score"`
+		LiveOddSelector     Selector `yaml:"live_odd_selector"`
+		LiveEvent           Selector `yaml:"live_event"`
+		InPlayBetButton     Selector `yaml:"in_play_bet_button"`
 	} `yaml:"live_betting"`
 
 	LineMovement struct {
-		LineChangeIndicator string `yaml:"line_change_indicator"`
-		OddsHistory         string `yaml:"odds_history"`
-		BettingLines        string `yaml:"betting_lines"`
+		LineChangeIndicator Selector `yaml:"line_change_indicator"`
+		OddsHistory         Selector `yaml:"odds_history"`
+		BettingLines        Selector `yaml:"betting_lines"`
 	} `yaml:"line_movement"`
 
 	FilterOptions struct {
-		SportDropdown      string `yaml:"sport_dropdown"`
-		MarketTypeDropdown string `yaml:"market_type_dropdown"`
-		TimeFilter         string `yaml:"time_filter"`
-		ResetFiltersButton string `yaml:"reset_filters_button"`
+		SportDropdown      Selector `yaml:"sport_dropdown"`
+		MarketTypeDropdown Selector `yaml:"market_type_dropdown"`
+		TimeFilter         Selector `yaml:"time_filter"`
+		ResetFiltersButton Selector `yaml:"reset_filters_button"`
 	} `yaml:"filter_options"`
 
 	BalanceTracker struct {
-		BalancePageLink         string `yaml:"balance_page_link"`
-		BalanceContainer        string `yaml:"balance_container"`
-		CurrentBalanceField     string `yaml:"current_balance_field"`
-		AvailableBalanceField   string `yaml:"available_balance_field"`
-		PendingWithdrawalsField string `yaml:"pending_withdrawals_field"`
-		TransactionRowSelector  string `yaml:"transaction_row_selector"`
-		TransactionTypeColumn   string `yaml:"transaction_type_column"`
-		TransactionAmountColumn string `yaml:"transaction_amount_column"`
-		TransactionDateColumn   string `yaml:"transaction_date_column"`
-		FilterByType            string `yaml:"filter_by_type"`
-		FilterByDate            string `yaml:"filter_by_date"`
+		BalancePageLink         Selector `yaml:"balance_page_link"`
+		BalanceContainer        Selector `yaml:"balance_container"`
+		CurrentBalanceField     Selector `yaml:"current_balance_field"`
+		AvailableBalanceField   Selector `yaml:"available_balance_field"`
+		PendingWithdrawalsField Selector `yaml:"pending_withdrawals_field"`
+		TransactionRowSelector  Selector `yaml:"transaction_row_selector"`
+		TransactionTypeColumn   Selector `yaml:"transaction_type_column"`
+		TransactionAmountColumn Selector `yaml:"transaction_amount_column"`
+		TransactionDateColumn   Selector `yaml:"transaction_date_column"`
+		FilterByType            Selector `yaml:"filter_by_type"`
+		FilterByDate            Selector `yaml:"filter_by_date"`
 	} `yaml:"balance_tracker"`
 
 	BetConfirmation struct {
-		ConfirmButton  string `yaml:"confirm_button"`
-		ErrorMessage   string `yaml:"error_message"`
-		SuccessMessage string `yaml:"success_message"`
-		BetSummary     string `yaml:"bet_summary"`
+		ConfirmButton  Selector `yaml:"confirm_button"`
+		ErrorMessage   Selector `yaml:"error_message"`
+		SuccessMessage Selector `yaml:"success_message"`
+		BetSummary     Selector `yaml:"bet_summary"`
 	} `yaml:"bet_confirmation"`
 
 	BetHistory struct {
-		HistoryPageLink string `yaml:"history_page_link"`
-		BetRowSelector  string `yaml:"bet_row_selector"`
-		EventColumn     string `yaml:"event_column"`
-		StakeColumn     string `yaml:"stake_column"`
-		OutcomeColumn   string `yaml:"outcome_column"`
-		FilterByResult  string `yaml:"filter_by_result"`
-		FilterByMarket  string `yaml:"filter_by_market"`
+		HistoryPageLink Selector `yaml:"history_page_link"`
+		BetRowSelector  Selector `yaml:"bet_row_selector"`
+		EventColumn     Selector `yaml:"event_column"`
+		StakeColumn     Selector `yaml:"stake_column"`
+		OutcomeColumn   Selector `yaml:"outcome_column"`
+		FilterByResult  Selector `yaml:"filter_by_result"`
+		FilterByMarket  Selector `yaml:"filter_by_market"`
 	} `yaml:"bet_history"`
 
 	Promotions struct {
-		PromotionBanner  string `yaml:"promotion_banner"`
-		RedeemButton     string `yaml:"redeem_button"`
-		PromoCodeInput   string `yaml:"promo_code_input"`
-		ApplyPromoButton string `yaml:"apply_promo_button"`
+		PromotionBanner  Selector `yaml:"promotion_banner"`
+		RedeemButton     Selector `yaml:"redeem_button"`
+		PromoCodeInput   Selector `yaml:"promo_code_input"`
+		ApplyPromoButton Selector `yaml:"apply_promo_button"`
 	} `yaml:"promotions"`
 
 	CashOut struct {
-		CashOutButton           string `yaml:"cash_out_button"`
-		OpenBet                 string `yaml:"open_bet"`
-		CancellableBetIndicator string `yaml:"cancellable_bet_indicator"`
-		CashoutOffer            string `yaml:"cashout_offer"`
-		ConfirmCashoutButton    string `yaml:"confirm_cashout_button"`
+		CashOutButton           Selector `yaml:"cash_out_button"`
+		OpenBet                 Selector `yaml:"open_bet"`
+		CancellableBetIndicator Selector `yaml:"cancellable_bet_indicator"`
+		CashoutOffer            Selector `yaml:"cashout_offer"`
+		ConfirmCashoutButton    Selector `yaml:"confirm_cashout_button"`
 	} `yaml:"cash_out"`
 
 	NotificationCenter struct {
-		NotificationPopup   string `yaml:"notification_popup"`
-		DismissButton       string `yaml:"dismiss_button"`
-		NotificationMessage string `yaml:"notification_message"`
-		NotificationType    string `yaml:"notification_type"`
+		NotificationPopup   Selector `yaml:"notification_popup"`
+		DismissButton       Selector `yaml:"dismiss_button"`
+		NotificationMessage Selector `yaml:"notification_message"`
+		NotificationType    Selector `yaml:"notification_type"`
 	} `yaml:"notification_center"`
 }
 
@@ -182,8 +201,51 @@ type UserCredentials struct {
 // OverrideMap holds per-bookie overrides loaded from YAML
 type OverrideMap map[string]map[string]interface{}
 
+// Set stores value under the nested YAML path for a bookie, creating
+// intermediate maps as needed
+func (o OverrideMap) Set(bookie string, path []string, value interface{}) {
+	if o[bookie] == nil {
+		o[bookie] = map[string]interface{}{}
+	}
+	m := o[bookie]
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
+
 // ApplyOverrides merges overrides into a Sportsbook struct
 func (sb *Sportsbook) ApplyOverrides(overrides map[string]interface{}) {
 	data, _ := yaml.Marshal(overrides)
 	_ = yaml.Unmarshal(data, sb)
 }
+
+// Load reads a YAML config for a single bookie
+func Load(path string) (*Sportsbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb Sportsbook
+	if err := yaml.Unmarshal(data, &sb); err != nil {
+		return nil, err
+	}
+	return &sb, nil
+}
+
+// Save writes a bookie config back to YAML using the generator's layout
+func Save(path string, sb *Sportsbook) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(sb); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
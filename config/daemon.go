@@ -0,0 +1,44 @@
+package config
+
+// DaemonSettings configure the scheduler of the daemon mode
+//
+//	daemon:
+//	  tiers:
+//	    - name: high
+//	      every: 5m
+//	      jitter: 30s
+//	      bookies: [betway, sportpesa]
+//	    - name: normal
+//	      cron: "0 * * * *"
+//	      jitter: 5m
+//	  schedules:
+//	    betika: {every: 15m}
+type DaemonSettings struct {
+	// StateFile keeps when each bookie last ran between restarts; relative
+	// paths are below the output dir
+	StateFile string `yaml:"state_file"`
+	// Reload is how often the manifest, settings and configs are checked for changes
+	Reload string `yaml:"reload"`
+	// Tiers are in priority order: when bookies of several tiers are due,
+	// the first tier runs first. A tier without bookies takes every bookie
+	// no other tier lists.
+	Tiers []Tier `yaml:"tiers"`
+	// Schedules override the schedule of single bookies within their tier
+	Schedules map[string]Schedule `yaml:"schedules"`
+}
+
+// Tier is a group of bookies checked on the same schedule
+type Tier struct {
+	Name     string `yaml:"name"`
+	Schedule `yaml:",inline"`
+	Bookies  []string `yaml:"bookies,omitempty"`
+}
+
+// Schedule is when a bookie is due: every interval, e.g. "5m", or on a
+// five-field cron expression, e.g. "*/15 * * * *", delayed by a random
+// jitter up to the given duration
+type Schedule struct {
+	Every  string `yaml:"every,omitempty"`
+	Cron   string `yaml:"cron,omitempty"`
+	Jitter string `yaml:"jitter,omitempty"`
+}
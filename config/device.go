@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDevice is the profile the base config describes
+const DefaultDevice = "desktop"
+
+// Device is a profile a bookie is verified as: the user agent and client
+// hint headers to send, an optional separate site URL, and overrides of the
+// base config in the same shape as config.yaml.
+//
+//	devices:
+//	  mobile:
+//	    url: https://m-ke.saharagames.com
+//	    overrides:
+//	      selectors:
+//	        login:
+//	          username_input: input[name=phone]
+type Device struct {
+	UserAgent string                 `yaml:"user_agent,omitempty"`
+	Viewport  string                 `yaml:"viewport,omitempty"`
+	Headers   map[string]string      `yaml:"headers,omitempty"`
+	URL       string                 `yaml:"url,omitempty"`
+	Overrides map[string]interface{} `yaml:"overrides,omitempty"`
+}
+
+// DevicePresets fill in the user agent and viewport of devices named
+// desktop, mobile or tablet when the config leaves them out
+var DevicePresets = map[string]Device{
+	"desktop": {
+		UserAgent: "Mozilla/5.0 (compatible; FetchBot/2.0; +https://yourdomain.com/bot)",
+		Viewport:  "1366x768",
+	},
+	"mobile": {
+		UserAgent: "Mozilla/5.0 (Linux; Android 13; SM-A146B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+		Viewport:  "390x844",
+		Headers:   map[string]string{"Sec-CH-UA-Mobile": "?1", "Sec-CH-UA-Platform": `"Android"`},
+	},
+	"tablet": {
+		UserAgent: "Mozilla/5.0 (Linux; Android 13; SM-X200) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		Viewport:  "800x1280",
+		Headers:   map[string]string{"Sec-CH-UA-Mobile": "?0", "Sec-CH-UA-Platform": `"Android"`},
+	},
+}
+
+// DeviceNames lists the bookie's profiles, the default one first. Bookies
+// without devices have none and are verified once as before.
+func (sb *Sportsbook) DeviceNames() []string {
+	if len(sb.Devices) == 0 {
+		return nil
+	}
+	names := []string{DefaultDevice}
+	for name := range sb.Devices {
+		if name != DefaultDevice {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// Device returns a profile with preset values filled in
+func (sb *Sportsbook) Device(name string) Device {
+	d := sb.Devices[name]
+	preset := DevicePresets[name]
+	if d.UserAgent == "" {
+		d.UserAgent = preset.UserAgent
+	}
+	if d.Viewport == "" {
+		d.Viewport = preset.Viewport
+	}
+	headers := map[string]string{}
+	for k, v := range preset.Headers {
+		headers[k] = v
+	}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	d.Headers = headers
+	return d
+}
+
+// Header returns the request headers of a profile, including viewport client hints
+func (d Device) Header() http.Header {
+	h := http.Header{}
+	if d.UserAgent != "" {
+		h.Set("User-Agent", d.UserAgent)
+	}
+	if w, ht, ok := strings.Cut(d.Viewport, "x"); ok {
+		h.Set("Viewport-Width", w)
+		h.Set("Sec-CH-Viewport-Width", w)
+		h.Set("Sec-CH-Viewport-Height", ht)
+	}
+	for k, v := range d.Headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+// Variant returns a copy of the config as seen by a device: its URL and
+// overrides applied, and no devices of its own
+func (sb *Sportsbook) Variant(name string) (*Sportsbook, error) {
+	data, err := yaml.Marshal(sb)
+	if err != nil {
+		return nil, err
+	}
+	var v Sportsbook
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to copy config: %w", err)
+	}
+
+	d := sb.Devices[name]
+	if len(d.Overrides) > 0 {
+		v.ApplyOverrides(d.Overrides)
+	}
+	if d.URL != "" {
+		v.BaseURL = d.URL
+	}
+	v.Devices = nil
+	return &v, nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DefaultFrameDepth is how deep nested iframes are followed when max_depth is unset
+const DefaultFrameDepth = 2
+
+// Frame path syntax: "frame:#sportsbook >> div.event-item" matches
+// div.event-item inside the iframe matching #sportsbook
+const (
+	FramePrefix    = "frame:"
+	FrameSeparator = ">>"
+)
+
+// Frames configure iframe traversal. Frames on the bookie's own host are
+// always allowed; AllowDomains adds provider domains and their subdomains.
+type Frames struct {
+	MaxDepth     int      `yaml:"max_depth,omitempty"`
+	AllowDomains []string `yaml:"allow_domains,omitempty"`
+	Disabled     bool     `yaml:"disabled,omitempty"`
+}
+
+// IsZero reports whether no frame settings are configured
+func (f Frames) IsZero() bool {
+	return f.MaxDepth == 0 && len(f.AllowDomains) == 0 && !f.Disabled
+}
+
+// Depth returns the configured depth, or DefaultFrameDepth
+func (f Frames) Depth() int {
+	if f.Disabled {
+		return 0
+	}
+	if f.MaxDepth > 0 {
+		return f.MaxDepth
+	}
+	return DefaultFrameDepth
+}
+
+// Allows reports whether an iframe URL may be fetched for a bookie at baseURL
+func (f Frames) Allows(baseURL, frameURL string) bool {
+	u, err := url.Parse(frameURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if base, err := url.Parse(baseURL); err == nil && strings.EqualFold(base.Hostname(), host) {
+		return true
+	}
+	for _, d := range f.AllowDomains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitFrames splits a selector into its frame path and the selector
+// applied inside the innermost frame. Selectors without a frame path
+// return no frames and are unchanged.
+func SplitFrames(sel string) (frames []string, inner string) {
+	if !strings.HasPrefix(strings.TrimSpace(sel), FramePrefix) {
+		return nil, sel
+	}
+	parts := strings.Split(sel, FrameSeparator)
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 && strings.HasPrefix(part, FramePrefix) {
+			frames = append(frames, strings.TrimSpace(strings.TrimPrefix(part, FramePrefix)))
+			continue
+		}
+		return frames, strings.TrimSpace(strings.Join(parts[i:], FrameSeparator))
+	}
+	return frames, ""
+}
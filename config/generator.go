@@ -22,233 +22,233 @@ func buildSportsbook(bookie string, index int, baseURL, browserPath string, over
 		Region:      "KE", // Default region
 		Selectors: Selectors{
 			Login: struct {
-				UsernameInput   string `yaml:"username_input"`
-				PasswordInput   string `yaml:"password_input"`
-				LoginButton     string `yaml:"login_button"`
-			        Modal struct {
-		   	            Enabled     bool   `yaml:"enabled"`
-			            Selector    string `yaml:"selector"`
-			            CloseButton string `yaml:"close_button"`
-			        } `yaml:"modal"`
+				UsernameInput Selector `yaml:"username_input"`
+				PasswordInput Selector `yaml:"password_input"`
+				LoginButton   Selector `yaml:"login_button"`
+				Modal         struct {
+					Enabled     bool     `yaml:"enabled"`
+					Selector    Selector `yaml:"selector"`
+					CloseButton Selector `yaml:"close_button"`
+				} `yaml:"modal"`
 
-				OtpInput        string `yaml:"otp_input"`
-				OtpSubmitButton string `yaml:"otp_submit_button"`
+				OtpInput        Selector `yaml:"otp_input"`
+				OtpSubmitButton Selector `yaml:"otp_submit_button"`
 			}{
-				UsernameInput: "input#username",
-				PasswordInput: "input#password",
-				LoginButton:   "button#login",
+				UsernameInput: NewSelector("input#username"),
+				PasswordInput: NewSelector("input#password"),
+				LoginButton:   NewSelector("button#login"),
 
-			        Modal: struct {
-        			    Enabled     bool   `yaml:"enabled"`
-       				    Selector    string `yaml:"selector"`
-     			            CloseButton string `yaml:"close_button"`
-        		}{
-         			    Enabled:     true,
-			            Selector:    "div#otpModal",
-			            CloseButton: "div#otpModal button.close",
-      			        },
+				Modal: struct {
+					Enabled     bool     `yaml:"enabled"`
+					Selector    Selector `yaml:"selector"`
+					CloseButton Selector `yaml:"close_button"`
+				}{
+					Enabled:     true,
+					Selector:    NewSelector("div#otpModal"),
+					CloseButton: NewSelector("div#otpModal button.close"),
+				},
 
-				OtpInput:        "input#otp",
-				OtpSubmitButton: "button#otpSubmit",
+				OtpInput:        NewSelector("input#otp"),
+				OtpSubmitButton: NewSelector("button#otpSubmit"),
 			},
 
-			Dashboard: "div#dashboard", // placeholder until you set real selector
+			Dashboard: NewSelector("div#dashboard"), // placeholder until you set real selector
 
 			UserMenu: struct {
-				LogoutButton string `yaml:"logout_button"`
-				AccountLink  string `yaml:"account_link"`
+				LogoutButton Selector `yaml:"logout_button"`
+				AccountLink  Selector `yaml:"account_link"`
 			}{
-				LogoutButton: "button#logout", // preserved from old Session.LogoutButton
-				AccountLink:  "a#account",     // placeholder
+				LogoutButton: NewSelector("button#logout"), // preserved from old Session.LogoutButton
+				AccountLink:  NewSelector("a#account"),     // placeholder
 			},
 
 			AccountForm: struct {
-				EmailInput string `yaml:"email_input"`
-				SaveButton string `yaml:"save_button"`
+				EmailInput Selector `yaml:"email_input"`
+				SaveButton Selector `yaml:"save_button"`
 			}{
-				EmailInput: "input#email", // placeholder
-				SaveButton: "button#save", // placeholder
+				EmailInput: NewSelector("input#email"), // placeholder
+				SaveButton: NewSelector("button#save"), // placeholder
 			},
 			Session: struct {
-				LogoutButton    string `yaml:"logout_button"`
-				SessionUserInfo string `yaml:"session_user_info"`
+				LogoutButton    Selector `yaml:"logout_button"`
+				SessionUserInfo Selector `yaml:"session_user_info"`
 			}{
-				LogoutButton:    "button#logout", // from your original selectors
-				SessionUserInfo: "div#userInfo",  // from your original selectors
+				LogoutButton:    NewSelector("button#logout"), // from your original selectors
+				SessionUserInfo: NewSelector("div#userInfo"),  // from your original selectors
 			},
 			EventSearch: struct {
-				SportDropdown string `yaml:"sport_dropdown"`
-				DatePicker    string `yaml:"date_picker"`
-				SearchButton  string `yaml:"search_button"`
-				EventResults  string `yaml:"event_results"`
-				EventItem     string `yaml:"event_item"`
-				EventTitle    string `yaml:"event_title"`
-				EventTeam     string `yaml:"event_team"`
+				SportDropdown Selector `yaml:"sport_dropdown"`
+				DatePicker    Selector `yaml:"date_picker"`
+				SearchButton  Selector `yaml:"search_button"`
+				EventResults  Selector `yaml:"event_results"`
+				EventItem     Selector `yaml:"event_item"`
+				EventTitle    Selector `yaml:"event_title"`
+				EventTeam     Selector `yaml:"event_team"`
 			}{
-				SportDropdown: "select#sport",
-				DatePicker:    "input#date",
-				SearchButton:  "button#search",
-				EventResults:  "div#eventResults",
-				EventItem:     "div.event-item",
-				EventTitle:    "div.event-title",
-				EventTeam:     "div.event-team",
+				SportDropdown: NewSelector("select#sport"),
+				DatePicker:    NewSelector("input#date"),
+				SearchButton:  NewSelector("button#search"),
+				EventResults:  NewSelector("div#eventResults"),
+				EventItem:     NewSelector("div.event-item"),
+				EventTitle:    NewSelector("div.event-title"),
+				EventTeam:     NewSelector("div.event-team"),
 			},
 			OddsSelector: struct {
-				Moneyline    string `yaml:"moneyline"`
-				Spread       string `yaml:"spread"`
-				Totals       string `yaml:"totals"`
-				OddsDropdown string `yaml:"odds_dropdown"`
+				Moneyline    Selector `yaml:"moneyline"`
+				Spread       Selector `yaml:"spread"`
+				Totals       Selector `yaml:"totals"`
+				OddsDropdown Selector `yaml:"odds_dropdown"`
 			}{
-				Moneyline:    "div.match-result",
-				Spread:       "div.over-under",
-				Totals:       "div.point-spread",
-				OddsDropdown: "select#odds",
+				Moneyline:    NewSelector("div.match-result"),
+				Spread:       NewSelector("div.over-under"),
+				Totals:       NewSelector("div.point-spread"),
+				OddsDropdown: NewSelector("select#odds"),
 			},
 			BetSlip: struct {
-				AddButton       string `yaml:"add_button"`
-				RemoveButton    string `yaml:"remove_button"`
-				StakeInput      string `yaml:"stake_input"`
-				CalculateButton string `yaml:"calculate_button"`
-				ClearButton     string `yaml:"clear_button"`
-				PotentialPayout string `yaml:"potential_payout"`
-				BetSlipItem     string `yaml:"bet_slip_item"`
+				AddButton       Selector `yaml:"add_button"`
+				RemoveButton    Selector `yaml:"remove_button"`
+				StakeInput      Selector `yaml:"stake_input"`
+				CalculateButton Selector `yaml:"calculate_button"`
+				ClearButton     Selector `yaml:"clear_button"`
+				PotentialPayout Selector `yaml:"potential_payout"`
+				BetSlipItem     Selector `yaml:"bet_slip_item"`
 			}{
-				AddButton:       "button#addToBetSlip",
-				RemoveButton:    "button#removeBetSlipItem",
-				StakeInput:      "input#stake",
-				CalculateButton: "button#calculate",
-				ClearButton:     "button#clearBetSlip",
-				PotentialPayout: "div#potentialPayout",
-				BetSlipItem:     "div.bet-slip-item",
+				AddButton:       NewSelector("button#addToBetSlip"),
+				RemoveButton:    NewSelector("button#removeBetSlipItem"),
+				StakeInput:      NewSelector("input#stake"),
+				CalculateButton: NewSelector("button#calculate"),
+				ClearButton:     NewSelector("button#clearBetSlip"),
+				PotentialPayout: NewSelector("div#potentialPayout"),
+				BetSlipItem:     NewSelector("div.bet-slip-item"),
 			},
 			LiveBetting: struct {
-				LiveBettingButton   string `yaml:"live_betting_button"`
-				OddsChangeIndicator string `yaml:"odds_change_indicator"`
-				LiveEventItem       string `yaml:"live_event_item"`
-				LiveScore           string `yaml:"live_score"`
-				LiveOddSelector     string `yaml:"live_odd_selector"`
-				LiveEvent           string `yaml:"live_event"`
-				InPlayBetButton     string `yaml:"in_play_bet_button"`
+				LiveBettingButton   Selector `yaml:"live_betting_button"`
+				OddsChangeIndicator Selector `yaml:"odds_change_indicator"`
+				LiveEventItem       Selector `yaml:"live_event_item"`
+				LiveScore           Selector `yaml:"live_score"`
+				LiveOddSelector     Selector `yaml:"live_odd_selector"`
+				LiveEvent           Selector `yaml:"live_event"`
+				InPlayBetButton     Selector `yaml:"in_play_bet_button"`
 			}{
-				LiveBettingButton:   "button#liveBetting",
-				OddsChangeIndicator: "div.odds-change-indicator",
-				LiveEventItem:       "div.live-event-item",
-				LiveScore:           "div.live-score",
-				LiveOddSelector:     "div.live-odd-selector",
-				LiveEvent:           "div.live-event",
+				LiveBettingButton:   NewSelector("button#liveBetting"),
+				OddsChangeIndicator: NewSelector("div.odds-change-indicator"),
+				LiveEventItem:       NewSelector("div.live-event-item"),
+				LiveScore:           NewSelector("div.live-score"),
+				LiveOddSelector:     NewSelector("div.live-odd-selector"),
+				LiveEvent:           NewSelector("div.live-event"),
 			},
 
 			LineMovement: struct {
-				LineChangeIndicator string `yaml:"line_change_indicator"`
-				OddsHistory         string `yaml:"odds_history"`
-				BettingLines        string `yaml:"betting_lines"`
+				LineChangeIndicator Selector `yaml:"line_change_indicator"`
+				OddsHistory         Selector `yaml:"odds_history"`
+				BettingLines        Selector `yaml:"betting_lines"`
 			}{
-				LineChangeIndicator: "div.line-change-indicator",
-				OddsHistory:         "div.odds-history",
-				BettingLines:        "div.betting-lines",
+				LineChangeIndicator: NewSelector("div.line-change-indicator"),
+				OddsHistory:         NewSelector("div.odds-history"),
+				BettingLines:        NewSelector("div.betting-lines"),
 			},
 			FilterOptions: struct {
-				SportDropdown      string `yaml:"sport_dropdown"`
-				MarketTypeDropdown string `yaml:"market_type_dropdown"`
-				TimeFilter         string `yaml:"time_filter"`
-				ResetFiltersButton string `yaml:"reset_filters_button"`
+				SportDropdown      Selector `yaml:"sport_dropdown"`
+				MarketTypeDropdown Selector `yaml:"market_type_dropdown"`
+				TimeFilter         Selector `yaml:"time_filter"`
+				ResetFiltersButton Selector `yaml:"reset_filters_button"`
 			}{
-				SportDropdown:      "select#sportFilter",
-				MarketTypeDropdown: "select#marketTypeFilter",
-				TimeFilter:         "input#timeFilter",
-				ResetFiltersButton: "button#resetFilters",
+				SportDropdown:      NewSelector("select#sportFilter"),
+				MarketTypeDropdown: NewSelector("select#marketTypeFilter"),
+				TimeFilter:         NewSelector("input#timeFilter"),
+				ResetFiltersButton: NewSelector("button#resetFilters"),
 			},
 			BalanceTracker: struct {
-				BalancePageLink         string `yaml:"balance_page_link"`
-				BalanceContainer        string `yaml:"balance_container"`
-				CurrentBalanceField     string `yaml:"current_balance_field"`
-				AvailableBalanceField   string `yaml:"available_balance_field"`
-				PendingWithdrawalsField string `yaml:"pending_withdrawals_field"`
-				TransactionRowSelector  string `yaml:"transaction_row_selector"`
-				TransactionTypeColumn   string `yaml:"transaction_type_column"`
-				TransactionAmountColumn string `yaml:"transaction_amount_column"`
-				TransactionDateColumn   string `yaml:"transaction_date_column"`
-				FilterByType            string `yaml:"filter_by_type"`
-				FilterByDate            string `yaml:"filter_by_date"`
+				BalancePageLink         Selector `yaml:"balance_page_link"`
+				BalanceContainer        Selector `yaml:"balance_container"`
+				CurrentBalanceField     Selector `yaml:"current_balance_field"`
+				AvailableBalanceField   Selector `yaml:"available_balance_field"`
+				PendingWithdrawalsField Selector `yaml:"pending_withdrawals_field"`
+				TransactionRowSelector  Selector `yaml:"transaction_row_selector"`
+				TransactionTypeColumn   Selector `yaml:"transaction_type_column"`
+				TransactionAmountColumn Selector `yaml:"transaction_amount_column"`
+				TransactionDateColumn   Selector `yaml:"transaction_date_column"`
+				FilterByType            Selector `yaml:"filter_by_type"`
+				FilterByDate            Selector `yaml:"filter_by_date"`
 			}{
-				BalancePageLink:         "a#accountBalanceLink",
-				BalanceContainer:        "div.balance-summary",
-				CurrentBalanceField:     "span.current-balance",
-				AvailableBalanceField:   "span.available-balance",
-				PendingWithdrawalsField: "span.pending-withdrawals",
-				TransactionRowSelector:  "div.transaction-row",
-				TransactionTypeColumn:   "div.transaction-row .type",
-				TransactionAmountColumn: "div.transaction-row .amount",
-				TransactionDateColumn:   "div.transaction-row .date",
-				FilterByType:            "select#filterByTransactionType",
-				FilterByDate:            "select#filterByDateRange",
+				BalancePageLink:         NewSelector("a#accountBalanceLink"),
+				BalanceContainer:        NewSelector("div.balance-summary"),
+				CurrentBalanceField:     NewSelector("span.current-balance"),
+				AvailableBalanceField:   NewSelector("span.available-balance"),
+				PendingWithdrawalsField: NewSelector("span.pending-withdrawals"),
+				TransactionRowSelector:  NewSelector("div.transaction-row"),
+				TransactionTypeColumn:   NewSelector("div.transaction-row .type"),
+				TransactionAmountColumn: NewSelector("div.transaction-row .amount"),
+				TransactionDateColumn:   NewSelector("div.transaction-row .date"),
+				FilterByType:            NewSelector("select#filterByTransactionType"),
+				FilterByDate:            NewSelector("select#filterByDateRange"),
 			},
 			BetConfirmation: struct {
-				ConfirmButton  string `yaml:"confirm_button"`
-				ErrorMessage   string `yaml:"error_message"`
-				SuccessMessage string `yaml:"success_message"`
-				BetSummary     string `yaml:"bet_summary"`
+				ConfirmButton  Selector `yaml:"confirm_button"`
+				ErrorMessage   Selector `yaml:"error_message"`
+				SuccessMessage Selector `yaml:"success_message"`
+				BetSummary     Selector `yaml:"bet_summary"`
 			}{
-				ConfirmButton:  "button#confirmBet",
-				ErrorMessage:   "div#errorMessage",
-				SuccessMessage: "div#successMessage",
-				BetSummary:     "div#betSummary",
+				ConfirmButton:  NewSelector("button#confirmBet"),
+				ErrorMessage:   NewSelector("div#errorMessage"),
+				SuccessMessage: NewSelector("div#successMessage"),
+				BetSummary:     NewSelector("div#betSummary"),
 			},
 			BetHistory: struct {
-				HistoryPageLink string `yaml:"history_page_link"`
-				BetRowSelector  string `yaml:"bet_row_selector"`
-				EventColumn     string `yaml:"event_column"`
-				StakeColumn     string `yaml:"stake_column"`
-				OutcomeColumn   string `yaml:"outcome_column"`
-				FilterByResult  string `yaml:"filter_by_result"`
-				FilterByMarket  string `yaml:"filter_by_market"`
+				HistoryPageLink Selector `yaml:"history_page_link"`
+				BetRowSelector  Selector `yaml:"bet_row_selector"`
+				EventColumn     Selector `yaml:"event_column"`
+				StakeColumn     Selector `yaml:"stake_column"`
+				OutcomeColumn   Selector `yaml:"outcome_column"`
+				FilterByResult  Selector `yaml:"filter_by_result"`
+				FilterByMarket  Selector `yaml:"filter_by_market"`
 			}{
-				HistoryPageLink: "a#betHistoryLink",
-				BetRowSelector:  "div.bet-row",
-				EventColumn:     "div.bet-row .event",
-				StakeColumn:     "div.bet-row .stake",
-				OutcomeColumn:   "div.bet-row .outcome",
-				FilterByResult:  "select#filterByResult",
-				FilterByMarket:  "select#filterByMarket",
+				HistoryPageLink: NewSelector("a#betHistoryLink"),
+				BetRowSelector:  NewSelector("div.bet-row"),
+				EventColumn:     NewSelector("div.bet-row .event"),
+				StakeColumn:     NewSelector("div.bet-row .stake"),
+				OutcomeColumn:   NewSelector("div.bet-row .outcome"),
+				FilterByResult:  NewSelector("select#filterByResult"),
+				FilterByMarket:  NewSelector("select#filterByMarket"),
 			},
 			Promotions: struct {
-				PromotionBanner  string `yaml:"promotion_banner"`
-				RedeemButton     string `yaml:"redeem_button"`
-				PromoCodeInput   string `yaml:"promo_code_input"`
-				ApplyPromoButton string `yaml:"apply_promo_button"`
+				PromotionBanner  Selector `yaml:"promotion_banner"`
+				RedeemButton     Selector `yaml:"redeem_button"`
+				PromoCodeInput   Selector `yaml:"promo_code_input"`
+				ApplyPromoButton Selector `yaml:"apply_promo_button"`
 			}{
-				PromotionBanner:  "div#promotionBanner",
-				RedeemButton:     "button#redeemPromo",
-				PromoCodeInput:   "input#promoCode",
-				ApplyPromoButton: "button#applyPromo",
+				PromotionBanner:  NewSelector("div#promotionBanner"),
+				RedeemButton:     NewSelector("button#redeemPromo"),
+				PromoCodeInput:   NewSelector("input#promoCode"),
+				ApplyPromoButton: NewSelector("button#applyPromo"),
 			},
 			CashOut: struct {
-				CashOutButton           string `yaml:"cash_out_button"`
-				OpenBet                 string `yaml:"open_bet"`
-				CancellableBetIndicator string `yaml:"cancellable_bet_indicator"`
-				CashoutOffer            string `yaml:"cashout_offer"`
-				ConfirmCashoutButton    string `yaml:"confirm_cashout_button"`
+				CashOutButton           Selector `yaml:"cash_out_button"`
+				OpenBet                 Selector `yaml:"open_bet"`
+				CancellableBetIndicator Selector `yaml:"cancellable_bet_indicator"`
+				CashoutOffer            Selector `yaml:"cashout_offer"`
+				ConfirmCashoutButton    Selector `yaml:"confirm_cashout_button"`
 			}{
-				CashOutButton:           "button#cashOut",
-				OpenBet:                 "div.open-bet",
-				CancellableBetIndicator: "div.cancellable-bet",
-				CashoutOffer:            "div.cashout-offer",
-				ConfirmCashoutButton:    "button#confirmCashout",
+				CashOutButton:           NewSelector("button#cashOut"),
+				OpenBet:                 NewSelector("div.open-bet"),
+				CancellableBetIndicator: NewSelector("div.cancellable-bet"),
+				CashoutOffer:            NewSelector("div.cashout-offer"),
+				ConfirmCashoutButton:    NewSelector("button#confirmCashout"),
 			},
 			NotificationCenter: struct {
-				NotificationPopup   string `yaml:"notification_popup"`
-				DismissButton       string `yaml:"dismiss_button"`
-				NotificationMessage string `yaml:"notification_message"`
-				NotificationType    string `yaml:"notification_type"`
+				NotificationPopup   Selector `yaml:"notification_popup"`
+				DismissButton       Selector `yaml:"dismiss_button"`
+				NotificationMessage Selector `yaml:"notification_message"`
+				NotificationType    Selector `yaml:"notification_type"`
 			}{
-				NotificationPopup:   "div#notificationPopup",
-				DismissButton:       "button#dismissNotification",
-				NotificationMessage: "div.notification-message",
-				NotificationType:    "div.notification-type",
+				NotificationPopup:   NewSelector("div#notificationPopup"),
+				DismissButton:       NewSelector("button#dismissNotification"),
+				NotificationMessage: NewSelector("div.notification-message"),
+				NotificationType:    NewSelector("div.notification-type"),
 			},
 		},
-		BetButton:  "button#placeBet",
-		BetHistory: "div#betHistory",
+		BetButton:  NewSelector("button#placeBet"),
+		BetHistory: NewSelector("div#betHistory"),
 		Timeout: Timeout{
 			BetOperation: 30000, // 30 seconds timeout for placing a bet
 			PageLoad:     5000,  // 5 seconds timeout for page loading
@@ -276,6 +276,14 @@ func buildSportsbook(bookie string, index int, baseURL, browserPath string, over
 	return sb
 }
 
+// IsPlaceholder reports whether css is the selector generated configs
+// start with for the field with the given label
+func IsPlaceholder(label, css string) bool {
+	sb := buildSportsbook("", 0, "", "", nil)
+	f, ok := sb.FindSelector(label)
+	return ok && f.Selector.CSS == css
+}
+
 // GenerateConfig builds a YAML config for a single bookie with optional override
 func GenerateConfig(bookie string, overrides OverrideMap, outputDir, baseURL, browserPath string, index int) error {
 	bookieDir := filepath.Join(outputDir, strings.ToLower(bookie))
@@ -350,3 +358,15 @@ func LoadOverrides(path string) (OverrideMap, error) {
 
 	return ovr, nil
 }
+
+// SaveOverrides writes overrides to a YAML file that LoadOverrides and
+// --bake-overrides can consume
+func SaveOverrides(path string, overrides OverrideMap) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(overrides); err != nil {
+		return fmt.Errorf("failed to encode overrides: %w", err)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,67 @@
+package config
+
+// NotifySettings configure where the results of a run are sent
+//
+//	notify:
+//	  sinks:
+//	    - name: ops
+//	      type: slack
+//	      url: ${SLACK_WEBHOOK_URL}
+//	      throttle: 6h
+//	      filter:
+//	        events: [regression, unreachable]
+//	        min_severity: critical
+type NotifySettings struct {
+	// StateFile keeps dedupe and throttle state between runs; relative
+	// paths are below the output dir
+	StateFile string `yaml:"state_file"`
+	Sinks     []Sink `yaml:"sinks"`
+}
+
+// Sink types
+const (
+	SinkWebhook = "webhook"
+	SinkSlack   = "slack"
+	SinkTeams   = "teams"
+	SinkEmail   = "email"
+)
+
+// Sink is one notification target. URL, Secret and the SMTP password may
+// reference environment variables as ${NAME}.
+type Sink struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+
+	// URL is where webhook, Slack and Teams messages are posted
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Secret signs webhook bodies with HMAC-SHA256
+	Secret string `yaml:"secret,omitempty"`
+
+	SMTP SMTP `yaml:"smtp,omitempty"`
+
+	Filter NotifyFilter `yaml:"filter,omitempty"`
+	// Throttle is the minimum time before the same event is sent again, e.g. "6h"
+	Throttle string `yaml:"throttle,omitempty"`
+}
+
+// SMTP is the mail server and envelope of an email sink
+type SMTP struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// NotifyFilter narrows what a sink is sent
+type NotifyFilter struct {
+	// Events are the kinds sent: regression, unreachable, fixed, recovered.
+	// Empty sends regressions and unreachable bookies.
+	Events []string `yaml:"events,omitempty"`
+	// MinSeverity drops selector events below critical, major or minor
+	MinSeverity string `yaml:"min_severity,omitempty"`
+	// Bookies limits the sink to these bookies; empty sends all
+	Bookies []string `yaml:"bookies,omitempty"`
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Selector is a CSS selector plus optional fallbacks and assertions about
+// what it matches. In YAML it is a plain string, an ordered list of
+// alternatives, or a mapping:
+//
+//	login_button:
+//	  css: form#login button[type=submit]
+//	  fallbacks: ["button.login-btn"]
+//	  assert:
+//	    unique: true
+//	    text_contains: Login
+//	  severity: critical
+type Selector struct {
+	CSS       string     `yaml:"css"`
+	Fallbacks []string   `yaml:"fallbacks,omitempty"`
+	Assert    Assertions `yaml:"assert,omitempty"`
+	Severity  Severity   `yaml:"severity,omitempty"`
+}
+
+// Assertions are extra checks on the elements a selector matches. Without
+// any assertions a selector passes when it matches at least one element.
+// Text, input type and attribute checks apply to the first match.
+type Assertions struct {
+	MinCount     *int            `yaml:"min_count,omitempty"`
+	MaxCount     *int            `yaml:"max_count,omitempty"`
+	Unique       bool            `yaml:"unique,omitempty"`
+	TextEquals   string          `yaml:"text_equals,omitempty"`
+	TextContains string          `yaml:"text_contains,omitempty"`
+	TextMatches  string          `yaml:"text_matches,omitempty"`
+	InputType    string          `yaml:"input_type,omitempty"`
+	Attributes   []AttrAssertion `yaml:"attributes,omitempty"`
+}
+
+// AttrAssertion requires an attribute to be present, optionally with an
+// exact value or a value matching a regular expression.
+type AttrAssertion struct {
+	Name    string `yaml:"name"`
+	Equals  string `yaml:"equals,omitempty"`
+	Matches string `yaml:"matches,omitempty"`
+}
+
+// NewSelector returns a plain CSS selector without assertions
+func NewSelector(css string) Selector {
+	return Selector{CSS: css}
+}
+
+// IsZero reports whether the selector is unset
+func (s Selector) IsZero() bool {
+	return s.CSS == "" && len(s.Fallbacks) == 0 && s.Assert.IsZero() && s.Severity == ""
+}
+
+// Alternatives returns the primary selector followed by its fallbacks, in priority order
+func (s Selector) Alternatives() []string {
+	var alts []string
+	for _, css := range append([]string{s.CSS}, s.Fallbacks...) {
+		if css != "" {
+			alts = append(alts, css)
+		}
+	}
+	return alts
+}
+
+// Promote reorders the alternatives by how often each one matched, keeping
+// the configured order for ties. It reports whether the order changed.
+func (s *Selector) Promote(wins map[string]int) bool {
+	alts := s.Alternatives()
+	if len(alts) < 2 {
+		return false
+	}
+
+	sorted := append([]string{}, alts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return wins[sorted[i]] > wins[sorted[j]]
+	})
+
+	changed := false
+	for i := range alts {
+		if alts[i] != sorted[i] {
+			changed = true
+			break
+		}
+	}
+	if changed {
+		s.CSS = sorted[0]
+		s.Fallbacks = sorted[1:]
+	}
+	return changed
+}
+
+// IsZero reports whether no assertions are configured
+func (a Assertions) IsZero() bool {
+	return a.MinCount == nil && a.MaxCount == nil && !a.Unique &&
+		a.TextEquals == "" && a.TextContains == "" && a.TextMatches == "" &&
+		a.InputType == "" && len(a.Attributes) == 0
+}
+
+// selectorFields is Selector without its YAML methods, used to decode the mapping form
+type selectorFields Selector
+
+// UnmarshalYAML accepts the plain string, list and mapping forms
+func (s *Selector) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*s = Selector{CSS: value.Value}
+		return nil
+	case yaml.SequenceNode:
+		var alts []string
+		if err := value.Decode(&alts); err != nil {
+			return err
+		}
+		*s = Selector{}
+		if len(alts) > 0 {
+			s.CSS = alts[0]
+			s.Fallbacks = alts[1:]
+		}
+		return nil
+	case yaml.MappingNode:
+		// Keys the mapping leaves out keep their value, so an override of
+		// css alone keeps the assertions, fallbacks and severity
+		f := selectorFields(*s)
+		if err := value.Decode(&f); err != nil {
+			return err
+		}
+		*s = Selector(f)
+		return nil
+	default:
+		return fmt.Errorf("line %d: selector must be a string, a list or a mapping", value.Line)
+	}
+}
+
+// MarshalYAML writes selectors back in the shortest form that keeps all settings
+func (s Selector) MarshalYAML() (interface{}, error) {
+	if !s.Assert.IsZero() || s.Severity != "" {
+		return selectorFields(s), nil
+	}
+	if len(s.Fallbacks) > 0 {
+		return append([]string{s.CSS}, s.Fallbacks...), nil
+	}
+	return s.CSS, nil
+}
+
+// SelectorField is one selector of a Sportsbook, addressed by its dotted
+// label (e.g. "Login.UsernameInput") and its YAML path.
+type SelectorField struct {
+	Label    string
+	Path     []string
+	Selector *Selector
+}
+
+// CSSPath is the YAML path that sets only the primary selector: the field
+// itself for plain selectors, its css key for the list and mapping forms
+// so an override keeps the fallbacks, assertions and severity
+func (f SelectorField) CSSPath() []string {
+	s := f.Selector
+	if len(s.Fallbacks) == 0 && s.Assert.IsZero() && s.Severity == "" {
+		return f.Path
+	}
+	return append(append([]string{}, f.Path...), "css")
+}
+
+var selectorType = reflect.TypeOf(Selector{})
+
+// SelectorFields lists every selector in declaration order, followed by the
+// top-level BetButton and BetHistory selectors.
+func (sb *Sportsbook) SelectorFields() []SelectorField {
+	var fields []SelectorField
+	collectSelectors(reflect.ValueOf(&sb.Selectors).Elem(), "", []string{"selectors"}, &fields)
+	fields = append(fields,
+		SelectorField{Label: "BetButton", Path: []string{"bet_button"}, Selector: &sb.BetButton},
+		SelectorField{Label: "BetHistory", Path: []string{"bet_history"}, Selector: &sb.BetHistory},
+	)
+	return fields
+}
+
+// SelectorMap returns the alternatives of every configured selector by label,
+// e.g. to record which selectors a run used
+func (sb *Sportsbook) SelectorMap() map[string][]string {
+	out := map[string][]string{}
+	for _, field := range sb.SelectorFields() {
+		if alts := field.Selector.Alternatives(); len(alts) > 0 {
+			out[field.Label] = alts
+		}
+	}
+	return out
+}
+
+// collectSelectors recursively walks nested selector structs
+func collectSelectors(v reflect.Value, prefix string, path []string, out *[]SelectorField) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		fieldType := t.Field(i)
+
+		label := fieldType.Name
+		if prefix != "" {
+			label = prefix + "." + label
+		}
+		key := strings.Split(fieldType.Tag.Get("yaml"), ",")[0]
+		fieldPath := append(append([]string{}, path...), key)
+
+		switch {
+		case field.Type() == selectorType:
+			*out = append(*out, SelectorField{
+				Label:    label,
+				Path:     fieldPath,
+				Selector: field.Addr().Interface().(*Selector),
+			})
+		case field.Kind() == reflect.Struct:
+			collectSelectors(field, label, fieldPath, out)
+		}
+	}
+}
+
+// FindSelector returns the selector with the given dotted label
+func (sb *Sportsbook) FindSelector(label string) (SelectorField, bool) {
+	for _, f := range sb.SelectorFields() {
+		if f.Label == label {
+			return f, true
+		}
+	}
+	return SelectorField{}, false
+}
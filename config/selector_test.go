@@ -0,0 +1,79 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSelectorFieldCSSPath(t *testing.T) {
+	path := []string{"selectors", "login", "password_input"}
+	tests := []struct {
+		name string
+		sel  Selector
+		want []string
+	}{
+		{"plain", Selector{CSS: "#pw"}, path},
+		{"fallbacks", Selector{CSS: "#pw", Fallbacks: []string{"input[type=password]"}}, append(path[:3:3], "css")},
+		{"assertions", Selector{CSS: "#pw", Assert: Assertions{Unique: true}}, append(path[:3:3], "css")},
+		{"severity", Selector{CSS: "#pw", Severity: SeverityCritical}, append(path[:3:3], "css")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := SelectorField{Label: "Login.PasswordInput", Path: path, Selector: &tt.sel}
+			if got := f.CSSPath(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CSSPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// An override of the css key, as written by --apply-suggestions, must keep
+// the rest of a mapping-form selector
+func TestApplyOverridesKeepsSelectorFields(t *testing.T) {
+	var sb Sportsbook
+	err := yaml.Unmarshal([]byte(`
+selectors:
+  login:
+    password_input:
+      css: "#old"
+      fallbacks: ["input[type=password]"]
+      assert:
+        unique: true
+        input_type: password
+      severity: critical
+`), &sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, ok := sb.FindSelector("Login.PasswordInput")
+	if !ok {
+		t.Fatal("Login.PasswordInput not found")
+	}
+
+	overrides := OverrideMap{}
+	overrides.Set("betway", field.CSSPath(), "#new")
+	sb.ApplyOverrides(overrides["betway"])
+
+	want := Selector{
+		CSS:       "#new",
+		Fallbacks: []string{"input[type=password]"},
+		Assert:    Assertions{Unique: true, InputType: "password"},
+		Severity:  SeverityCritical,
+	}
+	if got := sb.Selectors.Login.PasswordInput; !reflect.DeepEqual(got, want) {
+		t.Errorf("after override = %+v, want %+v", got, want)
+	}
+}
+
+func TestApplyOverridesScalarReplacesSelector(t *testing.T) {
+	sb := Sportsbook{}
+	sb.Selectors.Login.PasswordInput = Selector{CSS: "#old", Fallbacks: []string{".pw"}}
+	sb.ApplyOverrides(map[string]interface{}{
+		"selectors": map[string]interface{}{"login": map[string]interface{}{"password_input": "#new"}},
+	})
+	if got := sb.Selectors.Login.PasswordInput; !reflect.DeepEqual(got, Selector{CSS: "#new"}) {
+		t.Errorf("after override = %+v", got)
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are run-wide options shared by all bookies, read from diago.yaml
+type Settings struct {
+	Lint      LintSettings     `yaml:"lint"`
+	Drift     DriftSettings    `yaml:"drift"`
+	Snapshots SnapshotSettings `yaml:"snapshots"`
+	History   HistorySettings  `yaml:"history"`
+	Flaky     FlakySettings    `yaml:"flaky"`
+	Notify    NotifySettings   `yaml:"notify"`
+	Metrics   MetricsSettings  `yaml:"metrics"`
+	Daemon    DaemonSettings   `yaml:"daemon"`
+	Server    ServerSettings   `yaml:"server"`
+}
+
+// LintSettings configure the selector linter
+type LintSettings struct {
+	// Rules maps a rule id to error, warn or off; unset rules keep their default
+	Rules map[string]string `yaml:"rules"`
+}
+
+// DriftSettings configure layout drift alerts
+type DriftSettings struct {
+	// Threshold is the minimum structural similarity (0-1) to the previous run
+	Threshold float64 `yaml:"threshold"`
+	// Keep is how many fingerprint runs are stored per bookie
+	Keep int `yaml:"keep"`
+}
+
+// SnapshotSettings configure storing fetched pages for offline runs
+type SnapshotSettings struct {
+	// Disabled stops fetch from storing pages
+	Disabled bool `yaml:"disabled"`
+	// KeepRuns is how many runs are kept; 0 keeps all
+	KeepRuns int `yaml:"keep_runs"`
+	// MaxAgeDays deletes runs older than this; 0 keeps them regardless of age
+	MaxAgeDays int `yaml:"max_age_days"`
+}
+
+// HistorySettings configure the run history used for trends and promotion
+type HistorySettings struct {
+	// Disabled stops fetch from appending runs to the history
+	Disabled bool `yaml:"disabled"`
+	// KeepRuns is how many runs are kept; 0 keeps all
+	KeepRuns int `yaml:"keep_runs"`
+	// PromoteRuns is how many recent runs promote counts matches over
+	PromoteRuns int `yaml:"promote_runs"`
+}
+
+// FlakySettings configure classifying checks as stable, flaky or broken
+// across runs, and how long a check must fail before it alerts
+type FlakySettings struct {
+	// Window is how many recent runs are looked at
+	Window int `yaml:"window"`
+	// MinFlips is how many pass/fail flips within the window make a check flaky
+	MinFlips int `yaml:"min_flips"`
+	// BrokenAfter is how many failed runs in a row make a check broken
+	BrokenAfter int `yaml:"broken_after"`
+	// AlertAfter is how many failed runs in a row a check needs before it alerts
+	AlertAfter int `yaml:"alert_after"`
+}
+
+// Metrics detail levels, from the fewest series to the most
+const (
+	MetricsBookie   = "bookie"
+	MetricsSection  = "section"
+	MetricsSelector = "selector"
+)
+
+// MetricsSettings configure the Prometheus metrics
+type MetricsSettings struct {
+	// Textfile is written after every live fetch for node-exporter's
+	// textfile collector; empty disables it
+	Textfile string `yaml:"textfile"`
+	// Listen is the address the metrics mode serves /metrics on
+	Listen string `yaml:"listen"`
+	// Detail bounds label cardinality: bookie, section or selector
+	Detail string `yaml:"detail"`
+	// Variants adds series for device variants besides the default device
+	Variants bool `yaml:"variants"`
+}
+
+// ServerSettings configure the HTTP API of the serve mode
+type ServerSettings struct {
+	Listen string `yaml:"listen"`
+	// Tokens are accepted as bearer tokens; they may reference environment
+	// variables as ${NAME}. The server does not start without one.
+	Tokens []string `yaml:"tokens"`
+	// MaxRuns is how many runs may verify at the same time
+	MaxRuns int `yaml:"max_runs"`
+	// KeepRuns is how many finished runs stay available with their events
+	KeepRuns int `yaml:"keep_runs"`
+}
+
+// DefaultSettings are used for anything diago.yaml leaves unset
+func DefaultSettings() Settings {
+	return Settings{
+		Drift:     DriftSettings{Threshold: 0.85, Keep: 50},
+		Snapshots: SnapshotSettings{KeepRuns: 10, MaxAgeDays: 14},
+		History:   HistorySettings{KeepRuns: 500, PromoteRuns: 10},
+		Flaky:     FlakySettings{Window: 10, MinFlips: 3, BrokenAfter: 3, AlertAfter: 1},
+		Metrics:   MetricsSettings{Listen: ":9470", Detail: MetricsSection, Variants: true},
+		Daemon: DaemonSettings{
+			Reload: "30s",
+			Tiers:  []Tier{{Name: "default", Schedule: Schedule{Every: "1h", Jitter: "5m"}}},
+		},
+		Server: ServerSettings{Listen: ":8470", MaxRuns: 2, KeepRuns: 50},
+	}
+}
+
+// LoadSettings reads diago.yaml; a missing file yields the defaults
+func LoadSettings(path string) (Settings, error) {
+	s := DefaultSettings()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return s, err
+	}
+	return s, nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import "strings"
+
+// Severity ranks how much a failing selector matters for a bookie's health
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityMajor    Severity = "major"
+	SeverityMinor    Severity = "minor"
+	SeverityNA       Severity = "n/a"
+)
+
+// defaultSeverities maps a section or a full selector label to its severity.
+// Full labels take precedence over their section.
+var defaultSeverities = map[string]Severity{
+	"Login":                     SeverityCritical,
+	"Login.Modal.Selector":      SeverityMinor,
+	"Login.Modal.CloseButton":   SeverityMinor,
+	"Login.OtpInput":            SeverityMajor,
+	"Login.OtpSubmitButton":     SeverityMajor,
+	"Dashboard":                 SeverityMajor,
+	"UserMenu":                  SeverityMajor,
+	"AccountForm":               SeverityMinor,
+	"Session":                   SeverityMajor,
+	"EventSearch":               SeverityMajor,
+	"EventSearch.EventItem":     SeverityCritical,
+	"OddsSelector":              SeverityCritical,
+	"OddsSelector.OddsDropdown": SeverityMinor,
+	"BetSlip":                   SeverityCritical,
+	"BetSlip.CalculateButton":   SeverityMinor,
+	"BetSlip.ClearButton":       SeverityMinor,
+	"LiveBetting":               SeverityMinor,
+	"LineMovement":              SeverityMinor,
+	"FilterOptions":             SeverityMinor,
+	"BalanceTracker":            SeverityMajor,
+	"BetConfirmation":           SeverityMajor,
+	"BetHistory":                SeverityMinor,
+	"Promotions":                SeverityMinor,
+	"CashOut":                   SeverityMinor,
+	"NotificationCenter":        SeverityMinor,
+	"BetButton":                 SeverityCritical,
+}
+
+// DefaultSeverity returns the built-in severity for a selector label
+func DefaultSeverity(label string) Severity {
+	if s, ok := defaultSeverities[label]; ok {
+		return s
+	}
+	if s, ok := defaultSeverities[strings.SplitN(label, ".", 2)[0]]; ok {
+		return s
+	}
+	return SeverityMinor
+}
+
+// Valid reports whether s is one of the known severities
+func (s Severity) Valid() bool {
+	switch s {
+	case SeverityCritical, SeverityMajor, SeverityMinor, SeverityNA:
+		return true
+	}
+	return false
+}
+
+// Section returns the YAML key of the section a selector belongs to,
+// e.g. "live_betting" for Selectors.LiveBetting.InPlayBetButton
+func (f SelectorField) Section() string {
+	if len(f.Path) > 1 && f.Path[0] == "selectors" {
+		return f.Path[1]
+	}
+	return f.Path[0]
+}
+
+// Supports reports whether the bookie offers the feature a selector belongs
+// to. Sections are supported unless disabled under capabilities.
+func (sb *Sportsbook) Supports(f SelectorField) bool {
+	enabled, ok := sb.Capabilities[f.Section()]
+	return !ok || enabled
+}
+
+// SeverityOf returns the effective severity of a selector: n/a for
+// unsupported features, then the selector's own setting, then the default.
+func (sb *Sportsbook) SeverityOf(f SelectorField) Severity {
+	if !sb.Supports(f) {
+		return SeverityNA
+	}
+	if f.Selector.Severity != "" {
+		return f.Selector.Severity
+	}
+	return DefaultSeverity(f.Label)
+}
@@ -0,0 +1,279 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"diago/config"
+)
+
+// DefaultReload is how often watched files are checked when the settings leave it unset
+const DefaultReload = 30 * time.Second
+
+// Source is what the daemon schedules. It is loaded at start and again
+// whenever one of its files changes.
+type Source struct {
+	Bookies  []string
+	Settings config.DaemonSettings
+	// Files are watched for changes, e.g. the manifest and settings
+	Files []string
+}
+
+// Options configure a daemon
+type Options struct {
+	// Load reads the bookies and schedules
+	Load func() (Source, error)
+	// Run checks the bookies of one tier that are due and writes the reports
+	Run func(ctx context.Context, tier string, bookies []string)
+	// StatePath keeps when each bookie last ran and is due next
+	StatePath string
+}
+
+// State is when each bookie last ran and is next due, kept between restarts
+type State struct {
+	Bookies map[string]BookieState `json:"bookies"`
+}
+
+// BookieState is the schedule of one bookie
+type BookieState struct {
+	LastRun  time.Time `json:"last_run,omitempty"`
+	Next     time.Time `json:"next"`
+	Schedule string    `json:"schedule"`
+}
+
+// StatePath resolves where the state is kept; relative paths are below the output dir
+func StatePath(outputDir string, settings config.DaemonSettings) string {
+	switch {
+	case settings.StateFile == "":
+		return filepath.Join(outputDir, "daemon", "state.json")
+	case filepath.IsAbs(settings.StateFile):
+		return settings.StateFile
+	default:
+		return filepath.Join(outputDir, settings.StateFile)
+	}
+}
+
+// Run schedules the bookies until ctx is done. Each cycle runs every tier
+// with due bookies, highest priority first, so a tier that is always due
+// cannot starve the ones below it. A tier that is running when ctx is
+// cancelled finishes, and the state is saved, before Run returns.
+func Run(ctx context.Context, opts Options) error {
+	src, err := opts.Load()
+	if err != nil {
+		return err
+	}
+	entries, err := plan(src)
+	if err != nil {
+		return err
+	}
+	state, err := loadState(opts.StatePath)
+	if err != nil {
+		return err
+	}
+	reschedule(state, entries, time.Now())
+	stamps := modTimes(src.Files)
+	lastCheck := time.Now()
+
+	for {
+		now := time.Now()
+		if tiers := dueTiers(entries, state, now); len(tiers) > 0 {
+			for _, t := range tiers {
+				started := time.Now()
+				fmt.Printf("⏰ Cycle %s: %s\n", t.tier, strings.Join(t.bookies, ", "))
+				opts.Run(ctx, t.tier, t.bookies)
+				for _, e := range entries {
+					if contains(t.bookies, e.Bookie) {
+						state.Bookies[e.Bookie] = BookieState{LastRun: started, Next: e.Next(started), Schedule: e.Spec}
+					}
+				}
+				if err := saveState(opts.StatePath, state); err != nil {
+					fmt.Printf("⚠️ Failed to save daemon state: %v\n", err)
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+			}
+			continue
+		}
+
+		reload := reloadInterval(src.Settings)
+		wait := reload - now.Sub(lastCheck)
+		if next, ok := nextDue(entries, state); ok && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+		select {
+		case <-ctx.Done():
+			return saveState(opts.StatePath, state)
+		case <-time.After(wait):
+		}
+
+		if time.Since(lastCheck) < reload {
+			continue
+		}
+		lastCheck = time.Now()
+		current := modTimes(src.Files)
+		if sameTimes(stamps, current) {
+			continue
+		}
+		stamps = current
+
+		next, err := opts.Load()
+		if err == nil {
+			var nextEntries []Entry
+			if nextEntries, err = plan(next); err == nil {
+				src, entries = next, nextEntries
+				stamps = modTimes(src.Files)
+				reschedule(state, entries, time.Now())
+				fmt.Printf("🔁 Reloaded: %d bookie(s) scheduled\n", len(entries))
+			}
+		}
+		if err != nil {
+			fmt.Printf("⚠️ Keeping the previous schedule, reload failed: %v\n", err)
+		}
+	}
+}
+
+// plan resolves a source and reports the bookies left unscheduled
+func plan(src Source) ([]Entry, error) {
+	entries, unscheduled, err := Plan(src.Settings, src.Bookies)
+	if err != nil {
+		return nil, err
+	}
+	if len(unscheduled) > 0 {
+		fmt.Printf("⚠️ No tier takes %s; add a tier without bookies to schedule them\n", strings.Join(unscheduled, ", "))
+	}
+	for _, e := range entries {
+		fmt.Printf("🗓️ %s: %s (%s)\n", e.Bookie, e.Spec, e.Tier)
+	}
+	return entries, nil
+}
+
+// reschedule keeps the due time of bookies whose schedule did not change.
+// A bookie that never ran is due now; one whose schedule changed is due on
+// the new schedule from its last run.
+func reschedule(state State, entries []Entry, now time.Time) {
+	for _, e := range entries {
+		s, ok := state.Bookies[e.Bookie]
+		switch {
+		case !ok || s.LastRun.IsZero():
+			state.Bookies[e.Bookie] = BookieState{Next: now, Schedule: e.Spec}
+		case s.Schedule != e.Spec:
+			s.Next, s.Schedule = e.Next(s.LastRun), e.Spec
+			state.Bookies[e.Bookie] = s
+		}
+	}
+}
+
+// dueTier is the bookies of one tier that are due in a cycle
+type dueTier struct {
+	tier    string
+	bookies []string
+}
+
+// dueTiers groups the due bookies by tier, highest priority first
+func dueTiers(entries []Entry, state State, now time.Time) []dueTier {
+	var due []Entry
+	for _, e := range entries {
+		if next := state.Bookies[e.Bookie].Next; !next.IsZero() && !next.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].Priority < due[j].Priority })
+
+	var tiers []dueTier
+	for i, e := range due {
+		if i == 0 || e.Priority != due[i-1].Priority {
+			tiers = append(tiers, dueTier{tier: e.Tier})
+		}
+		tiers[len(tiers)-1].bookies = append(tiers[len(tiers)-1].bookies, e.Bookie)
+	}
+	return tiers
+}
+
+// nextDue returns the earliest time a bookie is due
+func nextDue(entries []Entry, state State) (time.Time, bool) {
+	var earliest time.Time
+	for _, e := range entries {
+		next := state.Bookies[e.Bookie].Next
+		if !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
+			earliest = next
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+func reloadInterval(settings config.DaemonSettings) time.Duration {
+	if d, err := time.ParseDuration(settings.Reload); err == nil && d > 0 {
+		return d
+	}
+	return DefaultReload
+}
+
+// modTimes records when each watched file last changed; missing files are zero
+func modTimes(files []string) map[string]time.Time {
+	times := map[string]time.Time{}
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			times[f] = info.ModTime()
+		} else {
+			times[f] = time.Time{}
+		}
+	}
+	return times
+}
+
+func sameTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for f, t := range a {
+		if !b[f].Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func loadState(path string) (State, error) {
+	s := State{Bookies: map[string]BookieState{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if s.Bookies == nil {
+		s.Bookies = map[string]BookieState{}
+	}
+	return s, nil
+}
+
+func saveState(path string, s State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
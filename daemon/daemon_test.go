@@ -0,0 +1,127 @@
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"diago/config"
+)
+
+func tieredSettings() config.DaemonSettings {
+	return config.DaemonSettings{Tiers: []config.Tier{
+		{Name: "top", Bookies: []string{"betika", "sportpesa"}, Schedule: config.Schedule{Every: "1m"}},
+		{Name: "rest", Schedule: config.Schedule{Every: "1h"}},
+	}}
+}
+
+func TestDueTiers(t *testing.T) {
+	entries, _, err := Plan(tieredSettings(), []string{"betway", "betika", "odibets", "sportpesa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	state := func(due ...string) State {
+		s := State{Bookies: map[string]BookieState{}}
+		for _, e := range entries {
+			s.Bookies[e.Bookie] = BookieState{Next: now.Add(time.Minute)}
+		}
+		for _, b := range due {
+			s.Bookies[b] = BookieState{Next: now.Add(-time.Second)}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		due  []string
+		want []dueTier
+	}{
+		{"nothing due", nil, nil},
+		{"one tier", []string{"betway"}, []dueTier{{"rest", []string{"betway"}}}},
+		// The top tier being due must not keep the lower one waiting
+		{"every due tier, top first", []string{"odibets", "sportpesa", "betika"}, []dueTier{
+			{"top", []string{"betika", "sportpesa"}},
+			{"rest", []string{"odibets"}},
+		}},
+	}
+	for _, tt := range tests {
+		if got := dueTiers(entries, state(tt.due...), now); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: dueTiers = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunChecksEveryDueTier(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "daemon", "state.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cycles []string
+	opts := Options{
+		Load: func() (Source, error) {
+			return Source{Bookies: []string{"betway", "betika", "sportpesa"}, Settings: tieredSettings()}, nil
+		},
+		Run: func(_ context.Context, tier string, bookies []string) {
+			cycles = append(cycles, tier+": "+strings.Join(bookies, ","))
+			if tier == "rest" {
+				cancel()
+			}
+		},
+		StatePath: statePath,
+	}
+	before := time.Now()
+	if err := Run(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"top: betika,sportpesa", "rest: betway"}; !reflect.DeepEqual(cycles, want) {
+		t.Errorf("cycles = %q, want %q", cycles, want)
+	}
+	state, err := loadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for bookie, every := range map[string]time.Duration{"betika": time.Minute, "sportpesa": time.Minute, "betway": time.Hour} {
+		s := state.Bookies[bookie]
+		if s.LastRun.Before(before) || !s.Next.Equal(s.LastRun.Add(every)) {
+			t.Errorf("%s: state %+v, want due %v after its run", bookie, s, every)
+		}
+	}
+}
+
+// A restart keeps the due times of the saved state
+func TestRunResumesFromState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	now := time.Now()
+	saved := State{Bookies: map[string]BookieState{
+		"betika":    {LastRun: now.Add(-2 * time.Minute), Next: now.Add(-time.Minute), Schedule: "every 1m"},
+		"sportpesa": {LastRun: now, Next: now.Add(time.Minute), Schedule: "every 1m"},
+		"betway":    {LastRun: now, Next: now.Add(time.Hour), Schedule: "every 1h"},
+	}}
+	if err := saveState(statePath, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran []string
+	err := Run(ctx, Options{
+		Load: func() (Source, error) {
+			return Source{Bookies: []string{"betway", "betika", "sportpesa"}, Settings: tieredSettings()}, nil
+		},
+		Run: func(_ context.Context, _ string, bookies []string) {
+			ran = append(ran, bookies...)
+			cancel()
+		},
+		StatePath: statePath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ran, []string{"betika"}) {
+		t.Errorf("ran %v, want only the overdue betika", ran)
+	}
+}
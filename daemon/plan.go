@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"diago/config"
+)
+
+// Entry is how one bookie is scheduled
+type Entry struct {
+	Bookie string
+	Tier   string
+	// Priority is the tier's position; lower runs first when several tiers are due
+	Priority int
+	// Spec describes the schedule, to notice when a reload changed it
+	Spec string
+
+	next   func(time.Time) time.Time
+	jitter time.Duration
+}
+
+// Next returns when the bookie is due again after a run started at t,
+// delayed by a random jitter
+func (e Entry) Next(t time.Time) time.Time {
+	next := e.next(t)
+	if e.jitter > 0 && !next.IsZero() {
+		next = next.Add(rand.N(e.jitter))
+	}
+	return next
+}
+
+// Plan resolves the tier and schedule of every bookie, in manifest order.
+// Bookies no tier takes are returned as unscheduled.
+func Plan(settings config.DaemonSettings, bookies []string) (entries []Entry, unscheduled []string, err error) {
+	if err := Validate(settings); err != nil {
+		return nil, nil, err
+	}
+
+	for _, bookie := range bookies {
+		priority := tierOf(settings.Tiers, bookie)
+		if priority < 0 {
+			unscheduled = append(unscheduled, bookie)
+			continue
+		}
+		tier := settings.Tiers[priority]
+		schedule := tier.Schedule
+		for name, s := range settings.Schedules {
+			if strings.EqualFold(name, bookie) {
+				schedule = s
+			}
+		}
+
+		e := Entry{Bookie: bookie, Tier: tier.Name, Priority: priority, Spec: specOf(schedule)}
+		if e.next, e.jitter, err = parseSchedule(schedule); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", bookie, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, unscheduled, nil
+}
+
+// tierOf returns the index of the first tier listing the bookie, or else
+// of the first tier listing none; -1 when no tier takes it
+func tierOf(tiers []config.Tier, bookie string) int {
+	catchAll := -1
+	for i, t := range tiers {
+		if len(t.Bookies) == 0 && catchAll < 0 {
+			catchAll = i
+		}
+		for _, b := range t.Bookies {
+			if strings.EqualFold(b, bookie) {
+				return i
+			}
+		}
+	}
+	return catchAll
+}
+
+// Validate checks every tier and schedule
+func Validate(settings config.DaemonSettings) error {
+	if settings.Reload != "" {
+		if _, err := time.ParseDuration(settings.Reload); err != nil {
+			return fmt.Errorf("invalid reload %q: %w", settings.Reload, err)
+		}
+	}
+	if len(settings.Tiers) == 0 {
+		return fmt.Errorf("no tiers configured")
+	}
+	names := map[string]bool{}
+	for _, t := range settings.Tiers {
+		if t.Name == "" || names[t.Name] {
+			return fmt.Errorf("tiers need a unique name (%q)", t.Name)
+		}
+		names[t.Name] = true
+		if _, _, err := parseSchedule(t.Schedule); err != nil {
+			return fmt.Errorf("tier %s: %w", t.Name, err)
+		}
+	}
+	for bookie, s := range settings.Schedules {
+		if _, _, err := parseSchedule(s); err != nil {
+			return fmt.Errorf("schedule of %s: %w", bookie, err)
+		}
+	}
+	return nil
+}
+
+// parseSchedule turns an interval or cron expression into a function
+// returning the next due time
+func parseSchedule(s config.Schedule) (func(time.Time) time.Time, time.Duration, error) {
+	var jitter time.Duration
+	if s.Jitter != "" {
+		var err error
+		if jitter, err = time.ParseDuration(s.Jitter); err != nil || jitter < 0 {
+			return nil, 0, fmt.Errorf("invalid jitter %q", s.Jitter)
+		}
+	}
+
+	switch {
+	case s.Every != "" && s.Cron != "":
+		return nil, 0, fmt.Errorf("set either every or cron, not both")
+	case s.Every != "":
+		every, err := time.ParseDuration(s.Every)
+		if err != nil || every < time.Minute {
+			return nil, 0, fmt.Errorf("every %q must be a duration of at least 1m", s.Every)
+		}
+		return func(t time.Time) time.Time { return t.Add(every) }, jitter, nil
+	case s.Cron != "":
+		c, err := ParseCron(s.Cron)
+		if err != nil {
+			return nil, 0, err
+		}
+		if c.Next(time.Now()).IsZero() {
+			return nil, 0, fmt.Errorf("cron %q never matches", s.Cron)
+		}
+		return c.Next, jitter, nil
+	default:
+		return nil, 0, fmt.Errorf("needs every or cron")
+	}
+}
+
+func specOf(s config.Schedule) string {
+	spec := "every " + s.Every
+	if s.Cron != "" {
+		spec = "cron " + s.Cron
+	}
+	if s.Jitter != "" {
+		spec += " ±" + s.Jitter
+	}
+	return spec
+}
@@ -0,0 +1,297 @@
+package discover
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"diago/config"
+	"diago/fetch"
+	"diago/suggest"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxPages bounds how many pages of a bookie are crawled
+const DefaultMaxPages = 8
+
+// linkKeywords pick the links worth following from the landing page
+var linkKeywords = []string{
+	"login", "signin", "sign-in", "ingia", "account", "register", "sport", "football",
+	"live", "betslip", "bet-slip", "history", "my-bets", "mybets", "promo", "bonus", "cashout", "balance", "wallet",
+}
+
+// Page is a crawled page of a bookie
+type Page struct {
+	URL string
+	Doc *goquery.Document
+}
+
+// Finding is the discovered selector for one config field. An empty
+// Selector means nothing convincing was found.
+type Finding struct {
+	Label      string
+	Path       []string
+	Selector   string
+	Confidence float64
+	Page       string
+	Reason     string
+}
+
+// Crawl fetches the landing page and up to maxPages-1 same-site pages linked
+// from it whose URL or text suggests login, sports, bet slip or account flows.
+// Requests go through opts.Client, or fetch.Client when it is nil, and
+// progress goes to opts.Output.
+func Crawl(opts fetch.Options, baseURL string, maxPages int) ([]Page, error) {
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	start, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL %q: %w", baseURL, err)
+	}
+
+	landing, err := opts.Get(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	doc := landing.Doc
+	pages := []Page{{URL: baseURL, Doc: doc}}
+
+	seen := map[string]bool{strings.TrimSuffix(start.String(), "/"): true}
+	var links []string
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		u, err := start.Parse(href)
+		if err != nil || u.Host != start.Host || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		key := strings.TrimSuffix(u.String(), "/")
+		if seen[key] {
+			return
+		}
+		hint := strings.ToLower(u.Path + " " + a.Text())
+		for _, kw := range linkKeywords {
+			if strings.Contains(hint, kw) {
+				seen[key] = true
+				links = append(links, u.String())
+				return
+			}
+		}
+	})
+
+	for _, link := range links {
+		if len(pages) >= maxPages {
+			break
+		}
+		logf(opts.Output, "🔗 Crawling %s\n", link)
+		page, err := opts.Get(link)
+		if err != nil {
+			logf(opts.Output, "⚠️ Skipping %s: %v\n", link, err)
+			continue
+		}
+		pages = append(pages, Page{URL: link, Doc: page.Doc})
+	}
+	return pages, nil
+}
+
+// logf writes progress to out, if any
+func logf(out io.Writer, format string, args ...any) {
+	if out != nil {
+		fmt.Fprintf(out, format, args...)
+	}
+}
+
+// Discover picks the most confident candidate for every selector field
+// across the crawled pages. Fields whose best candidate is below
+// minConfidence are returned with an empty selector.
+func Discover(pages []Page, fields []config.SelectorField, minConfidence float64) []Finding {
+	best := make([]Finding, len(fields))
+	for i, f := range fields {
+		best[i] = Finding{Label: f.Label, Path: f.Path}
+	}
+
+	for _, page := range pages {
+		// First pass without anchors, then rescore with the confident
+		// picks of each section as siblings
+		first := make([]string, len(fields))
+		siblings := map[string][]*html.Node{}
+		for i, f := range fields {
+			cands := suggest.Candidates(page.Doc, f.Label, nil)
+			if len(cands) == 0 || cands[0].Confidence < minConfidence {
+				continue
+			}
+			first[i] = cands[0].Selector
+			if m := page.Doc.Find(cands[0].Selector); m.Length() > 0 {
+				siblings[f.Section()] = append(siblings[f.Section()], m.Get(0))
+			}
+		}
+
+		for i, f := range fields {
+			if first[i] == "" && len(siblings[f.Section()]) == 0 {
+				continue
+			}
+			cands := suggest.Candidates(page.Doc, f.Label, siblings[f.Section()])
+			if len(cands) == 0 || cands[0].Confidence <= best[i].Confidence {
+				continue
+			}
+			best[i].Selector = cands[0].Selector
+			best[i].Confidence = cands[0].Confidence
+			best[i].Reason = cands[0].Reason
+			best[i].Page = page.URL
+		}
+	}
+
+	// An element can only be one field of a section; keep the most confident claim
+	claims := map[string]int{}
+	for i, f := range fields {
+		if best[i].Selector == "" {
+			continue
+		}
+		key := f.Section() + " " + best[i].Selector
+		if j, ok := claims[key]; ok {
+			if best[i].Confidence > best[j].Confidence {
+				best[j].Selector = ""
+				best[j].Reason = "same element as " + f.Label
+				claims[key] = i
+			} else {
+				best[i].Selector = ""
+				best[i].Reason = "same element as " + fields[j].Label
+			}
+			continue
+		}
+		claims[key] = i
+	}
+
+	for i := range best {
+		if best[i].Confidence > 0 && best[i].Confidence < minConfidence {
+			best[i].Selector = ""
+			best[i].Reason = fmt.Sprintf("best candidate %.2f below threshold", best[i].Confidence)
+		}
+	}
+	return best
+}
+
+// comment describes a finding for the YAML line comment
+func (f Finding) comment() string {
+	if f.Selector == "" {
+		if f.Reason != "" {
+			return fmt.Sprintf("⚠️ not found (%s) – fill in manually", f.Reason)
+		}
+		return "⚠️ not found – fill in manually"
+	}
+	return fmt.Sprintf("confidence %.2f on %s: %s", f.Confidence, f.Page, f.Reason)
+}
+
+// OverridesNode renders findings as an overrides.yaml document for one
+// bookie, with the confidence of every field as a line comment.
+func OverridesNode(bookie string, findings []Finding) *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	bookieNode := &yaml.Node{Kind: yaml.MappingNode}
+	root.Content = append(root.Content, scalar(bookie), bookieNode)
+
+	for _, f := range findings {
+		setPath(bookieNode, f.Path, f.Selector, f.comment())
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+}
+
+// AnnotateConfig writes the findings into an encoded config.yaml document.
+// Only fields that are empty or still hold the generated placeholder are
+// filled, unless force is set; the others keep their selector. It returns
+// the labels of the fields it kept.
+func AnnotateConfig(doc *yaml.Node, findings []Finding, force bool) []string {
+	root := doc
+	if root.Kind == yaml.DocumentNode {
+		root = root.Content[0]
+	}
+	var kept []string
+	for _, f := range findings {
+		if css := primary(lookup(root, f.Path)); !force && css != "" && !config.IsPlaceholder(f.Label, css) {
+			kept = append(kept, f.Label)
+			continue
+		}
+		setPath(root, f.Path, f.Selector, f.comment())
+	}
+	return kept
+}
+
+// lookup returns the node at a nested mapping path, or nil
+func lookup(m *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		if m == nil || m.Kind != yaml.MappingNode {
+			return nil
+		}
+		var child *yaml.Node
+		for j := 0; j+1 < len(m.Content); j += 2 {
+			if m.Content[j].Value == key {
+				child = m.Content[j+1]
+				break
+			}
+		}
+		m = child
+	}
+	return m
+}
+
+// primary returns the primary selector of a selector node in any form
+func primary(n *yaml.Node) string {
+	switch {
+	case n == nil:
+		return ""
+	case n.Kind == yaml.ScalarNode:
+		return n.Value
+	case n.Kind == yaml.SequenceNode && len(n.Content) > 0:
+		return n.Content[0].Value
+	case n.Kind == yaml.MappingNode:
+		return primary(lookup(n, []string{"css"}))
+	}
+	return ""
+}
+
+// setPath sets the scalar at a nested mapping path, creating mappings as needed
+func setPath(m *yaml.Node, path []string, value, comment string) {
+	for i, key := range path {
+		var child *yaml.Node
+		for j := 0; j+1 < len(m.Content); j += 2 {
+			if m.Content[j].Value == key {
+				child = m.Content[j+1]
+				break
+			}
+		}
+
+		last := i == len(path)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			if last {
+				child = scalar("")
+			}
+			m.Content = append(m.Content, scalar(key), child)
+		}
+
+		if last {
+			switch {
+			case child.Kind == yaml.MappingNode:
+				// Keep assertions and severity of mapping-form selectors
+				setPath(child, []string{"css"}, value, comment)
+				return
+			case child.Kind == yaml.SequenceNode && len(child.Content) > 0:
+				// Keep the fallbacks of list-form selectors
+				child = child.Content[0]
+			}
+			*child = *scalar(value)
+			child.LineComment = comment
+			return
+		}
+		m = child
+	}
+}
+
+func scalar(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
@@ -0,0 +1,231 @@
+package discover
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"diago/config"
+	"diago/fetch"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v3"
+)
+
+const loginPage = `<html><body>
+<form id="login-form">
+  <input id="email" name="email" type="email">
+  <input id="pw" name="password" type="password">
+  <a href="/help">Log in help</a>
+</form>
+</body></html>`
+
+func page(t *testing.T, url, body string) Page {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Page{URL: url, Doc: doc}
+}
+
+func field(label string, path ...string) config.SelectorField {
+	return config.SelectorField{Label: label, Path: path}
+}
+
+func TestDiscover(t *testing.T) {
+	fields := []config.SelectorField{
+		field("Login.UsernameInput", "selectors", "login", "username_input"),
+		field("Login.EmailInput", "selectors", "login", "email_input"),
+		field("Login.PasswordInput", "selectors", "login", "password_input"),
+		field("Login.LoginButton", "selectors", "login", "login_button"),
+		field("BetButton", "bet_button"),
+	}
+	pages := []Page{page(t, "https://bookie.test/", `<p>Welcome</p>`), page(t, "https://bookie.test/login", loginPage)}
+	found := Discover(pages, fields, 0.7)
+
+	tests := []struct {
+		label    string
+		selector string
+		reason   string
+	}{
+		// The email input fits both fields; the more confident claim keeps it
+		{"Login.UsernameInput", "", "same element as Login.EmailInput"},
+		{"Login.EmailInput", "#email", ""},
+		{"Login.PasswordInput", "#pw", ""},
+		// The help link is only a weak match, found through its sibling fields
+		{"Login.LoginButton", "", "below threshold"},
+		{"BetButton", "", ""},
+	}
+	for i, tt := range tests {
+		f := found[i]
+		if f.Label != tt.label || f.Selector != tt.selector || !strings.Contains(f.Reason, tt.reason) {
+			t.Errorf("finding %d = %+v, want %s = %q (%s)", i, f, tt.label, tt.selector, tt.reason)
+		}
+		if f.Selector != "" && (f.Page != "https://bookie.test/login" || f.Confidence < 0.7) {
+			t.Errorf("%s found on %s with confidence %.2f", f.Label, f.Page, f.Confidence)
+		}
+	}
+}
+
+// decode parses a YAML document into its root mapping
+func decode(t *testing.T, src string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.Content[0]
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want config.Selector
+	}{
+		{"scalar", "login_button: button#login", config.Selector{CSS: "#new"}},
+		{"empty scalar", "login_button: ''", config.Selector{CSS: "#new"}},
+		{"missing", "other: x", config.Selector{CSS: "#new"}},
+		{"sequence keeps fallbacks", "login_button:\n  - button#login\n  - .login\n  - .m-login",
+			config.Selector{CSS: "#new", Fallbacks: []string{".login", ".m-login"}}},
+		{"mapping keeps its settings", "login_button:\n  css: button#login\n  fallbacks: [.login]\n  severity: critical\n  assert:\n    unique: true",
+			config.Selector{CSS: "#new", Fallbacks: []string{".login"}, Severity: "critical", Assert: config.Assertions{Unique: true}}},
+	}
+	for _, tt := range tests {
+		root := decode(t, tt.src)
+		setPath(root, []string{"login_button"}, "#new", "found")
+
+		var got config.Selector
+		if err := lookup(root, []string{"login_button"}).Decode(&got); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: selector = %+v, want %+v", tt.name, got, tt.want)
+		}
+		out, _ := yaml.Marshal(root)
+		if !strings.Contains(string(out), "'#new' # found") {
+			t.Errorf("%s: comment is not on the selector:\n%s", tt.name, out)
+		}
+	}
+
+	// Paths are created as needed
+	root := decode(t, "name: Betway")
+	setPath(root, []string{"selectors", "login", "otp_input"}, "#otp", "")
+	if got := primary(lookup(root, []string{"selectors", "login", "otp_input"})); got != "#otp" {
+		t.Errorf("created selector = %q", got)
+	}
+}
+
+func TestAnnotateConfig(t *testing.T) {
+	const src = `selectors:
+  login:
+    username_input: input#username
+    password_input: [input#password, .pw]
+    login_button: {css: button#login, severity: critical}
+    otp_input: ''
+  dashboard: div.my-dashboard
+  user_menu:
+    logout_button: a.logout
+bet_button: button#placeBet
+`
+	findings := []Finding{
+		{Label: "Login.UsernameInput", Path: []string{"selectors", "login", "username_input"}, Selector: "#user", Confidence: 0.9},
+		{Label: "Login.PasswordInput", Path: []string{"selectors", "login", "password_input"}, Selector: "#pw", Confidence: 0.9},
+		{Label: "Login.LoginButton", Path: []string{"selectors", "login", "login_button"}, Selector: "#go", Confidence: 0.9},
+		{Label: "Login.OtpInput", Path: []string{"selectors", "login", "otp_input"}, Selector: "#otp", Confidence: 0.9},
+		{Label: "Dashboard", Path: []string{"selectors", "dashboard"}, Selector: "#dash", Confidence: 0.9},
+		{Label: "UserMenu.LogoutButton", Path: []string{"selectors", "user_menu", "logout_button"}},
+		{Label: "BetButton", Path: []string{"bet_button"}},
+	}
+
+	tests := []struct {
+		force bool
+		kept  []string
+		want  map[string]string
+	}{
+		{false, []string{"Dashboard", "UserMenu.LogoutButton"}, map[string]string{
+			"Login.UsernameInput":   "#user",
+			"Login.PasswordInput":   "#pw",
+			"Login.LoginButton":     "#go",
+			"Login.OtpInput":        "#otp",
+			"Dashboard":             "div.my-dashboard",
+			"UserMenu.LogoutButton": "a.logout",
+			"BetButton":             "",
+		}},
+		{true, nil, map[string]string{
+			"Login.UsernameInput":   "#user",
+			"Login.PasswordInput":   "#pw",
+			"Login.LoginButton":     "#go",
+			"Login.OtpInput":        "#otp",
+			"Dashboard":             "#dash",
+			"UserMenu.LogoutButton": "",
+			"BetButton":             "",
+		}},
+	}
+	for _, tt := range tests {
+		root := decode(t, src)
+		kept := AnnotateConfig(root, findings, tt.force)
+		if !reflect.DeepEqual(kept, tt.kept) {
+			t.Errorf("force=%v: kept %v, want %v", tt.force, kept, tt.kept)
+		}
+		for _, f := range findings {
+			if got := primary(lookup(root, f.Path)); got != tt.want[f.Label] {
+				t.Errorf("force=%v: %s = %q, want %q", tt.force, f.Label, got, tt.want[f.Label])
+			}
+		}
+
+		var cfg config.Sportsbook
+		if err := root.Decode(&cfg); err != nil {
+			t.Fatal(err)
+		}
+		if pw := cfg.Selectors.Login.PasswordInput; !reflect.DeepEqual(pw.Fallbacks, []string{".pw"}) {
+			t.Errorf("force=%v: password fallbacks = %v", tt.force, pw.Fallbacks)
+		}
+		if cfg.Selectors.Login.LoginButton.Severity != config.SeverityCritical {
+			t.Errorf("force=%v: login button lost its severity", tt.force)
+		}
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/login">Ingia</a><a href="/about">About us</a><a href="https://other.test/sports">Sports</a>
+				<a href="/sports#top">Sports</a><a href="/sports">Football</a><a href="/live">Live</a>`)
+		case "/login", "/sports":
+			fmt.Fprint(w, `<form></form>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	pages, err := Crawl(fetch.Options{Client: srv.Client(), Output: &out}, srv.URL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, p := range pages {
+		urls = append(urls, strings.TrimPrefix(p.URL, srv.URL))
+	}
+	// Unrelated and off-site links are skipped, and /live fails
+	if want := []string{"", "/login", "/sports"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("crawled %q, want %q", urls, want)
+	}
+	for _, want := range []string{"🔗 Crawling " + srv.URL + "/login", "⚠️ Skipping " + srv.URL + "/live"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if pages, err := Crawl(fetch.Options{Client: srv.Client()}, srv.URL, 2); err != nil || len(pages) != 2 {
+		t.Errorf("crawled %d pages (%v), want 2", len(pages), err)
+	}
+}
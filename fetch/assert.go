@@ -0,0 +1,125 @@
+package fetch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"diago/config"
+	"diago/locator"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// checkSelector runs a selector against the frame's document and returns a
+// message for every failed assertion. An empty result means the selector passed.
+func checkSelector(fr *Frame, sel config.Selector) []string {
+	matches, err := fr.find(sel.CSS)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	a := sel.Assert
+
+	failures := checkCount(matches.Length(), a)
+	if matches.Length() == 0 {
+		return failures
+	}
+
+	first := matches.First()
+	text := normalizeText(first.Text())
+
+	if a.TextEquals != "" && text != a.TextEquals {
+		failures = append(failures, fmt.Sprintf("text %q != %q", truncate(text, 40), a.TextEquals))
+	}
+	if a.TextContains != "" && !strings.Contains(strings.ToLower(text), strings.ToLower(a.TextContains)) {
+		failures = append(failures, fmt.Sprintf("text %q does not contain %q", truncate(text, 40), a.TextContains))
+	}
+	if a.TextMatches != "" {
+		if msg := matchRegex("text", a.TextMatches, text); msg != "" {
+			failures = append(failures, msg)
+		}
+	}
+
+	if a.InputType != "" {
+		inputType := strings.ToLower(first.AttrOr("type", "text"))
+		if goquery.NodeName(first) != "input" {
+			failures = append(failures, fmt.Sprintf("expected input, got <%s>", goquery.NodeName(first)))
+		} else if inputType != strings.ToLower(a.InputType) {
+			failures = append(failures, fmt.Sprintf("input type %q != %q", inputType, a.InputType))
+		}
+	}
+
+	for _, attr := range a.Attributes {
+		value, ok := first.Attr(attr.Name)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("attribute %q missing", attr.Name))
+			continue
+		}
+		if attr.Equals != "" && value != attr.Equals {
+			failures = append(failures, fmt.Sprintf("attribute %s=%q != %q", attr.Name, value, attr.Equals))
+		}
+		if attr.Matches != "" {
+			if msg := matchRegex("attribute "+attr.Name, attr.Matches, value); msg != "" {
+				failures = append(failures, msg)
+			}
+		}
+	}
+
+	return failures
+}
+
+// find runs a css:, xpath:, text: or role: locator against the document;
+// unprefixed selectors are CSS
+func find(doc *goquery.Document, sel string) (*goquery.Selection, error) {
+	l, err := locator.Compile(sel)
+	if err != nil {
+		return nil, err
+	}
+	return doc.FindNodes(l.Match(doc.Get(0))...), nil
+}
+
+// checkCount validates the number of matches against the count assertions
+func checkCount(n int, a config.Assertions) []string {
+	minCount := 1
+	if a.MinCount != nil {
+		minCount = *a.MinCount
+	}
+
+	switch {
+	case n == 0 && minCount > 0:
+		return []string{"no match"}
+	case a.Unique && n != 1:
+		return []string{fmt.Sprintf("expected a unique match, got %d", n)}
+	case n < minCount:
+		return []string{fmt.Sprintf("expected at least %d matches, got %d", minCount, n)}
+	case a.MaxCount != nil && n > *a.MaxCount:
+		return []string{fmt.Sprintf("expected at most %d matches, got %d", *a.MaxCount, n)}
+	}
+	return nil
+}
+
+// matchRegex returns a failure message when value does not match pattern
+func matchRegex(what, pattern, value string) string {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Sprintf("invalid %s regex %q: %v", what, pattern, err)
+	}
+	if !re.MatchString(value) {
+		return fmt.Sprintf("%s %q does not match /%s/", what, truncate(value, 40), pattern)
+	}
+	return ""
+}
+
+// normalizeText trims and collapses whitespace
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
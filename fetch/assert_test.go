@@ -0,0 +1,85 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+
+	"diago/config"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const assertPage = `<html><body>
+<ul><li class="item">One</li><li class="item">Two</li><li class="item">Three</li></ul>
+<h1 id="title">  Welcome   back </h1>
+<form><input id="pw" type="password" name="pw" autocomplete="current-password"><input id="q" name="q"></form>
+<a id="odds" href="/odds/123" data-market="1x2">Odds 2.50</a>
+</body></html>`
+
+// frameOf parses a page into an unindexed top frame
+func frameOf(t testing.TB, page string) *Frame {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Frame{Doc: doc}
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestCheckSelector(t *testing.T) {
+	fr := frameOf(t, assertPage)
+
+	tests := []struct {
+		name   string
+		css    string
+		assert config.Assertions
+		want   []string
+	}{
+		{"match", ".item", config.Assertions{}, nil},
+		{"no match", ".missing", config.Assertions{}, []string{"no match"}},
+		{"optional", ".missing", config.Assertions{MinCount: intPtr(0)}, nil},
+		{"min count", ".item", config.Assertions{MinCount: intPtr(3)}, nil},
+		{"below min count", ".item", config.Assertions{MinCount: intPtr(4)}, []string{"expected at least 4 matches, got 3"}},
+		{"max count", ".item", config.Assertions{MaxCount: intPtr(3)}, nil},
+		{"above max count", ".item", config.Assertions{MaxCount: intPtr(2)}, []string{"expected at most 2 matches, got 3"}},
+		{"exact count", ".item", config.Assertions{MinCount: intPtr(3), MaxCount: intPtr(3)}, nil},
+		{"unique", "#title", config.Assertions{Unique: true}, nil},
+		{"not unique", ".item", config.Assertions{Unique: true}, []string{"expected a unique match, got 3"}},
+		{"text equals", "#title", config.Assertions{TextEquals: "Welcome back"}, nil},
+		{"text differs", "#title", config.Assertions{TextEquals: "Welcome"}, []string{`text "Welcome back" != "Welcome"`}},
+		{"text contains", "#title", config.Assertions{TextContains: "BACK"}, nil},
+		{"text lacks", "#title", config.Assertions{TextContains: "goodbye"}, []string{`text "Welcome back" does not contain "goodbye"`}},
+		{"text matches", "#odds", config.Assertions{TextMatches: `\d+\.\d{2}$`}, nil},
+		{"text mismatch", "#odds", config.Assertions{TextMatches: `^\d+$`}, []string{`text "Odds 2.50" does not match /^\d+$/`}},
+		{"invalid regex", "#odds", config.Assertions{TextMatches: `(`}, []string{"invalid text regex \"(\": error parsing regexp: missing closing ): `(`"}},
+		{"input type", "#pw", config.Assertions{InputType: "password"}, nil},
+		{"default input type", "#q", config.Assertions{InputType: "password"}, []string{`input type "text" != "password"`}},
+		{"not an input", "#title", config.Assertions{InputType: "text"}, []string{"expected input, got <h1>"}},
+		{"attribute present", "#pw", config.Assertions{Attributes: []config.AttrAssertion{{Name: "autocomplete"}}}, nil},
+		{"attribute missing", "#q", config.Assertions{Attributes: []config.AttrAssertion{{Name: "autocomplete"}}}, []string{`attribute "autocomplete" missing`}},
+		{"attribute equals", "#odds", config.Assertions{Attributes: []config.AttrAssertion{{Name: "data-market", Equals: "1x2"}}}, nil},
+		{"attribute differs", "#odds", config.Assertions{Attributes: []config.AttrAssertion{{Name: "data-market", Equals: "ou"}}}, []string{`attribute data-market="1x2" != "ou"`}},
+		{"attribute matches", "#odds", config.Assertions{Attributes: []config.AttrAssertion{{Name: "href", Matches: `^/odds/\d+$`}}}, nil},
+		{"attribute mismatch", "#odds", config.Assertions{Attributes: []config.AttrAssertion{{Name: "href", Matches: `^https://`}}}, []string{`attribute href "/odds/123" does not match /^https:///`}},
+		{"several failures", "#odds", config.Assertions{TextEquals: "x", Attributes: []config.AttrAssertion{{Name: "rel"}}}, []string{`text "Odds 2.50" != "x"`, `attribute "rel" missing`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkSelector(fr, config.Selector{CSS: tt.css, Assert: tt.assert})
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("héllo wörld", 5); got != "héllo…" {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := truncate("short", 40); got != "short" {
+		t.Errorf("truncate = %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package fetch
+
+import (
+	"strings"
+
+	"diago/report"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Evidence limits keep report.json small on pages with huge matches
+const (
+	MaxEvidenceText    = 120
+	MaxEvidenceHTML    = 400
+	MaxEvidenceClasses = 6
+)
+
+// collectEvidence describes what css matched: the match count plus the tag,
+// id, classes, text, outer HTML and DOM path of the first match
+func collectEvidence(fr *Frame, css string) *report.Evidence {
+	matches, err := fr.find(css)
+	if err != nil || matches.Length() == 0 {
+		return nil
+	}
+
+	first := matches.First()
+	ev := &report.Evidence{
+		Count: matches.Length(),
+		Tag:   goquery.NodeName(first),
+		ID:    first.AttrOr("id", ""),
+		Text:  truncate(normalizeText(first.Text()), MaxEvidenceText),
+		Path:  domPath(first.Get(0)),
+	}
+
+	classes := strings.Fields(first.AttrOr("class", ""))
+	if len(classes) > MaxEvidenceClasses {
+		classes = classes[:MaxEvidenceClasses]
+	}
+	ev.Classes = classes
+
+	if outer, err := goquery.OuterHtml(first); err == nil {
+		ev.OuterHTML = truncate(outer, MaxEvidenceHTML)
+	}
+	return ev
+}
+
+// domPath renders the ancestors of n as "html > body > div#main > form.login"
+func domPath(n *html.Node) string {
+	var parts []string
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		part := n.Data
+		for _, a := range n.Attr {
+			if a.Key == "id" && a.Val != "" {
+				part += "#" + a.Val
+				break
+			}
+		}
+		if part == n.Data {
+			for _, a := range n.Attr {
+				if a.Key == "class" {
+					if c := strings.Fields(a.Val); len(c) > 0 {
+						part += "." + c[0]
+					}
+				}
+			}
+		}
+		parts = append([]string{part}, parts...)
+	}
+	return strings.Join(parts, " > ")
+}
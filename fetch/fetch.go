@@ -1,34 +1,77 @@
 package fetch
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"reflect"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"diago/api"
 	"diago/config"
+	"diago/fingerprint"
+	"diago/locator"
 	"diago/report"
+	"diago/suggest"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
 )
 
+// Client performs the requests of calls that are not given their own client
+var Client = &http.Client{Timeout: 10 * time.Second}
+
+// Page is a fetched document together with the response it came from
+type Page struct {
+	URL      string
+	FinalURL string
+	Status   int
+	Header   http.Header
+	HTML     []byte
+	Doc      *goquery.Document
+}
+
 // FetchPage fetches a URL and returns a parsed goquery document.
 func FetchPage(urlStr string) (*goquery.Document, error) {
+	page, err := Get(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return page.Doc, nil
+}
+
+// Get fetches a URL and keeps the raw HTML, headers and final URL after redirects.
+func Get(urlStr string) (*Page, error) {
+	return GetWithHeader(urlStr, nil)
+}
+
+// GetWithHeader fetches a URL sending extra headers, such as a device's user agent
+func GetWithHeader(urlStr string, header http.Header) (*Page, error) {
+	return get(context.Background(), Client, urlStr, header)
+}
+
+// get fetches a URL through client until ctx is done
+func get(ctx context.Context, client *http.Client, urlStr string, header http.Header) (*Page, error) {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL %q: %w", urlStr, err)
 	}
 	parsedURL.Fragment = ""
 
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	req, err := http.NewRequest("GET", parsedURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", parsedURL.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for %q: %w", parsedURL.String(), err)
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; FetchBot/2.0; +https://yourdomain.com/bot)")
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -37,96 +80,375 @@ func FetchPage(urlStr string) (*goquery.Document, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("received HTTP %d for %q", resp.StatusCode, parsedURL.String())
+		return nil, &StatusError{URL: parsedURL.String(), Status: resp.StatusCode}
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse response from %q: %w", parsedURL.String(), err)
+		return nil, fmt.Errorf("failed to read response from %q: %w", parsedURL.String(), err)
 	}
 
-	return doc, nil
+	return NewPage(parsedURL.String(), resp.Request.URL.String(), resp.StatusCode, resp.Header, body)
 }
 
-// VerifyBookieWithConfig checks all selectors dynamically from config.Sportsbook
+// StatusError is returned when a page answers with a non-2xx status
+type StatusError struct {
+	URL    string
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received HTTP %d for %q", e.Status, e.URL)
+}
+
+// NewPage parses raw HTML into a Page
+func NewPage(urlStr, finalURL string, status int, header http.Header, body []byte) (*Page, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response from %q: %w", urlStr, err)
+	}
+	return &Page{URL: urlStr, FinalURL: finalURL, Status: status, Header: header, HTML: body, Doc: doc}, nil
+}
+
+// Options tune how a bookie is verified
+type Options struct {
+	// Suggest searches the page for replacement candidates of failing selectors
+	Suggest bool
+	// Source loads the page instead of fetching it live, e.g. from a snapshot
+	Source func(bookie, url string) (*Page, error)
+	// OnPage is called with every page fetched, e.g. to store a snapshot
+	OnPage func(bookie string, page *Page)
+	// Header is sent with every page and frame request
+	Header http.Header
+	// Context cancels the requests of a run; nil never cancels
+	Context context.Context
+	// Client performs the page, frame and probe requests instead of the package Client
+	Client *http.Client
+	// Render loads live pages and frames instead of a plain GET, e.g. through
+	// a headless browser. Unlike Source, frames and API probes still run.
+	Render func(ctx context.Context, url string, header http.Header) (*Page, error)
+	// Output receives progress and warnings; nil prints nothing
+	Output io.Writer
+
+	// OnBookieStart, OnResult and OnBookieDone report progress as a bookie
+	// is verified, e.g. to stream it. Bookies are named by their report key,
+	// e.g. betway@mobile.
+	OnBookieStart func(bookie string)
+	OnResult      func(bookie string, r report.SelectorResult)
+	OnBookieDone  func(r report.BookieReport)
+}
+
+// client returns the client requests go through
+func (o Options) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return Client
+}
+
+// ctx returns the context requests are cancelled with
+func (o Options) ctx() context.Context {
+	if o.Context != nil {
+		return o.Context
+	}
+	return context.Background()
+}
+
+// Get loads a live page or frame through the renderer or the client
+func (o Options) Get(url string) (*Page, error) {
+	ctx := o.ctx()
+	if o.Render != nil {
+		return o.Render(ctx, url, o.Header)
+	}
+	return get(ctx, o.client(), url, o.Header)
+}
+
+// logf writes progress to the output, if any
+func (o Options) logf(format string, args ...any) {
+	if o.Output != nil {
+		fmt.Fprintf(o.Output, format, args...)
+	}
+}
+
+// started, result and done call the progress hooks that are set
+func (o Options) started(bookie string) {
+	if o.OnBookieStart != nil {
+		o.OnBookieStart(bookie)
+	}
+}
+
+func (o Options) result(bookie string, r report.SelectorResult) {
+	if o.OnResult != nil {
+		o.OnResult(bookie, r)
+	}
+}
+
+func (o Options) done(r report.BookieReport) {
+	if o.OnBookieDone != nil {
+		o.OnBookieDone(r)
+	}
+}
+
+// VerifyBookieWithConfig checks all selectors dynamically from config.Sportsbook,
+// printing progress to stdout
 func VerifyBookieWithConfig(name, url string, cfg *config.Sportsbook) report.BookieReport {
-	fmt.Printf("🔍 Checking %s at %s...\n", name, url)
+	return VerifyBookieWithOptions(name, url, cfg, Options{Output: os.Stdout})
+}
+
+// VerifyBookieWithOptions checks all selectors of a bookie with the given options
+func VerifyBookieWithOptions(name, url string, cfg *config.Sportsbook, opts Options) report.BookieReport {
+	opts.logf("🔍 Checking %s at %s...\n", name, url)
+	start := time.Now()
+	opts.started(name)
+
+	source := opts.Source
+	if source == nil {
+		source = func(_, u string) (*Page, error) { return opts.Get(u) }
+	}
 
-	doc, err := FetchPage(cfg.BaseURL)
+	page, err := source(name, cfg.BaseURL)
 	if err != nil {
-		return report.BookieReport{
+		// API probes do not depend on the page, so they still run
+		r := report.BookieReport{
 			Name:    name,
 			URL:     cfg.BaseURL,
 			AllPass: false,
-			Results: []report.SelectorResult{{Label: "Fetch error", Status: err.Error()}},
+			Results: append([]report.SelectorResult{{Label: report.FetchErrorLabel, Status: err.Error()}}, runProbes(cfg, opts)...),
+		}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			r.HTTPStatus = statusErr.Status
+		}
+		for _, res := range r.Results {
+			opts.result(name, res)
 		}
+		r.DurationMS = millis(time.Since(start))
+		r.ApplyScores()
+		opts.done(r)
+		return r
+	}
+	if opts.OnPage != nil {
+		opts.OnPage(name, page)
+	}
+	doc := page.Doc
+
+	// Snapshot replays only hold the top document, so frames are fetched live only
+	top := &Frame{URL: page.FinalURL, Doc: doc}
+	if opts.Source == nil {
+		loadFrames(top, cfg.BaseURL, cfg.Frames, cfg.Frames.Depth(), opts)
+	}
+
+	// Every selector is compiled once and matched in a single walk per document
+	selectors := selectorsOf(cfg)
+	for _, fr := range top.all() {
+		fr.index(selectors)
 	}
 
 	results := []report.SelectorResult{}
 	allPass := true
 
-	// Traverse all selector fields dynamically via reflection
-	traverseSelectors(reflect.ValueOf(cfg.Selectors), "", doc, &results, &allPass)
+	// Check every selector field, including the top-level BetButton and BetHistory
+	for _, field := range cfg.SelectorFields() {
+		if len(field.Selector.Alternatives()) == 0 {
+			continue
+		}
 
-	// Also check top-level selectors if present
-	topLevelChecks := map[string]string{
-		"BetButton":  cfg.BetButton,
-		"BetHistory": cfg.BetHistory,
-	}
-	for label, selector := range topLevelChecks {
-		if selector == "" {
+		severity := cfg.SeverityOf(field)
+		if severity == config.SeverityNA {
+			na := report.SelectorResult{
+				Label:    field.Label,
+				Status:   "➖",
+				Severity: string(severity),
+				Message:  "not applicable",
+			}
+			opts.result(name, na)
+			results = append(results, na)
 			continue
 		}
-		if doc.Find(selector).Length() > 0 {
-			results = append(results, report.SelectorResult{Label: label, Status: "✅"})
-		} else {
-			results = append(results, report.SelectorResult{Label: label, Status: "❌"})
+
+		checkStart := time.Now()
+		r := verifySelector(top, field.Label, *field.Selector)
+		r.Severity = string(severity)
+		r.DurationMS = millis(time.Since(checkStart))
+		if r.Status == "❌" {
 			allPass = false
 		}
+		opts.result(name, r)
+		results = append(results, r)
 	}
 
-	return report.BookieReport{
-		Name:    name,
-		URL:     cfg.BaseURL,
-		AllPass: allPass,
-		Results: results,
+	if opts.Suggest {
+		addSuggestions(top, results)
+	}
+
+	for _, r := range runProbes(cfg, opts) {
+		if r.Status == "❌" {
+			allPass = false
+		}
+		opts.result(name, r)
+		results = append(results, r)
+	}
+
+	fp := fingerprint.Compute(doc.Get(0))
+	r := report.BookieReport{
+		Name:        name,
+		URL:         cfg.BaseURL,
+		AllPass:     allPass,
+		Results:     results,
+		Fingerprint: &fp,
+		HTTPStatus:  page.Status,
+	}
+	for _, fr := range top.all()[1:] {
+		r.Frames = append(r.Frames, report.Frame{Path: fr.Path, URL: fr.URL})
+	}
+	r.DurationMS = millis(time.Since(start))
+	r.ApplyScores()
+	opts.done(r)
+	return r
+}
+
+// millis converts a duration to fractional milliseconds for the reports
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// VerifyBookieVariants verifies a bookie once per device profile. The
+// desktop report is returned with the other devices as its variants and
+// AllPass covering all of them. Bookies without devices are verified once.
+func VerifyBookieVariants(name string, cfg *config.Sportsbook, opts Options) report.BookieReport {
+	devices := cfg.DeviceNames()
+	if len(devices) == 0 {
+		return VerifyBookieWithOptions(name, cfg.BaseURL, cfg, opts)
+	}
+
+	var primary report.BookieReport
+	for i, device := range devices {
+		variant, err := cfg.Variant(device)
+		if err != nil {
+			opts.logf("⚠️ Skipping %s %s: %v\n", name, device, err)
+			continue
+		}
+
+		vopts := opts
+		vopts.Header = cfg.Device(device).Header()
+		for k, v := range opts.Header {
+			vopts.Header[k] = v
+		}
+
+		// Non-default devices get their own snapshot key, e.g. betway@mobile
+		key := name
+		if i > 0 {
+			key = name + "@" + device
+		}
+		r := VerifyBookieWithOptions(key, variant.BaseURL, variant, vopts)
+		r.Name = name
+		r.Device = device
+
+		if i == 0 {
+			primary = r
+			continue
+		}
+		primary.Variants = append(primary.Variants, r)
+		primary.AllPass = primary.AllPass && r.AllPass
 	}
+	return primary
 }
 
-// traverseSelectors recursively inspects nested structs and verifies each selector
-func traverseSelectors(v reflect.Value, prefix string, doc *goquery.Document, results *[]report.SelectorResult, allPass *bool) {
-	v = reflect.Indirect(v)
-	if !v.IsValid() {
-		return
+// runProbes checks the bookie's JSON API probes through the fetch client.
+// Snapshot replays hold no API responses, so probes are skipped there.
+func runProbes(cfg *config.Sportsbook, opts Options) []report.SelectorResult {
+	if opts.Source != nil {
+		return nil
+	}
+
+	var results []report.SelectorResult
+	for _, probe := range cfg.APIProbes {
+		start := time.Now()
+		r := api.Run(opts.ctx(), opts.client(), cfg, probe)
+		r.DurationMS = millis(time.Since(start))
+		r.Severity = string(probe.Severity)
+		if r.Severity == "" {
+			r.Severity = string(config.SeverityMajor)
+		}
+		results = append(results, r)
 	}
+	return results
+}
 
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		fieldType := t.Field(i)
-		label := fieldType.Name
+// verifySelector tries each alternative in priority order and reports the
+// first one that satisfies the assertions. A match on a fallback passes with
+// a warning so the chain can be promoted. Alternatives with a frame path are
+// checked inside that frame only; others are checked in the top document and
+// then in every loaded frame.
+func verifySelector(top *Frame, label string, sel config.Selector) report.SelectorResult {
+	alts := sel.Alternatives()
 
-		// Build hierarchical labels like "Login.UsernameInput"
-		fullLabel := label
-		if prefix != "" {
-			fullLabel = prefix + "." + label
+	var firstFailures []string
+	var firstEvidence *report.Evidence
+	for i, css := range alts {
+		framePath, inner := config.SplitFrames(css)
+		frames := top.all()
+		if len(framePath) > 0 {
+			frames = top.resolve(framePath)
 		}
 
-		switch field.Kind() {
-		case reflect.String:
-			selector := field.String()
-			if selector == "" {
-				continue
+		candidate := sel
+		candidate.CSS = inner
+		var failures []string
+		for j, fr := range frames {
+			f := checkSelector(fr, candidate)
+			if len(f) == 0 {
+				r := report.SelectorResult{Label: label, Status: "✅", Matched: css, Alternative: i + 1, Frame: fr.Path, Locator: locator.TypeOf(inner)}
+				r.Evidence = collectEvidence(fr, inner)
+				if i > 0 {
+					r.Status = "⚠️"
+					r.Message = fmt.Sprintf("only fallback %d/%d matched", i+1, len(alts))
+				}
+				return r
 			}
-			if doc.Find(selector).Length() > 0 {
-				*results = append(*results, report.SelectorResult{Label: fullLabel, Status: "✅"})
-			} else {
-				*results = append(*results, report.SelectorResult{Label: fullLabel, Status: "❌"})
-				*allPass = false
+			if j == 0 {
+				failures = f
+				if i == 0 {
+					firstEvidence = collectEvidence(fr, inner)
+				}
 			}
-		case reflect.Struct:
-			traverseSelectors(field, fullLabel, doc, results, allPass)
 		}
+		if len(frames) == 0 {
+			failures = []string{fmt.Sprintf("frame %s not found", strings.Join(framePath, " "+config.FrameSeparator+" "))}
+		}
+		if i == 0 {
+			firstFailures = failures
+		}
+	}
+
+	msg := strings.Join(firstFailures, "; ")
+	if len(alts) > 1 {
+		msg = fmt.Sprintf("none of %d alternatives passed; primary: %s", len(alts), msg)
+	}
+	// Evidence of the primary shows why its assertions failed, if it matched at all
+	_, primary := config.SplitFrames(alts[0])
+	return report.SelectorResult{Label: label, Status: "❌", Message: msg, Evidence: firstEvidence, Locator: locator.TypeOf(primary)}
+}
+
+// addSuggestions attaches replacement candidates to failing selectors, using
+// the elements matched by passing selectors of the same section as anchors
+func addSuggestions(top *Frame, results []report.SelectorResult) {
+	siblings := map[string][]*html.Node{}
+	for _, r := range results {
+		if r.Healthy() && r.Matched != "" && r.Frame == "" {
+			section := strings.SplitN(r.Label, ".", 2)[0]
+			if m, err := top.find(r.Matched); err == nil && m.Length() > 0 {
+				siblings[section] = append(siblings[section], m.Get(0))
+			}
+		}
+	}
+
+	for i, r := range results {
+		if r.Status != "❌" {
+			continue
+		}
+		section := strings.SplitN(r.Label, ".", 2)[0]
+		results[i].Suggestions = suggest.Candidates(top.Doc, r.Label, siblings[section])
 	}
 }
 
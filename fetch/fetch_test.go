@@ -0,0 +1,170 @@
+package fetch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"diago/config"
+	"diago/report"
+)
+
+func TestVerifyUnreachableBookieScoresZero(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Sportsbook{Name: "betway", BaseURL: srv.URL}
+	cfg.Selectors.Login.UsernameInput = config.NewSelector("#user")
+
+	r := VerifyBookieWithOptions(cfg.Name, cfg.BaseURL, cfg, Options{Client: srv.Client()})
+	if len(r.Results) != 1 || r.Results[0].Label != report.FetchErrorLabel {
+		t.Fatalf("Results = %+v, want a fetch error", r.Results)
+	}
+	if r.HTTPStatus != http.StatusServiceUnavailable {
+		t.Errorf("HTTPStatus = %d, want 503", r.HTTPStatus)
+	}
+	if r.AllPass || r.Score != 0 {
+		t.Errorf("AllPass = %v, Score = %v; an unreachable bookie must fail with score 0", r.AllPass, r.Score)
+	}
+}
+
+// resultsByLabel indexes a report's results
+func resultsByLabel(r report.BookieReport) map[string]report.SelectorResult {
+	out := map[string]report.SelectorResult{}
+	for _, res := range r.Results {
+		out[res.Label] = res
+	}
+	return out
+}
+
+func TestVerifyCollectsEvidence(t *testing.T) {
+	long := strings.Repeat("Karibu sana ", 40)
+	page := `<html><body><div id="main"><form class="login box">
+<input id="user" class="a b c d e f g h" name="phone">
+<button class="btn primary" type="submit">  Ingia
+   sasa </button>
+<button class="btn" type="button">Register</button>
+</form><p class="promo">` + long + `</p></div></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Sportsbook{Name: "betway", BaseURL: srv.URL}
+	cfg.Selectors.Login.UsernameInput = config.NewSelector("#user")
+	cfg.Selectors.Login.LoginButton = config.NewSelector("form .btn")
+	cfg.Selectors.Login.OtpInput = config.Selector{CSS: "button", Assert: config.Assertions{Unique: true}}
+	cfg.Selectors.Dashboard = config.NewSelector("p.promo")
+	cfg.Selectors.UserMenu.LogoutButton = config.NewSelector("#logout")
+
+	results := resultsByLabel(VerifyBookieWithOptions(cfg.Name, cfg.BaseURL, cfg, Options{Client: srv.Client()}))
+
+	user := results["Login.UsernameInput"].Evidence
+	if user == nil || user.Count != 1 || user.Tag != "input" || user.ID != "user" ||
+		!reflect.DeepEqual(user.Classes, []string{"a", "b", "c", "d", "e", "f"}) ||
+		user.Path != "html > body > div#main > form.login > input#user" ||
+		user.OuterHTML != `<input id="user" class="a b c d e f g h" name="phone"/>` {
+		t.Errorf("username evidence = %+v", user)
+	}
+
+	button := results["Login.LoginButton"].Evidence
+	if button == nil || button.Count != 2 || button.Tag != "button" || button.Text != "Ingia sasa" ||
+		button.ID != "" || button.Path != "html > body > div#main > form.login > button.btn" {
+		t.Errorf("login button evidence = %+v", button)
+	}
+
+	promo := results["Dashboard"].Evidence
+	if promo == nil || promo.Text != strings.TrimSpace(long)[:MaxEvidenceText]+"…" ||
+		len([]rune(promo.OuterHTML)) != MaxEvidenceHTML+1 || !strings.HasPrefix(promo.OuterHTML, `<p class="promo">Karibu`) {
+		t.Errorf("promo evidence = %+v", promo)
+	}
+
+	// A failed assertion still shows what the selector matched
+	otp := results["Login.OtpInput"]
+	if otp.Status != "❌" || otp.Evidence == nil || otp.Evidence.Count != 2 {
+		t.Errorf("otp result = %+v", otp)
+	}
+	if logout := results["UserMenu.LogoutButton"]; logout.Status != "❌" || logout.Evidence != nil {
+		t.Errorf("logout result = %+v, want no evidence without a match", logout)
+	}
+}
+
+func TestVerifySearchesFrames(t *testing.T) {
+	var blocked int
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		blocked++
+		fmt.Fprint(w, `<button class="odds">9.99</button>`)
+	}))
+	defer other.Close()
+	// Frames on other hosts are skipped unless allowed
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<input id="user"><iframe id="sportsbook" src="/sports"></iframe><iframe src="%s/ads"></iframe>`, otherURL)
+		case "/sports":
+			fmt.Fprint(w, `<input id="pw" type="password"><iframe name="odds" src="/odds"></iframe>`)
+		case "/odds":
+			fmt.Fprint(w, `<button class="odds">2.10</button><input id="user">`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Sportsbook{Name: "betway", BaseURL: srv.URL}
+	cfg.Selectors.Login.UsernameInput = config.NewSelector("#user")
+	cfg.Selectors.Login.PasswordInput = config.NewSelector("#pw")
+	cfg.Selectors.Login.LoginButton = config.NewSelector(`frame:#sportsbook >> frame:iframe[name="odds"] >> button.odds`)
+	cfg.Selectors.Login.OtpInput = config.NewSelector("frame:#sportsbook >> #user")
+	cfg.Selectors.Login.OtpSubmitButton = config.NewSelector("frame:#missing >> button")
+	cfg.Selectors.Dashboard = config.Selector{CSS: "frame:#sportsbook >> .dash", Fallbacks: []string{"button.odds"}}
+
+	r := VerifyBookieWithOptions(cfg.Name, cfg.BaseURL, cfg, Options{Client: srv.Client()})
+
+	odds := `#sportsbook >> iframe[name="odds"]`
+	wantFrames := []report.Frame{{Path: "#sportsbook", URL: srv.URL + "/sports"}, {Path: odds, URL: srv.URL + "/odds"}}
+	if !reflect.DeepEqual(r.Frames, wantFrames) {
+		t.Errorf("frames = %+v, want %+v", r.Frames, wantFrames)
+	}
+	if blocked != 0 {
+		t.Errorf("fetched a frame from another host %d times", blocked)
+	}
+
+	tests := []struct {
+		label, status, frame, message string
+	}{
+		// The top document is searched first
+		{"Login.UsernameInput", "✅", "", ""},
+		// Unscoped selectors fall back to the frames
+		{"Login.PasswordInput", "✅", "#sportsbook", ""},
+		{"Login.LoginButton", "✅", odds, ""},
+		// A frame path searches that frame only
+		{"Login.OtpInput", "❌", "", "no match"},
+		{"Login.OtpSubmitButton", "❌", "", "frame #missing not found"},
+		{"Dashboard", "⚠️", odds, "only fallback 2/2 matched"},
+	}
+	results := resultsByLabel(r)
+	for _, tt := range tests {
+		res := results[tt.label]
+		if res.Status != tt.status || res.Frame != tt.frame || !strings.Contains(res.Message, tt.message) {
+			t.Errorf("%s = %+v, want %s in frame %q (%s)", tt.label, res, tt.status, tt.frame, tt.message)
+		}
+	}
+	if ev := results["Login.LoginButton"].Evidence; ev == nil || ev.Text != "2.10" {
+		t.Errorf("frame evidence = %+v", ev)
+	}
+
+	// Allowed provider domains are loaded too
+	cfg.Frames.AllowDomains = []string{"localhost"}
+	r = VerifyBookieWithOptions(cfg.Name, cfg.BaseURL, cfg, Options{Client: srv.Client()})
+	if len(r.Frames) != 3 || blocked != 1 {
+		t.Errorf("frames = %+v, want the provider frame loaded", r.Frames)
+	}
+}
@@ -0,0 +1,99 @@
+package fetch
+
+import (
+	"net/url"
+
+	"diago/config"
+	"diago/locator"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Frame is a fetched document with the iframes loaded inside it. The top
+// document has an empty Path.
+type Frame struct {
+	// Path identifies the frame in frame: syntax, e.g. "#sportsbook >> #login"
+	Path     string
+	URL      string
+	Doc      *goquery.Document
+	Children []*Frame
+
+	node    *html.Node
+	matches map[string][]*html.Node
+}
+
+// loadFrames fetches the iframes of f that the bookie's frame settings
+// allow, recursing until depth is used up. Frames that fail to load are
+// skipped with a warning.
+func loadFrames(f *Frame, baseURL string, settings config.Frames, depth int, opts Options) {
+	if depth <= 0 {
+		return
+	}
+	parent, err := url.Parse(f.URL)
+	if err != nil {
+		return
+	}
+
+	f.Doc.Find("iframe[src]").Each(func(_ int, s *goquery.Selection) {
+		src, err := parent.Parse(s.AttrOr("src", ""))
+		if err != nil || !settings.Allows(baseURL, src.String()) {
+			return
+		}
+
+		page, err := opts.Get(src.String())
+		if err != nil {
+			opts.logf("⚠️ Skipping frame %s: %v\n", src, err)
+			return
+		}
+
+		child := &Frame{URL: page.FinalURL, Doc: page.Doc, node: s.Get(0)}
+		child.Path = frameID(s)
+		if f.Path != "" {
+			child.Path = f.Path + " " + config.FrameSeparator + " " + child.Path
+		}
+		f.Children = append(f.Children, child)
+		loadFrames(child, baseURL, settings, depth-1, opts)
+	})
+}
+
+// frameID returns a selector for an iframe, preferring id, then name, then src
+func frameID(s *goquery.Selection) string {
+	if id := s.AttrOr("id", ""); id != "" {
+		return "#" + locator.CSSIdent(id)
+	}
+	if name := s.AttrOr("name", ""); name != "" {
+		return "iframe[name=" + locator.CSSString(name) + "]"
+	}
+	return "iframe[src=" + locator.CSSString(s.AttrOr("src", "")) + "]"
+}
+
+// resolve returns the frames reached by following a frame path from f
+func (f *Frame) resolve(path []string) []*Frame {
+	frames := []*Frame{f}
+	for _, sel := range path {
+		var next []*Frame
+		for _, fr := range frames {
+			iframes, err := fr.find(sel)
+			if err != nil {
+				continue
+			}
+			for _, child := range fr.Children {
+				if iframes.IndexOfNode(child.node) >= 0 {
+					next = append(next, child)
+				}
+			}
+		}
+		frames = next
+	}
+	return frames
+}
+
+// all returns f and every frame below it, breadth first
+func (f *Frame) all() []*Frame {
+	out := []*Frame{f}
+	for i := 0; i < len(out); i++ {
+		out = append(out, out[i].Children...)
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"diago/config"
+	"diago/locator"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// selectorsOf lists every selector string a bookie's fields can run: each
+// alternative's inner locator and the frame selectors in its path
+func selectorsOf(cfg *config.Sportsbook) []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	for _, field := range cfg.SelectorFields() {
+		for _, alt := range field.Selector.Alternatives() {
+			frames, inner := config.SplitFrames(alt)
+			for _, fr := range frames {
+				add(fr)
+			}
+			add(inner)
+		}
+	}
+	return out
+}
+
+// index matches all selectors against the frame's document in one walk and
+// keeps the results for find. Selectors that do not compile are left out
+// so find reports their cached error.
+func (f *Frame) index(selectors []string) {
+	var sels []string
+	var locs []*locator.Locator
+	for _, s := range selectors {
+		if l, err := locator.Compile(s); err == nil {
+			sels = append(sels, s)
+			locs = append(locs, l)
+		}
+	}
+
+	f.matches = make(map[string][]*html.Node, len(sels))
+	for i, nodes := range locator.MatchAll(f.Doc.Get(0), locs) {
+		f.matches[sels[i]] = nodes
+	}
+}
+
+// find returns the elements a selector matches in the frame, from the index
+// when it was built and by a separate walk otherwise
+func (f *Frame) find(sel string) (*goquery.Selection, error) {
+	if nodes, ok := f.matches[sel]; ok {
+		return f.Doc.FindNodes(nodes...), nil
+	}
+	return find(f.Doc, sel)
+}
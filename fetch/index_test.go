@@ -0,0 +1,185 @@
+package fetch
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"diago/config"
+	"diago/locator"
+	"diago/report"
+
+	"gopkg.in/yaml.v3"
+)
+
+// benchConfig has selectors of every locator type, with fallbacks and
+// assertions, as a generated config has them
+const benchConfig = `
+name: bench
+base_url: https://bench.test/
+selectors:
+  login:
+    username_input: "#username"
+    password_input:
+      css: "input#password"
+      assert: {input_type: password, unique: true}
+    login_button: ["button.login-submit", "xpath://form[@id='login']//button", "role:button[name='Log in']"]
+  dashboard: "#dashboard"
+  user_menu:
+    logout_button: "text:Log out"
+    account_link: "nav a.account"
+  event_search:
+    sport_dropdown: "select[name=sport]"
+    search_button: "role:button[name*=search]"
+    event_results: "ul.events"
+    event_item:
+      css: "li.event"
+      assert: {min_count: 100}
+    event_title: ".event .title"
+    event_team: "xpath://li[@class='event'][1]//span[contains(@class, 'team')]"
+  odds_selector:
+    moneyline: ".odds .moneyline"
+    spread: [".odds .spread", ".odds .handicap"]
+    totals: "li.event button.totals"
+    odds_dropdown: "#odds-format"
+  bet_slip:
+    add_button: "button[data-action=add]"
+    stake_input: "#betslip input.stake"
+    potential_payout: {css: ".payout", assert: {text_matches: "^\\d+\\.\\d{2}$"}}
+bet_button: "#place-bet"
+bet_history: "a[href='/history']"
+`
+
+// largePage builds a page with n events, each with teams and odds buttons
+func largePage(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><nav><a class="account" href="/account">Account</a><a href="/history">History</a><a href="/logout">Log out</a></nav>
+<form id="login"><input id="username"><input id="password" type="password"><button class="login-submit">Log in</button></form>
+<div id="dashboard"><select name="sport"><option>Football</option></select><select id="odds-format"></select><button>Search events</button>
+<ul class="events">`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<li class="event" data-id="%d"><div class="title"><span class="team home">Home %d</span> v <span class="team away">Away %d</span></div>
+<div class="odds"><button class="moneyline">2.%02d</button><button class="handicap">-1.5</button><button class="totals">O 2.5</button><button data-action="add">+</button></div></li>`, i, i, i, i%100)
+	}
+	b.WriteString(`</ul></div><div id="betslip"><input class="stake"><span class="payout">12.50</span><button id="place-bet">Place bet</button></div></body></html>`)
+	return b.String()
+}
+
+func loadBenchConfig(t testing.TB) *config.Sportsbook {
+	t.Helper()
+	var cfg config.Sportsbook
+	if err := yaml.Unmarshal([]byte(benchConfig), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return &cfg
+}
+
+// verifyAll checks every selector field of cfg in the frame
+func verifyAll(fr *Frame, cfg *config.Sportsbook) []report.SelectorResult {
+	var results []report.SelectorResult
+	for _, field := range cfg.SelectorFields() {
+		if len(field.Selector.Alternatives()) > 0 {
+			results = append(results, verifySelector(fr, field.Label, *field.Selector))
+		}
+	}
+	return results
+}
+
+// The index is an optimisation only: results must match a walk per selector
+func TestIndexedVerifyMatchesUnindexed(t *testing.T) {
+	cfg := loadBenchConfig(t)
+	page := largePage(150)
+
+	plain := frameOf(t, page)
+	indexed := frameOf(t, page)
+	indexed.index(selectorsOf(cfg))
+
+	want := verifyAll(plain, cfg)
+	got := verifyAll(indexed, cfg)
+	if !reflect.DeepEqual(got, want) {
+		for i := range want {
+			if i < len(got) && !reflect.DeepEqual(got[i], want[i]) {
+				t.Errorf("%s: indexed %+v, unindexed %+v", want[i].Label, got[i], want[i])
+			}
+		}
+		t.Fatalf("indexed results differ")
+	}
+	for _, r := range want {
+		if r.Status == "❌" {
+			t.Errorf("%s failed on the benchmark page: %s", r.Label, r.Message)
+		}
+	}
+}
+
+func TestIndexedFindMatchesDocFind(t *testing.T) {
+	cfg := loadBenchConfig(t)
+	fr := frameOf(t, largePage(20))
+	sels := selectorsOf(cfg)
+	fr.index(sels)
+
+	for _, sel := range sels {
+		got, err := fr.find(sel)
+		if err != nil {
+			t.Fatalf("%s: %v", sel, err)
+		}
+		want, err := find(fr.Doc, sel)
+		if err != nil {
+			t.Fatalf("%s: %v", sel, err)
+		}
+		if !reflect.DeepEqual(got.Nodes, want.Nodes) {
+			t.Errorf("%s: indexed %d matches, unindexed %d", sel, got.Length(), want.Length())
+		}
+		if locator.TypeOf(sel) == locator.TypeCSS && !reflect.DeepEqual(want.Nodes, fr.Doc.Find(sel).Nodes) {
+			t.Errorf("%s: locator and doc.Find disagree", sel)
+		}
+	}
+}
+
+// BenchmarkVerify compares looking up every selector with its own walk
+// (doc.Find for CSS) against indexing all of them in one walk, on a page of
+// 2000 events: first the lookups alone, then the full selector checks
+func BenchmarkVerify(b *testing.B) {
+	cfg := loadBenchConfig(b)
+	page := largePage(2000)
+	sels := selectorsOf(cfg)
+
+	b.Run("find/per-selector", func(b *testing.B) {
+		fr := frameOf(b, page)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, sel := range sels {
+				if locator.TypeOf(sel) == locator.TypeCSS {
+					fr.Doc.Find(sel)
+				} else {
+					find(fr.Doc, sel)
+				}
+			}
+		}
+	})
+	b.Run("find/indexed", func(b *testing.B) {
+		fr := frameOf(b, page)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fr.index(sels)
+			for _, sel := range sels {
+				fr.find(sel)
+			}
+		}
+	})
+	b.Run("verify/per-selector", func(b *testing.B) {
+		fr := frameOf(b, page)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			verifyAll(fr, cfg)
+		}
+	})
+	b.Run("verify/indexed", func(b *testing.B) {
+		fr := frameOf(b, page)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fr.index(sels)
+			verifyAll(fr, cfg)
+		}
+	})
+}
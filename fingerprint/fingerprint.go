@@ -0,0 +1,183 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"diago/lint"
+
+	"golang.org/x/net/html"
+)
+
+// ShingleDepth is how many ancestors each structural path includes
+const ShingleDepth = 4
+
+// Fingerprint summarises the structure of a page: a hash of the normalised
+// tag/attribute tree plus counts of short ancestor paths used to measure
+// similarity between runs.
+type Fingerprint struct {
+	Hash     string         `json:"hash"`
+	Elements int            `json:"elements"`
+	Paths    map[string]int `json:"paths"`
+}
+
+// ignored elements carry no layout information
+var ignored = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"meta": true, "link": true, "head": true, "path": true, "g": true,
+}
+
+// Compute fingerprints the tree below root
+func Compute(root *html.Node) Fingerprint {
+	fp := Fingerprint{Paths: map[string]int{}}
+	h := sha256.New()
+
+	var walk func(n *html.Node, ancestors []string)
+	walk = func(n *html.Node, ancestors []string) {
+		if n.Type == html.ElementNode {
+			if ignored[n.Data] {
+				return
+			}
+			tok := token(n)
+			fp.Elements++
+			h.Write([]byte(strings.Repeat(" ", len(ancestors)) + tok + "\n"))
+
+			ancestors = append(ancestors, tok)
+			start := len(ancestors) - ShingleDepth
+			if start < 0 {
+				start = 0
+			}
+			fp.Paths[strings.Join(ancestors[start:], ">")]++
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, ancestors)
+		}
+	}
+	walk(root, nil)
+
+	fp.Hash = hex.EncodeToString(h.Sum(nil))[:16]
+	return fp
+}
+
+// token normalises an element to its tag, stable classes, role and sorted
+// attribute names. Attribute values, hashed classes and framework scoping
+// attributes are dropped so content changes do not count as drift.
+func token(n *html.Node) string {
+	var names, classes []string
+	role := ""
+	for _, a := range n.Attr {
+		switch {
+		case a.Key == "class":
+			for _, c := range strings.Fields(a.Val) {
+				if !lint.Hashed(c) {
+					classes = append(classes, c)
+				}
+			}
+		case a.Key == "role":
+			role = a.Val
+		case strings.HasPrefix(a.Key, "on"), strings.HasPrefix(a.Key, "data-v-"), lint.Hashed(a.Key):
+			continue
+		default:
+			names = append(names, a.Key)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(classes)
+
+	var b strings.Builder
+	b.WriteString(n.Data)
+	for _, c := range classes {
+		b.WriteString("." + c)
+	}
+	if role != "" {
+		b.WriteString("[role=" + role + "]")
+	}
+	if len(names) > 0 {
+		b.WriteString("[" + strings.Join(names, ",") + "]")
+	}
+	return b.String()
+}
+
+// Similarity is the weighted Jaccard similarity (0-1) of two fingerprints'
+// path counts; identical hashes are always 1
+func Similarity(a, b Fingerprint) float64 {
+	if a.Hash == b.Hash {
+		return 1
+	}
+	var inter, union int
+	for path, ca := range a.Paths {
+		cb := b.Paths[path]
+		inter += min(ca, cb)
+		union += max(ca, cb)
+	}
+	for path, cb := range b.Paths {
+		if _, ok := a.Paths[path]; !ok {
+			union += cb
+		}
+	}
+	if union == 0 {
+		return 1
+	}
+	return float64(inter) / float64(union)
+}
+
+// Run is one stored fingerprint observation
+type Run struct {
+	At         time.Time `json:"at"`
+	Hash       string    `json:"hash"`
+	Elements   int       `json:"elements"`
+	Similarity float64   `json:"similarity"`
+}
+
+// Store is the per-bookie fingerprint history kept in the output dir
+type Store struct {
+	Latest Fingerprint `json:"latest"`
+	Runs   []Run       `json:"runs"`
+}
+
+// Path returns where a bookie's fingerprint history is stored
+func Path(outputDir, bookie string) string {
+	return filepath.Join(outputDir, strings.ToLower(bookie), "fingerprint.json")
+}
+
+// Record compares fp with the previously stored fingerprint, appends the run
+// (keeping at most keep runs) and saves it. It returns the previous
+// fingerprint, or nil on the first run, and the similarity to it.
+func Record(path string, fp Fingerprint, keep int) (*Fingerprint, float64, error) {
+	var store Store
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &store); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	var prev *Fingerprint
+	similarity := 1.0
+	if store.Latest.Hash != "" {
+		p := store.Latest
+		prev = &p
+		similarity = Similarity(p, fp)
+	}
+
+	store.Latest = fp
+	store.Runs = append(store.Runs, Run{At: time.Now().UTC(), Hash: fp.Hash, Elements: fp.Elements, Similarity: similarity})
+	if keep > 0 && len(store.Runs) > keep {
+		store.Runs = store.Runs[len(store.Runs)-keep:]
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return prev, similarity, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return prev, similarity, err
+	}
+	return prev, similarity, os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,129 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"diago/config"
+
+	"golang.org/x/net/html"
+)
+
+const basePage = `<html><head><script>var x = 1</script></head><body>
+<header class="top"><nav><a href="/">Home</a><a href="/live">Live</a><a href="/login" class="sc-bdVaJa">Login</a></nav></header>
+<main>
+  <ul class="events">
+    <li class="event"><span class="team">Arsenal</span><span class="team">Chelsea</span><button class="odds">2.10</button></li>
+    <li class="event"><span class="team">Inter</span><span class="team">Milan</span><button class="odds">1.90</button></li>
+    <li class="event"><span class="team">Gor Mahia</span><span class="team">AFC Leopards</span><button class="odds">2.45</button></li>
+  </ul>
+  <form id="login"><input name="phone"><input name="pin" type="password"><button type="submit">Ingia</button></form>
+</main>
+<footer class="bottom"><p>18+</p></footer>
+</body></html>`
+
+func compute(t *testing.T, page string) Fingerprint {
+	t.Helper()
+	root, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Compute(root)
+}
+
+// Pages compared with basePage, at the default drift threshold
+var variants = []struct {
+	name  string
+	page  string
+	drift bool
+}{
+	{"identical", basePage, false},
+	// Text, attribute values, scripts and hashed classes do not count
+	{"content changed", strings.NewReplacer("Arsenal", "Spurs", "2.10", "3.05", `href="/live"`, `href="/in-play"`,
+		"sc-bdVaJa", "sc-kEYyzF", "var x = 1", "var x = 2").Replace(basePage), false},
+	{"one more event", strings.Replace(basePage, "</ul>",
+		`<li class="event"><span class="team">Yanga</span><span class="team">Simba</span><button class="odds">2.80</button></li></ul>`, 1), false},
+	{"restructured", `<html><body><div class="app"><div class="grid">
+<div class="card"><div class="row"><div class="cell">Arsenal</div><div class="cell">Chelsea</div></div><a role="button">2.10</a></div>
+<div class="card"><div class="row"><div class="cell">Inter</div><div class="cell">Milan</div></div><a role="button">1.90</a></div>
+</div><div class="login"><input name="phone"><input name="pin" type="password"><a role="button">Ingia</a></div></div></body></html>`, true},
+}
+
+func TestSimilarity(t *testing.T) {
+	base := compute(t, basePage)
+	threshold := config.DefaultSettings().Drift.Threshold
+
+	for _, v := range variants {
+		fp := compute(t, v.page)
+		s := Similarity(base, fp)
+		if s < 0 || s > 1 || s != Similarity(fp, base) {
+			t.Errorf("%s: similarity %.3f is not symmetric in [0, 1]", v.name, s)
+		}
+		if drift := s < threshold; drift != v.drift {
+			t.Errorf("%s: similarity %.3f, drift = %v, want %v", v.name, s, drift, v.drift)
+		}
+	}
+
+	if changed := compute(t, variants[1].page); changed.Hash != base.Hash {
+		t.Errorf("content changes altered the hash: %s != %s", changed.Hash, base.Hash)
+	}
+	if grown := compute(t, variants[2].page); grown.Hash == base.Hash || grown.Elements != base.Elements+4 {
+		t.Errorf("an extra event gave hash %s with %d elements (base %s, %d)", grown.Hash, grown.Elements, base.Hash, base.Elements)
+	}
+	if s := Similarity(Fingerprint{Hash: "a"}, Fingerprint{Hash: "b"}); s != 1 {
+		t.Errorf("empty fingerprints have similarity %.2f, want 1", s)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "betway", "fingerprint.json")
+	base := compute(t, basePage)
+
+	prev, s, err := Record(path, base, 3)
+	if err != nil || prev != nil || s != 1 {
+		t.Fatalf("first Record = %v, %.2f, %v; want no previous run", prev, s, err)
+	}
+
+	threshold := config.DefaultSettings().Drift.Threshold
+	for _, v := range variants {
+		fp := compute(t, v.page)
+		prev, s, err := Record(path, fp, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev == nil || prev.Hash != base.Hash {
+			t.Fatalf("%s: previous = %+v, want the base page", v.name, prev)
+		}
+		if drift := s < threshold; drift != v.drift {
+			t.Errorf("%s: similarity %.3f, drift = %v, want %v", v.name, s, drift, v.drift)
+		}
+		// Compare each variant with the base page again
+		if _, _, err := Record(path, base, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Runs) != 3 || store.Latest.Hash != base.Hash {
+		t.Errorf("store keeps %d runs with latest %s, want 3 ending with %s", len(store.Runs), store.Latest.Hash, base.Hash)
+	}
+	last := store.Runs[len(store.Runs)-2]
+	if last.Similarity >= threshold || last.Hash != compute(t, variants[3].page).Hash {
+		t.Errorf("restructured run = %+v", last)
+	}
+}
+
+func load(path string) (Store, error) {
+	var store Store
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return store, err
+	}
+	return store, json.Unmarshal(data, &store)
+}
@@ -0,0 +1,156 @@
+package har_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"diago/config"
+	"diago/fetch"
+	"diago/har"
+	"diago/report"
+)
+
+// bookieConfig checks a login form on the page, a bet button inside its
+// betslip iframe and a JSON API, so a verification takes several requests
+func bookieConfig(baseURL string) *config.Sportsbook {
+	one := 1
+	cfg := &config.Sportsbook{Name: "bookie", BaseURL: baseURL}
+	cfg.Selectors.Login.UsernameInput = config.NewSelector("#user")
+	cfg.Selectors.Login.PasswordInput = config.Selector{CSS: "#pw", Assert: config.Assertions{InputType: "password"}}
+	cfg.BetButton = config.NewSelector("frame:#betslip >> .place-bet")
+	cfg.APIProbes = []config.APIProbe{{
+		Name:   "events",
+		URL:    "{{base_url}}/api/events",
+		Assert: []config.JSONAssertion{{Path: "$.events", MinCount: &one}},
+	}}
+	return cfg
+}
+
+// replayClient answers from a HAR file only
+func replayClient(t *testing.T, f *har.File) *http.Client {
+	t.Helper()
+	return &http.Client{Transport: har.NewReplayer(f)}
+}
+
+func verify(cfg *config.Sportsbook, client *http.Client) report.BookieReport {
+	return fetch.VerifyBookieWithOptions(cfg.Name, cfg.BaseURL, cfg, fetch.Options{Client: client})
+}
+
+// statuses maps each result label to its status
+func statuses(r report.BookieReport) map[string]string {
+	out := map[string]string{}
+	for _, res := range r.Results {
+		out[res.Label] = res.Status
+	}
+	return out
+}
+
+// The fixture's host does not resolve, so any request that is not replayed fails
+func TestReplayVerifiesBookieOffline(t *testing.T) {
+	f, err := har.Load(filepath.Join("testdata", "bookie.har"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := bookieConfig("https://bookie.test/")
+
+	r := verify(cfg, replayClient(t, f))
+	if !r.AllPass {
+		t.Fatalf("replay failed: %+v", r.Results)
+	}
+	if r.HTTPStatus != http.StatusOK {
+		t.Errorf("HTTPStatus = %d, want 200 after the redirect", r.HTTPStatus)
+	}
+	if len(r.Frames) != 1 || r.Frames[0].URL != "https://bookie.test/frames/betslip" {
+		t.Errorf("Frames = %+v, want the betslip frame", r.Frames)
+	}
+	want := map[string]string{
+		"Login.UsernameInput": "✅",
+		"Login.PasswordInput": "✅",
+		"BetButton":           "✅",
+		"API.events":          "✅",
+	}
+	for label, status := range want {
+		if got := statuses(r)[label]; got != status {
+			t.Errorf("%s = %q, want %q", label, got, status)
+		}
+	}
+
+	// A second replay of the same file gives the same results
+	again := verify(cfg, replayClient(t, f))
+	if !reflect.DeepEqual(statuses(again), statuses(r)) {
+		t.Errorf("replays differ: %v vs %v", statuses(again), statuses(r))
+	}
+}
+
+func TestReplayMissingRequestFails(t *testing.T) {
+	f, err := har.Load(filepath.Join("testdata", "bookie.har"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := bookieConfig("https://bookie.test/elsewhere")
+	cfg.APIProbes = nil
+
+	r := verify(cfg, replayClient(t, f))
+	if r.AllPass || len(r.Results) != 1 || r.Results[0].Label != report.FetchErrorLabel {
+		t.Fatalf("want a fetch error, got %+v", r.Results)
+	}
+}
+
+// A recording taken against a live server replays the same verification
+// once that server is gone
+func TestRecordThenReplay(t *testing.T) {
+	var hits int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		hits++
+		switch req.URL.Path {
+		case "/":
+			http.Redirect(w, req, "/home", http.StatusFound)
+		case "/home":
+			fmt.Fprint(w, `<form><input id="user"><input id="pw" type="password"></form><iframe id="betslip" src="/frames/betslip"></iframe>`)
+		case "/frames/betslip":
+			fmt.Fprint(w, `<button class="place-bet">Place bet</button>`)
+		case "/api/events":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"events":[{"id":1}]}`)
+		default:
+			http.NotFound(w, req)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	cfg := bookieConfig(srv.URL + "/")
+
+	recorder := har.NewRecorder(nil)
+	live := verify(cfg, &http.Client{Transport: recorder})
+	srv.Close()
+	if !live.AllPass {
+		t.Fatalf("live run failed: %+v", live.Results)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.har")
+	if err := har.Save(path, recorder.HAR()); err != nil {
+		t.Fatal(err)
+	}
+	f, err := har.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Log.Entries) != hits {
+		t.Errorf("recorded %d entries for %d requests", len(f.Log.Entries), hits)
+	}
+	if f.Log.Entries[0].Response.Status != http.StatusFound || f.Log.Entries[0].Response.RedirectURL != "/home" {
+		t.Errorf("first entry = %d %q, want the redirect", f.Log.Entries[0].Response.Status, f.Log.Entries[0].Response.RedirectURL)
+	}
+
+	replayed := verify(cfg, replayClient(t, f))
+	if !reflect.DeepEqual(statuses(replayed), statuses(live)) {
+		t.Errorf("replay %v differs from live %v", statuses(replayed), statuses(live))
+	}
+	if replayed.HTTPStatus != live.HTTPStatus || len(replayed.Frames) != len(live.Frames) {
+		t.Errorf("replay status %d with %d frames, live %d with %d", replayed.HTTPStatus, len(replayed.Frames), live.HTTPStatus, len(live.Frames))
+	}
+}
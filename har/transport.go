@@ -0,0 +1,241 @@
+package har
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Recorder is an http.RoundTripper that passes requests to Transport and
+// records every exchange, including each hop of a redirect
+type Recorder struct {
+	Transport http.RoundTripper
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder records the traffic sent through transport; nil uses http.DefaultTransport
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{Transport: transport}
+}
+
+// RoundTrip performs the request and records it
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	wait := time.Since(start)
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	receive := time.Since(start) - wait
+
+	entry := Entry{
+		StartedDateTime: start.UTC(),
+		Time:            ms(wait + receive),
+		Request:         request(req, reqBody),
+		Response:        response(resp, body),
+		Timings:         Timings{Wait: ms(wait), Receive: ms(receive)},
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// HAR returns everything recorded so far, in request order
+func (r *Recorder) HAR() *File {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := append([]Entry{}, r.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+	return &File{Log: Log{
+		Version: Version,
+		Creator: Creator{Name: "diago", Version: Version},
+		Entries: entries,
+	}}
+}
+
+// Replayer is an http.RoundTripper that answers requests from a HAR file
+// without touching the network. Repeated requests for the same method, URL
+// and body get the recorded responses in order; once exhausted the last one
+// is repeated, so replays are deterministic.
+type Replayer struct {
+	mu      sync.Mutex
+	entries map[string][]Entry
+	served  map[string]int
+}
+
+// NewReplayer indexes the entries of a HAR file
+func NewReplayer(f *File) *Replayer {
+	r := &Replayer{entries: map[string][]Entry{}, served: map[string]int{}}
+	for _, e := range f.Log.Entries {
+		body := ""
+		if e.Request.PostData != nil {
+			body = e.Request.PostData.Text
+		}
+		k := key(e.Request.Method, e.Request.URL, body)
+		r.entries[k] = append(r.entries[k], e)
+	}
+	return r
+}
+
+// RoundTrip returns the recorded response for the request
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		body = string(data)
+	}
+
+	k := key(req.Method, req.URL.String(), body)
+	r.mu.Lock()
+	entries := r.entries[k]
+	i := r.served[k]
+	if i < len(entries)-1 {
+		r.served[k]++
+	}
+	r.mu.Unlock()
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	return entries[i].Response.http(req)
+}
+
+func key(method, url, body string) string {
+	return method + " " + url + "\n" + body
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// request converts an http.Request into its HAR form
+func request(req *http.Request, body []byte) Request {
+	r := Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Headers:     headers(req.Header),
+		QueryString: []NameValue{},
+		Cookies:     []NameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			r.QueryString = append(r.QueryString, NameValue{Name: name, Value: v})
+		}
+	}
+	sort.Slice(r.QueryString, func(i, j int) bool { return r.QueryString[i].Name < r.QueryString[j].Name })
+	for _, c := range req.Cookies() {
+		r.Cookies = append(r.Cookies, NameValue{Name: c.Name, Value: c.Value})
+	}
+	if len(body) > 0 {
+		r.PostData = &PostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+	return r
+}
+
+// response converts an http.Response into its HAR form
+func response(resp *http.Response, body []byte) Response {
+	r := Response{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Headers:     headers(resp.Header),
+		Cookies:     []NameValue{},
+		Content:     Content{Size: len(body), MimeType: resp.Header.Get("Content-Type")},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	for _, c := range resp.Cookies() {
+		r.Cookies = append(r.Cookies, NameValue{Name: c.Name, Value: c.Value})
+	}
+	if utf8.Valid(body) {
+		r.Content.Text = string(body)
+	} else {
+		r.Content.Text = base64.StdEncoding.EncodeToString(body)
+		r.Content.Encoding = "base64"
+	}
+	return r
+}
+
+// http rebuilds the recorded response for a replayed request
+func (r Response) http(req *http.Request) (*http.Response, error) {
+	body := []byte(r.Content.Text)
+	if r.Content.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(r.Content.Text); err != nil {
+			return nil, fmt.Errorf("failed to decode recorded body of %s: %w", req.URL, err)
+		}
+	}
+
+	header := http.Header{}
+	for _, h := range r.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	// The recorded body is already decoded
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, r.StatusText),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func headers(h http.Header) []NameValue {
+	out := []NameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			out = append(out, NameValue{Name: name, Value: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
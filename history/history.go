@@ -0,0 +1,211 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"diago/report"
+)
+
+// MaxLine bounds a single stored run; reports with evidence of 65 bookies
+// stay well below it
+const MaxLine = 64 << 20
+
+// Run is one stored fetch: when it ran, where, and its full report
+type Run struct {
+	ID        string            `json:"id"`
+	StartedAt time.Time         `json:"started_at"`
+	Commit    string            `json:"commit,omitempty"`
+	Report    report.FullReport `json:"report"`
+
+	// Selectors are the configured alternatives the run checked
+	Selectors report.Selectors `json:"selectors,omitempty"`
+}
+
+// Path returns the history file below the output dir
+func Path(outputDir string) string {
+	return filepath.Join(outputDir, "history", "runs.jsonl")
+}
+
+// NewRun wraps a report and the selectors it checked with their run
+// metadata. The commit is taken from GITHUB_SHA when running in CI.
+func NewRun(r report.FullReport, selectors report.Selectors) Run {
+	run := Run{ID: r.RunID, Commit: os.Getenv("GITHUB_SHA"), Report: Compact(r), Selectors: selectors}
+	if t, err := time.Parse(time.RFC3339, r.StartedAt); err == nil {
+		run.StartedAt = t.UTC()
+	} else {
+		run.StartedAt = time.Now().UTC()
+	}
+	if run.ID == "" {
+		run.ID = run.StartedAt.Format("20060102-150405.000")
+	}
+	return run
+}
+
+// Compact drops the parts of a report that are only useful for the run
+// itself, the outer HTML of matched elements and suggestions, so the
+// history stays small
+func Compact(r report.FullReport) report.FullReport {
+	r.Summary = nil
+	r.Details = compactBookies(r.Details)
+	return r
+}
+
+func compactBookies(bookies []report.BookieReport) []report.BookieReport {
+	out := make([]report.BookieReport, len(bookies))
+	for i, b := range bookies {
+		results := make([]report.SelectorResult, len(b.Results))
+		for j, res := range b.Results {
+			if res.Evidence != nil {
+				ev := *res.Evidence
+				ev.OuterHTML = ""
+				res.Evidence = &ev
+			}
+			res.Suggestions = nil
+			results[j] = res
+		}
+		b.Results = results
+		b.Variants = compactBookies(b.Variants)
+		out[i] = b
+	}
+	return out
+}
+
+// Append adds a run to the end of the history file
+func Append(path string, run Run) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	line, err := encode(run)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(line)
+	return err
+}
+
+// Load reads all runs, oldest first. A missing file is an empty history.
+func Load(path string) ([]Run, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), MaxLine)
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// Find returns the run with the given id
+func Find(runs []Run, id string) (Run, bool) {
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].ID == id {
+			return runs[i], true
+		}
+	}
+	return Run{}, false
+}
+
+// LatestOf builds a run from the most recent check of each named bookie, to
+// diff a scheduled cycle that only checked some bookies against their last
+// check. It returns nil when none of them was checked before.
+func LatestOf(runs []Run, names []string) *Run {
+	var merged *Run
+	for _, name := range names {
+		for i := len(runs) - 1; i >= 0; i-- {
+			b, ok := bookie(runs[i], name)
+			if !ok {
+				continue
+			}
+			if merged == nil {
+				merged = &Run{Selectors: report.Selectors{}}
+			}
+			if runs[i].StartedAt.After(merged.StartedAt) {
+				merged.ID, merged.StartedAt = runs[i].ID, runs[i].StartedAt
+				merged.Report.RunID, merged.Report.StartedAt = runs[i].Report.RunID, runs[i].Report.StartedAt
+			}
+			merged.Report.Details = append(merged.Report.Details, b)
+			for _, v := range b.WithVariants() {
+				merged.Selectors[v.Key()] = runs[i].Selectors[v.Key()]
+			}
+			break
+		}
+	}
+	return merged
+}
+
+// bookie finds a bookie's report in a run by name
+func bookie(run Run, name string) (report.BookieReport, bool) {
+	for _, b := range run.Report.Details {
+		if strings.EqualFold(b.Name, name) {
+			return b, true
+		}
+	}
+	return report.BookieReport{}, false
+}
+
+// Prune keeps the newest keep runs; 0 keeps all. It returns how many runs
+// were dropped.
+func Prune(path string, keep int) (int, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+	runs, err := Load(path)
+	if err != nil || len(runs) <= keep {
+		return 0, err
+	}
+
+	dropped := len(runs) - keep
+	var buf bytes.Buffer
+	for _, run := range runs[dropped:] {
+		line, err := encode(run)
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(line)
+	}
+
+	// Write next to the file and rename so a crash never leaves half a history
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return 0, err
+	}
+	return dropped, os.Rename(tmp, path)
+}
+
+// encode renders a run as one JSON line
+func encode(run Run) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(run); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
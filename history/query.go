@@ -0,0 +1,177 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"diago/report"
+)
+
+// Point is the status of a bookie or selector in one run
+type Point struct {
+	RunID   string    `json:"run_id"`
+	At      time.Time `json:"at"`
+	Status  string    `json:"status"`
+	Score   float64   `json:"score,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Healthy reports whether the point counts towards uptime
+func (p Point) Healthy() bool {
+	return p.Status == "✅" || p.Status == "⚠️"
+}
+
+// Stats summarise a timeline. Runs where the check was not applicable are
+// not counted.
+type Stats struct {
+	Checked      int     `json:"checked"`
+	Healthy      int     `json:"healthy"`
+	Uptime       float64 `json:"uptime"`
+	FirstFailure *Point  `json:"first_failure,omitempty"`
+	LastFailure  *Point  `json:"last_failure,omitempty"`
+	Last         *Point  `json:"last,omitempty"`
+}
+
+// Since returns the runs started at or after t
+func Since(runs []Run, t time.Time) []Run {
+	i := sort.Search(len(runs), func(i int) bool { return !runs[i].StartedAt.Before(t) })
+	return runs[i:]
+}
+
+// Bookies returns the reports of a run by key, device variants included
+func Bookies(run Run) map[string]report.BookieReport {
+	out := map[string]report.BookieReport{}
+	for _, b := range run.Report.Details {
+		for _, v := range b.WithVariants() {
+			out[v.Key()] = v
+		}
+	}
+	return out
+}
+
+// Keys lists every bookie key seen in the runs, sorted
+func Keys(runs []Run) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, run := range runs {
+		for key := range Bookies(run) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Labels lists the selector labels a bookie had, in the order of its latest run
+func Labels(runs []Run, key string) []string {
+	seen := map[string]bool{}
+	var labels []string
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, r := range Bookies(runs[i])[key].Results {
+			if !seen[r.Label] {
+				seen[r.Label] = true
+				labels = append(labels, r.Label)
+			}
+		}
+	}
+	return labels
+}
+
+// BookieTimeline returns a bookie's status in every run that checked it, oldest first
+func BookieTimeline(runs []Run, key string) []Point {
+	var points []Point
+	for _, run := range runs {
+		b, ok := Bookies(run)[key]
+		if !ok {
+			continue
+		}
+		points = append(points, bookiePoint(run, b))
+	}
+	return points
+}
+
+// bookiePoint is a bookie's status in one run
+func bookiePoint(run Run, b report.BookieReport) Point {
+	p := Point{RunID: run.ID, At: run.StartedAt, Status: Status(b), Score: b.Score}
+	if !b.AllPass {
+		p.Message = failureMessage(b)
+	}
+	return p
+}
+
+// Status is 💥 when a bookie's page could not be fetched, ✅ when all of its
+// checks passed and ⚠️ when some failed. A bookie counts as up whenever it
+// could be fetched: failing selectors show in their own timelines.
+func Status(b report.BookieReport) string {
+	for _, r := range b.Results {
+		if r.Label == report.FetchErrorLabel {
+			return "💥"
+		}
+	}
+	if b.AllPass {
+		return "✅"
+	}
+	return "⚠️"
+}
+
+// SelectorTimeline returns one selector's status in every run of a bookie, oldest first
+func SelectorTimeline(runs []Run, key, label string) []Point {
+	var points []Point
+	for _, run := range runs {
+		b, ok := Bookies(run)[key]
+		if !ok {
+			continue
+		}
+		for _, r := range b.Results {
+			if r.Label == label {
+				points = append(points, Point{RunID: run.ID, At: run.StartedAt, Status: r.Status, Message: r.Message})
+				break
+			}
+		}
+	}
+	return points
+}
+
+// Summarize computes the uptime and failure range of a timeline
+func Summarize(points []Point) Stats {
+	var s Stats
+	for i := range points {
+		p := points[i]
+		s.Last = &points[i]
+		if p.Status == "➖" {
+			continue
+		}
+		s.Checked++
+		if p.Healthy() {
+			s.Healthy++
+			continue
+		}
+		if s.FirstFailure == nil {
+			s.FirstFailure = &points[i]
+		}
+		s.LastFailure = &points[i]
+	}
+	if s.Checked > 0 {
+		s.Uptime = math.Round(float64(s.Healthy)/float64(s.Checked)*1000) / 10
+	}
+	return s
+}
+
+// failureMessage names why a bookie failed: its fetch error or failing count
+func failureMessage(b report.BookieReport) string {
+	failed := 0
+	for _, r := range b.Results {
+		if r.Label == report.FetchErrorLabel {
+			return r.Status
+		}
+		if !r.Healthy() && r.Status != "➖" {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d check(s) failed", failed)
+}
@@ -0,0 +1,118 @@
+package history
+
+import (
+	"diago/report"
+)
+
+// Stability classes of a bookie or selector over the recent runs
+const (
+	Stable = "stable"
+	Flaky  = "flaky"
+	Broken = "broken"
+)
+
+// Window configures the classification: how many recent runs to look at,
+// how many pass/fail flips make a check flaky, and how many failures in a
+// row make it broken
+type Window struct {
+	Runs        int
+	MinFlips    int
+	BrokenAfter int
+}
+
+// Classification is how a timeline behaved within the window
+type Classification struct {
+	Stability  string
+	Flips      int
+	FailStreak int
+}
+
+// Classify looks at the last w.Runs points of a timeline. A check that is
+// failing now and has failed w.BrokenAfter runs in a row is broken; one that
+// flipped between passing and failing at least w.MinFlips times is flaky.
+// Points that were not applicable are ignored.
+func Classify(points []Point, w Window) Classification {
+	var checked []Point
+	for _, p := range points {
+		if p.Status != "➖" {
+			checked = append(checked, p)
+		}
+	}
+	if w.Runs > 0 && len(checked) > w.Runs {
+		checked = checked[len(checked)-w.Runs:]
+	}
+
+	c := Classification{Stability: Stable}
+	for i := len(checked) - 1; i >= 0 && !checked[i].Healthy(); i-- {
+		c.FailStreak++
+	}
+	for i := 1; i < len(checked); i++ {
+		if checked[i].Healthy() != checked[i-1].Healthy() {
+			c.Flips++
+		}
+	}
+
+	switch {
+	case c.FailStreak > 0 && c.FailStreak >= w.BrokenAfter:
+		c.Stability = Broken
+	case c.Flips >= w.MinFlips && w.MinFlips > 0:
+		c.Stability = Flaky
+	}
+	return c
+}
+
+// Annotate classifies every bookie and selector of the report from its
+// runs in the history followed by the report itself
+func Annotate(r *report.FullReport, runs []Run, w Window) {
+	if w.Runs > 0 && len(runs) > w.Runs {
+		runs = runs[len(runs)-w.Runs:]
+	}
+	runs = append(append([]Run{}, runs...), Run{ID: r.RunID, Report: *r})
+
+	// Index every run's bookies once rather than once per selector
+	index := make([]map[string]report.BookieReport, len(runs))
+	for i, run := range runs {
+		index[i] = Bookies(run)
+	}
+
+	for _, list := range [][]report.BookieReport{r.Details, r.Summary} {
+		for i := range list {
+			annotateBookie(&list[i], runs, index, w)
+			for j := range list[i].Variants {
+				annotateBookie(&list[i].Variants[j], runs, index, w)
+			}
+		}
+	}
+}
+
+func annotateBookie(b *report.BookieReport, runs []Run, index []map[string]report.BookieReport, w Window) {
+	key := b.Key()
+	var points []Point
+	selectors := map[string][]Point{}
+	for i, run := range runs {
+		past, ok := index[i][key]
+		if !ok {
+			continue
+		}
+		points = append(points, bookiePoint(run, past))
+		for _, res := range past.Results {
+			selectors[res.Label] = append(selectors[res.Label], Point{RunID: run.ID, Status: res.Status})
+		}
+	}
+
+	c := Classify(points, w)
+	b.Stability, b.FailStreak = c.Stability, c.FailStreak
+	b.DownStreak = 0
+	for i := len(points) - 1; i >= 0 && points[i].Status == "💥"; i-- {
+		b.DownStreak++
+	}
+
+	for i := range b.Results {
+		res := &b.Results[i]
+		if res.Label == report.FetchErrorLabel || res.Status == "➖" {
+			continue
+		}
+		c := Classify(selectors[res.Label], w)
+		res.Stability, res.FailStreak = c.Stability, c.FailStreak
+	}
+}
@@ -0,0 +1,132 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"diago/report"
+)
+
+// bookieRuns builds one run per bookie report, a minute apart
+func bookieRuns(bookies ...report.BookieReport) []Run {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	runs := make([]Run, len(bookies))
+	for i, b := range bookies {
+		runs[i] = Run{
+			ID:        fmt.Sprintf("run-%d", i),
+			StartedAt: start.Add(time.Duration(i) * time.Minute),
+			Report:    report.FullReport{Details: []report.BookieReport{b}},
+		}
+	}
+	return runs
+}
+
+func passing() report.BookieReport {
+	return report.BookieReport{Name: "betway", AllPass: true, Score: 100, Results: []report.SelectorResult{
+		{Label: "Login.UsernameInput", Status: "✅"},
+		{Label: "Footer.Logo", Status: "✅"},
+	}}
+}
+
+// failing could be fetched but one of its selectors is missing
+func failing() report.BookieReport {
+	return report.BookieReport{Name: "betway", Score: 80, Results: []report.SelectorResult{
+		{Label: "Login.UsernameInput", Status: "✅"},
+		{Label: "Footer.Logo", Status: "❌"},
+	}}
+}
+
+func unreachable() report.BookieReport {
+	return report.BookieReport{Name: "betway", Results: []report.SelectorResult{
+		{Label: report.FetchErrorLabel, Status: "received HTTP 503"},
+	}}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		b    report.BookieReport
+		want string
+	}{
+		{"all pass", passing(), "✅"},
+		{"failing checks", failing(), "⚠️"},
+		{"unreachable", unreachable(), "💥"},
+	}
+	for _, tt := range tests {
+		if got := Status(tt.b); got != tt.want {
+			t.Errorf("%s: Status = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// A bookie whose page loads is up even when some of its selectors fail;
+// the failure shows in the selector's timeline
+func TestBookieUptimeIsReachability(t *testing.T) {
+	runs := bookieRuns(failing(), failing(), unreachable(), failing())
+
+	st := Summarize(BookieTimeline(runs, "betway"))
+	if st.Checked != 4 || st.Uptime != 75 {
+		t.Errorf("bookie uptime = %v over %d runs, want 75 over 4", st.Uptime, st.Checked)
+	}
+	if st.FirstFailure == nil || st.FirstFailure.RunID != "run-2" || st.LastFailure.RunID != "run-2" {
+		t.Errorf("failures = %+v..%+v, want only run-2", st.FirstFailure, st.LastFailure)
+	}
+	if st.Last.Message != "1 check(s) failed" {
+		t.Errorf("last message = %q", st.Last.Message)
+	}
+
+	sel := Summarize(SelectorTimeline(runs, "betway", "Footer.Logo"))
+	if sel.Checked != 3 || sel.Uptime != 0 {
+		t.Errorf("selector uptime = %v over %d runs, want 0 over 3", sel.Uptime, sel.Checked)
+	}
+}
+
+func TestAnnotateBookieStability(t *testing.T) {
+	w := Window{Runs: 10, MinFlips: 3, BrokenAfter: 3}
+	tests := []struct {
+		name       string
+		history    []report.BookieReport
+		current    report.BookieReport
+		stability  string
+		failStreak int
+		downStreak int
+	}{
+		{"failing selectors keep the bookie stable",
+			[]report.BookieReport{failing(), failing(), failing()}, failing(), Stable, 0, 0},
+		{"passing and failing selectors are not flaky",
+			[]report.BookieReport{passing(), failing(), passing(), failing()}, passing(), Stable, 0, 0},
+		{"unreachable runs in a row are broken",
+			[]report.BookieReport{passing(), unreachable(), unreachable()}, unreachable(), Broken, 3, 3},
+		{"coming and going is flaky",
+			[]report.BookieReport{passing(), unreachable(), failing(), unreachable()}, passing(), Flaky, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &report.FullReport{RunID: "now", Details: []report.BookieReport{tt.current}}
+			Annotate(r, bookieRuns(tt.history...), w)
+
+			b := r.Details[0]
+			if b.Stability != tt.stability || b.FailStreak != tt.failStreak || b.DownStreak != tt.downStreak {
+				t.Errorf("stability %s, fail streak %d, down streak %d; want %s, %d, %d",
+					b.Stability, b.FailStreak, b.DownStreak, tt.stability, tt.failStreak, tt.downStreak)
+			}
+		})
+	}
+}
+
+// Selectors are still classified by their own status
+func TestAnnotateSelectorStability(t *testing.T) {
+	r := &report.FullReport{RunID: "now", Details: []report.BookieReport{failing()}}
+	Annotate(r, bookieRuns(failing(), failing()), Window{Runs: 10, MinFlips: 3, BrokenAfter: 3})
+
+	for _, res := range r.Details[0].Results {
+		want := Stable
+		if res.Label == "Footer.Logo" {
+			want = Broken
+		}
+		if res.Stability != want {
+			t.Errorf("%s stability = %s, want %s", res.Label, res.Stability, want)
+		}
+	}
+}
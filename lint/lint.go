@@ -0,0 +1,357 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"diago/config"
+	"diago/locator"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Levels a rule can be configured to
+const (
+	LevelError = "error"
+	LevelWarn  = "warn"
+	LevelOff   = "off"
+)
+
+// MaxChain is the number of compound selectors allowed in a descendant chain
+const MaxChain = 4
+
+// Rule is a single lint check on a selector string
+type Rule struct {
+	ID          string
+	Description string
+	Level       string
+	check       func(css string) string
+}
+
+// Rules are the built-in lint rules with their default levels
+var Rules = []Rule{
+	{"invalid", "selector does not compile", LevelError, checkInvalid},
+	{"hashed-class", "generated CSS-in-JS class or id that changes every release", LevelError, checkHashed},
+	{"positional", "positional pseudo-class such as :nth-child", LevelWarn, checkPositional},
+	{"long-chain", fmt.Sprintf("more than %d compound selectors in a chain", MaxChain), LevelWarn, checkChain},
+	{"generic", "bare tag selector without any anchor", LevelWarn, checkGeneric},
+	{"ad-or-iframe", "selector targets or matches inside ads or iframes", LevelWarn, checkAdOrIframe},
+}
+
+// Finding is one rule violation
+type Finding struct {
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Result is the robustness score (0-100) of one selector alternative
+type Result struct {
+	Label    string    `json:"label"`
+	Selector string    `json:"selector"`
+	Score    int       `json:"score"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Errors counts error-level findings
+func (r Result) Errors() int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Level == LevelError {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	hashedPrefix = regexp.MustCompile(`^(sc-|css-|jsx-|emotion-|svelte-)`)
+	// 1X2 is the football match-result market, not part of a hash
+	marketRe      = regexp.MustCompile(`(?i)1x2`)
+	interleavedRe = regexp.MustCompile(`[0-9][A-Za-z]{1,2}[0-9]`)
+	tokenRe       = regexp.MustCompile(`[.#]((?:\\.|[A-Za-z0-9_-])+)`)
+	positionRe    = regexp.MustCompile(`:(nth-child|nth-last-child|nth-of-type|nth-last-of-type|first-child|last-child|first-of-type|last-of-type|eq|first|last)\b`)
+	adRe          = regexp.MustCompile(`(?i)iframe|adsbygoogle|google_ads|doubleclick|[#.\[="' -]ads?[-_ \]"'.#]|[#.]ads?$|sponsor|banner-ad|advert`)
+	genericRe     = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	anchorRe      = regexp.MustCompile(`#|\[(data-|name|role|aria-|id)`)
+	xpathPosRe    = regexp.MustCompile(`\[\s*(\d+|last\(\)[^\]]*|position\(\)[^\]]*)\s*\]`)
+)
+
+// Hashed reports whether a class or id looks machine-generated: a known
+// CSS-in-JS prefix, or a segment that looks random such as "a1b2c3" or
+// "bdVaJa". A word followed by a number like "team1" or "top10" is not.
+func Hashed(token string) bool {
+	if hashedPrefix.MatchString(token) {
+		return true
+	}
+	for _, part := range strings.FieldsFunc(token, func(r rune) bool { return r == '-' || r == '_' }) {
+		if random(marketRe.ReplaceAllString(part, "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// random reports digits interleaved with single letters, or letters that
+// switch case almost every character
+func random(part string) bool {
+	if len(part) < 5 {
+		return false
+	}
+	if interleavedRe.MatchString(part) {
+		return true
+	}
+	runs, singles, size := 0, 0, 0
+	var upper bool
+	for _, r := range part {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if size == 0 || unicode.IsUpper(r) != upper {
+			if size == 1 {
+				singles++
+			}
+			runs, size, upper = runs+1, 0, unicode.IsUpper(r)
+		}
+		size++
+	}
+	if size == 1 {
+		singles++
+	}
+	return runs >= 4 && singles*2 > runs
+}
+
+func checkInvalid(css string) string {
+	if locator.TypeOf(css) != locator.TypeCSS {
+		if _, err := locator.Compile(css); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	if _, err := cascadia.ParseGroup(strings.TrimPrefix(strings.TrimSpace(css), locator.TypeCSS+":")); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// locatorFindings checks xpath, text and role locators: they must compile,
+// and xpath positions count as positional
+func locatorFindings(sel string, levels map[string]string) []Finding {
+	var findings []Finding
+	if level := levels["invalid"]; level != LevelOff {
+		if msg := checkInvalid(sel); msg != "" {
+			findings = append(findings, Finding{Rule: "invalid", Level: level, Message: msg})
+		}
+	}
+	if level := levels["positional"]; level != LevelOff && locator.TypeOf(sel) == locator.TypeXPath {
+		if m := xpathPosRe.FindString(sel); m != "" {
+			findings = append(findings, Finding{Rule: "positional", Level: level, Message: "uses " + m})
+		}
+	}
+	return findings
+}
+
+// locatorScore rates role and text locators highly because they survive
+// markup changes; xpath is as brittle as the structure it walks
+func locatorScore(t string, findings []Finding) int {
+	base := map[string]int{locator.TypeRole: 90, locator.TypeText: 70, locator.TypeXPath: 50}[t]
+	return deduct(base, findings)
+}
+
+func checkHashed(css string) string {
+	var hashed []string
+	for _, m := range tokenRe.FindAllStringSubmatch(css, -1) {
+		if Hashed(m[1]) {
+			hashed = append(hashed, m[1])
+		}
+	}
+	if len(hashed) > 0 {
+		return "generated name " + strings.Join(hashed, ", ")
+	}
+	return ""
+}
+
+func checkPositional(css string) string {
+	if m := positionRe.FindString(css); m != "" {
+		return "uses " + m
+	}
+	return ""
+}
+
+func checkChain(css string) string {
+	for _, part := range strings.Split(css, ",") {
+		// Collapse combinators so each compound selector becomes one field
+		normalized := strings.NewReplacer(">", " ", "+", " ", "~", " ").Replace(part)
+		if n := len(strings.Fields(normalized)); n > MaxChain {
+			return fmt.Sprintf("%d compound selectors", n)
+		}
+	}
+	return ""
+}
+
+func checkGeneric(css string) string {
+	for _, part := range strings.Split(css, ",") {
+		if genericRe.MatchString(strings.TrimSpace(part)) {
+			return fmt.Sprintf("matches every <%s>", strings.TrimSpace(part))
+		}
+	}
+	return ""
+}
+
+func checkAdOrIframe(css string) string {
+	if m := adRe.FindString(css); m != "" {
+		return fmt.Sprintf("references %q", strings.Trim(m, "#.[=\"' -_]"))
+	}
+	return ""
+}
+
+// Levels resolves the effective level of every rule from the settings
+func Levels(settings config.LintSettings) map[string]string {
+	levels := map[string]string{}
+	for _, r := range Rules {
+		levels[r.ID] = r.Level
+		if lvl, ok := settings.Rules[r.ID]; ok {
+			levels[r.ID] = lvl
+		}
+	}
+	return levels
+}
+
+// Selector lints one selector string. When doc is not nil the matched
+// elements are also checked for ad or iframe containers.
+func Selector(label, css string, levels map[string]string, doc *goquery.Document) Result {
+	res := Result{Label: label, Selector: css}
+
+	// Rules apply to the selector inside a frame path; the frame selectors only need to compile
+	frames, inner := config.SplitFrames(css)
+	for _, fr := range frames {
+		if msg := checkInvalid(fr); msg != "" && levels["invalid"] != LevelOff {
+			res.Findings = append(res.Findings, Finding{Rule: "invalid", Level: levels["invalid"], Message: "frame " + msg})
+		}
+	}
+
+	// The CSS rules do not apply to xpath, text and role locators
+	if t := locator.TypeOf(inner); t != locator.TypeCSS {
+		res.Findings = append(res.Findings, locatorFindings(inner, levels)...)
+		res.Score = locatorScore(t, res.Findings)
+		return res
+	}
+	inner = strings.TrimPrefix(strings.TrimSpace(inner), locator.TypeCSS+":")
+
+	for _, r := range Rules {
+		level := levels[r.ID]
+		if level == LevelOff {
+			continue
+		}
+		if msg := r.check(inner); msg != "" {
+			res.Findings = append(res.Findings, Finding{Rule: r.ID, Level: level, Message: msg})
+		}
+	}
+
+	// The fetched document does not contain iframe contents
+	if doc != nil && len(frames) == 0 && levels["ad-or-iframe"] != LevelOff && !hasRule(res, "ad-or-iframe") {
+		if msg := matchesInsideAd(doc, css); msg != "" {
+			res.Findings = append(res.Findings, Finding{Rule: "ad-or-iframe", Level: levels["ad-or-iframe"], Message: msg})
+		}
+	}
+
+	res.Score = score(inner, res.Findings)
+	return res
+}
+
+// Config lints every selector alternative of a bookie, in config order
+func Config(cfg *config.Sportsbook, levels map[string]string, doc *goquery.Document) []Result {
+	var results []Result
+	for _, field := range cfg.SelectorFields() {
+		for _, css := range field.Selector.Alternatives() {
+			results = append(results, Selector(field.Label, css, levels, doc))
+		}
+	}
+	return results
+}
+
+// score favours ids, data-* attributes, names and ARIA roles, then
+// subtracts for every finding
+func score(css string, findings []Finding) int {
+	s := 40
+	if strings.Contains(css, "#") {
+		s += 30
+	}
+	if strings.Contains(css, "[data-") {
+		s += 30
+	}
+	if strings.Contains(css, "[role") || strings.Contains(css, "[aria-") {
+		s += 25
+	}
+	if strings.Contains(css, "[name") {
+		s += 20
+	}
+	if !anchorRe.MatchString(css) && strings.Contains(css, ".") {
+		s += 10
+	}
+	if s > 100 {
+		s = 100
+	}
+	return deduct(s, findings)
+}
+
+// deduct subtracts 40 for every error and 15 for every warning
+func deduct(s int, findings []Finding) int {
+	for _, f := range findings {
+		switch f.Level {
+		case LevelError:
+			s -= 40
+		case LevelWarn:
+			s -= 15
+		}
+	}
+	if s < 0 {
+		s = 0
+	}
+	return s
+}
+
+// matchesInsideAd reports matched elements that sit inside an ad container or an iframe
+func matchesInsideAd(doc *goquery.Document, css string) string {
+	l, err := locator.Compile(css)
+	if err != nil {
+		return ""
+	}
+	for _, n := range l.Match(doc.Get(0)) {
+		for p := n.Parent; p != nil; p = p.Parent {
+			if p.Type != html.ElementNode {
+				continue
+			}
+			if p.Data == "iframe" {
+				return "matches inside an iframe"
+			}
+			for _, a := range p.Attr {
+				if (a.Key == "id" || a.Key == "class") && adRe.MatchString(" "+a.Val+" ") {
+					return fmt.Sprintf("matches inside <%s %s=%q>", p.Data, a.Key, a.Val)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func hasRule(r Result, id string) bool {
+	for _, f := range r.Findings {
+		if f.Rule == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Worst returns results sorted by ascending score
+func Worst(results []Result) []Result {
+	out := append([]Result{}, results...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score < out[j].Score })
+	return out
+}
@@ -0,0 +1,122 @@
+package lint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"diago/config"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestHashed(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		// Known CSS-in-JS prefixes
+		{"sc-bdVaJa", true},
+		{"css-1q2w3e", true},
+		{"jsx-2841935", true},
+		{"emotion-0", true},
+		{"svelte-xyz", true},
+		// Random segments
+		{"a1b2c3", true},
+		{"Button_root__3xY7a", true},
+		{"ebu8m4d0", true},
+		{"odds-bdVaJa", true},
+		// A word followed by a number
+		{"team1", false},
+		{"step1", false},
+		{"password2", false},
+		{"h1title", false},
+		{"top10", false},
+		{"login2024", false},
+		{"event-12345", false},
+		// The 1X2 market and bookie names
+		{"market1x2", false},
+		{"odds-1X2", false},
+		{"1x2abc", false},
+		{"bet365", false},
+		{"22bet", false},
+		// Plain names
+		{"loginButton", false},
+		{"loginButtonPrimary", false},
+		{"card__title", false},
+		{"nav", false},
+	}
+	for _, tt := range tests {
+		if got := Hashed(tt.token); got != tt.want {
+			t.Errorf("Hashed(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	levels := Levels(config.LintSettings{})
+
+	tests := []struct {
+		css   string
+		rules []string
+		score int
+	}{
+		{"#login", nil, 70},
+		{"[data-testid=login]", nil, 70},
+		{"button[name=submit]", nil, 60},
+		{".market1x2 .team1", nil, 50},
+		{"[role=button]", nil, 65},
+		{"#login[", []string{"invalid"}, 30},
+		{".sc-bdVaJa", []string{"hashed-class"}, 10},
+		{"#a1b2c3 .step1", []string{"hashed-class"}, 30},
+		{"li:nth-child(2)", []string{"positional"}, 25},
+		{"div > ul li span a b", []string{"long-chain"}, 25},
+		{"button", []string{"generic"}, 25},
+		{"#ads .bet", []string{"ad-or-iframe"}, 55},
+		{"iframe#odds", []string{"ad-or-iframe"}, 55},
+		{"xpath://form/input[2]", []string{"positional"}, 35},
+		{"xpath://form[", []string{"invalid"}, 10},
+		{"role:button[name=Log in]", nil, 90},
+		{"text:Log in", nil, 70},
+		// Rules apply inside a frame path; the frame only has to compile
+		{"frame:#sports >> .sc-bdVaJa", []string{"hashed-class"}, 10},
+	}
+	for _, tt := range tests {
+		res := Selector("Login.Button", tt.css, levels, nil)
+		var rules []string
+		for _, f := range res.Findings {
+			rules = append(rules, f.Rule)
+		}
+		if !reflect.DeepEqual(rules, tt.rules) || res.Score != tt.score {
+			t.Errorf("%s: rules %v score %d, want %v score %d (%+v)", tt.css, rules, res.Score, tt.rules, tt.score, res.Findings)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	levels := Levels(config.LintSettings{Rules: map[string]string{"positional": LevelError, "hashed-class": LevelOff}})
+	if levels["positional"] != LevelError || levels["hashed-class"] != LevelOff || levels["generic"] != LevelWarn {
+		t.Fatalf("levels = %v", levels)
+	}
+
+	res := Selector("Odds", ".sc-bdVaJa li:first-child", levels, nil)
+	if len(res.Findings) != 1 || res.Findings[0].Rule != "positional" || res.Errors() != 1 {
+		t.Errorf("findings = %+v, want only an error-level positional", res.Findings)
+	}
+}
+
+func TestSelectorMatchesInsideAd(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<div class="banner ad"><a id="promo">Bet now</a></div><a id="login">Log in</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := Levels(config.LintSettings{})
+
+	if res := Selector("Promo", "#promo", levels, doc); len(res.Findings) != 1 || res.Findings[0].Message != `matches inside <div class="banner ad">` {
+		t.Errorf("findings = %+v", res.Findings)
+	}
+	if res := Selector("Login", "#login", levels, doc); len(res.Findings) != 0 {
+		t.Errorf("findings = %+v, want none", res.Findings)
+	}
+}
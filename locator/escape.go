@@ -0,0 +1,51 @@
+package locator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CSSIdent escapes s for use as a CSS identifier, such as an id or class
+// in a selector, following the CSSOM serialization rules
+func CSSIdent(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == 0:
+			b.WriteRune('�')
+		case r < 0x20 || r == 0x7f,
+			r >= '0' && r <= '9' && (i == 0 || i == 1 && s[0] == '-'):
+			fmt.Fprintf(&b, "\\%x ", r)
+		case r == '-' && len(s) == 1:
+			b.WriteString(`\-`)
+		case r >= 0x80 || r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// CSSString quotes s as a CSS string, such as an attribute value in a
+// selector. Unlike Go quoting it leaves non-ASCII text as it is.
+func CSSString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == 0:
+			b.WriteRune('�')
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\%x ", r)
+		case r == '"' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
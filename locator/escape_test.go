@@ -0,0 +1,67 @@
+package locator
+
+import (
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestCSSIdent(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"login", "login"},
+		{"login-btn_2", "login-btn_2"},
+		{"1x2", `\31 x2`},
+		{"-1x2", `-\31 x2`},
+		{"-", `\-`},
+		{"a.b:c", `a\.b\:c`},
+		{"café", "café"},
+		{"tab\there", `tab\9 here`},
+		{`say"hi"`, `say\"hi\"`},
+	}
+	for _, tt := range tests {
+		if got := CSSIdent(tt.in); got != tt.want {
+			t.Errorf("CSSIdent(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCSSString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Log in", `"Log in"`},
+		{"Ingia sasa", `"Ingia sasa"`},
+		{"Parié", `"Parié"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\bets`, `"C:\\bets"`},
+		{"a\tb\nc", `"a\9 b\a c"`},
+	}
+	for _, tt := range tests {
+		if got := CSSString(tt.in); got != tt.want {
+			t.Errorf("CSSString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Escaped selectors match the element whose values they were built from
+func TestEscapedSelectorsMatch(t *testing.T) {
+	values := []string{"login", "1x2", "-1x2", "a.b:c", "café", "tab\there", `say "hi"`, `C:\bets`, "Parié ⚽"}
+	for _, v := range values {
+		el := &html.Node{Type: html.ElementNode, Data: "input", Attr: []html.Attribute{{Key: "id", Val: v}, {Key: "placeholder", Val: v}}}
+		root := &html.Node{Type: html.DocumentNode}
+		root.AppendChild(el)
+
+		for _, sel := range []string{"#" + CSSIdent(v), "input[placeholder=" + CSSString(v) + "]"} {
+			l, err := Compile(sel)
+			if err != nil {
+				t.Errorf("%q: %s: %v", v, sel, err)
+				continue
+			}
+			if m := l.Match(root); len(m) != 1 || m[0] != el {
+				t.Errorf("%q: %s matched %d elements", v, sel, len(m))
+			}
+		}
+	}
+}
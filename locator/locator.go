@@ -0,0 +1,376 @@
+package locator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Locator types, used as selector prefixes such as "xpath://button"
+const (
+	TypeCSS   = "css"
+	TypeXPath = "xpath"
+	TypeText  = "text"
+	TypeRole  = "role"
+)
+
+// Locator finds elements in a parsed HTML tree
+type Locator struct {
+	Type string
+	Expr string
+
+	match func(root *html.Node) []*html.Node
+	css   cascadia.Selector
+	hint  hint
+}
+
+// TypeOf returns the locator type of a selector; unprefixed selectors are CSS
+func TypeOf(s string) string {
+	t, _ := split(s)
+	return t
+}
+
+// split separates the type prefix from the expression
+func split(s string) (string, string) {
+	trimmed := strings.TrimSpace(s)
+	for _, t := range []string{TypeCSS, TypeXPath, TypeText, TypeRole} {
+		if strings.HasPrefix(trimmed, t+":") {
+			return t, strings.TrimSpace(trimmed[len(t)+1:])
+		}
+	}
+	return TypeCSS, trimmed
+}
+
+// Parse compiles a selector with an optional css:, xpath:, text: or role: prefix
+func Parse(s string) (*Locator, error) {
+	t, expr := split(s)
+	l := &Locator{Type: t, Expr: expr}
+	if expr == "" {
+		return nil, fmt.Errorf("empty %s locator", t)
+	}
+
+	switch t {
+	case TypeCSS:
+		sel, err := cascadia.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid css %q: %w", expr, err)
+		}
+		l.match = sel.MatchAll
+		l.css = sel
+		l.hint = cssHint(expr)
+	case TypeXPath:
+		path, err := compileXPath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath %q: %w", expr, err)
+		}
+		l.match = path.match
+	case TypeText:
+		m, err := parseTextMatcher(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid text locator %q: %w", expr, err)
+		}
+		l.match = func(root *html.Node) []*html.Node { return matchText(root, m) }
+	case TypeRole:
+		r, err := parseRole(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid role locator %q: %w", expr, err)
+		}
+		l.match = r.match
+	}
+	return l, nil
+}
+
+// Match returns the matching elements below root in document order
+func (l *Locator) Match(root *html.Node) []*html.Node {
+	return l.match(root)
+}
+
+// compiled is a cached Parse result, including its error
+type compiled struct {
+	loc *Locator
+	err error
+}
+
+// cache holds every selector compiled so far, shared by all bookies
+var cache sync.Map
+
+// Compile is Parse with a process-wide cache keyed by the selector string,
+// so each selector and its parse error are only computed once
+func Compile(s string) (*Locator, error) {
+	if c, ok := cache.Load(s); ok {
+		return c.(compiled).loc, c.(compiled).err
+	}
+	l, err := Parse(s)
+	cache.Store(s, compiled{loc: l, err: err})
+	return l, err
+}
+
+// MatchAll evaluates many locators against the tree below root. All CSS
+// locators share a single walk; xpath, text and role locators walk on their
+// own. The result holds the matches of locs[i] at index i.
+func MatchAll(root *html.Node, locs []*Locator) [][]*html.Node {
+	out := make([][]*html.Node, len(locs))
+
+	var css []int
+	for i, l := range locs {
+		if l.css != nil {
+			css = append(css, i)
+		} else {
+			out[i] = l.Match(root)
+		}
+	}
+	if len(css) == 0 {
+		return out
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, i := range css {
+				if locs[i].hint.allows(n) && locs[i].css.Match(n) {
+					out[i] = append(out[i], n)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return out
+}
+
+// hint is a cheap necessary condition for a CSS match: the tag, id and
+// classes of the selector's rightmost compound
+type hint struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// cssHint derives a hint from simple selectors; anything with attributes,
+// pseudo-class arguments, groups or escapes gets no hint
+func cssHint(expr string) hint {
+	if strings.ContainsAny(expr, "[(,\\\"'") {
+		return hint{}
+	}
+	parts := strings.FieldsFunc(expr, func(r rune) bool {
+		return r == ' ' || r == '>' || r == '+' || r == '~' || r == '\t' || r == '\n'
+	})
+	if len(parts) == 0 {
+		return hint{}
+	}
+	last := parts[len(parts)-1]
+	if i := strings.Index(last, ":"); i >= 0 {
+		last = last[:i]
+	}
+
+	var h hint
+	end := strings.IndexAny(last, "#.")
+	if end < 0 {
+		end = len(last)
+	}
+	if tag := last[:end]; tag != "*" {
+		h.tag = strings.ToLower(tag)
+	}
+	for _, token := range splitKeep(last[end:]) {
+		switch token[0] {
+		case '#':
+			h.id = token[1:]
+		case '.':
+			h.classes = append(h.classes, token[1:])
+		}
+	}
+	return h
+}
+
+// splitKeep splits "#a.b.c" into "#a", ".b", ".c"
+func splitKeep(s string) []string {
+	var out []string
+	for len(s) > 0 {
+		next := strings.IndexAny(s[1:], "#.")
+		if next < 0 {
+			out = append(out, s)
+			break
+		}
+		out = append(out, s[:next+1])
+		s = s[next+1:]
+	}
+	return out
+}
+
+// allows reports whether n can match, before running the full selector
+func (h hint) allows(n *html.Node) bool {
+	if h.tag != "" && n.Data != h.tag {
+		return false
+	}
+	if h.id != "" {
+		if id, _ := attr(n, "id"); id != h.id {
+			return false
+		}
+	}
+	if len(h.classes) > 0 {
+		class, _ := attr(n, "class")
+		have := strings.Fields(class)
+		for _, want := range h.classes {
+			found := false
+			for _, c := range have {
+				if c == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// textMatcher compares normalised text exactly, by substring or by regex
+type textMatcher struct {
+	exact    string
+	contains string
+	re       *regexp.Regexp
+}
+
+// parseTextMatcher reads "quoted" (exact), /regex/flags or plain text
+// (case-insensitive substring)
+func parseTextMatcher(s string) (textMatcher, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0]:
+		return textMatcher{exact: Normalize(s[1 : len(s)-1])}, nil
+	case len(s) >= 2 && s[0] == '/' && strings.LastIndex(s, "/") > 0:
+		end := strings.LastIndex(s, "/")
+		pattern, flags := s[1:end], s[end+1:]
+		if strings.Contains(flags, "i") {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return textMatcher{}, err
+		}
+		return textMatcher{re: re}, nil
+	case s == "":
+		return textMatcher{}, fmt.Errorf("empty text")
+	default:
+		return textMatcher{contains: strings.ToLower(Normalize(s))}, nil
+	}
+}
+
+func (m textMatcher) matches(text string) bool {
+	switch {
+	case m.re != nil:
+		return m.re.MatchString(text)
+	case m.contains != "":
+		return strings.Contains(strings.ToLower(text), m.contains)
+	default:
+		return text == m.exact
+	}
+}
+
+// skipped elements never contain visible text
+var skipped = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "head": true}
+
+// matchText returns the innermost elements whose text matches: an element
+// is dropped when one of its children matches as well
+func matchText(root *html.Node, m textMatcher) []*html.Node {
+	texts := textIndex(root)
+
+	var out []*html.Node
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && skipped[n.Data] {
+			return false
+		}
+		childMatched := false
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				childMatched = true
+			}
+		}
+		if n.Type != html.ElementNode {
+			return childMatched
+		}
+		if !childMatched && m.matches(texts[n]) {
+			out = append(out, n)
+			return true
+		}
+		return childMatched
+	}
+	walk(root)
+
+	// walk appends children before parents; restore document order
+	return documentOrder(root, out)
+}
+
+// textIndex computes the normalised text of every element in one pass
+func textIndex(root *html.Node) map[*html.Node]string {
+	index := map[*html.Node]string{}
+	var walk func(n *html.Node) string
+	walk = func(n *html.Node) string {
+		switch {
+		case n.Type == html.TextNode:
+			return n.Data
+		case n.Type == html.ElementNode && skipped[n.Data]:
+			return ""
+		}
+		var b strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			b.WriteString(walk(c))
+			b.WriteString(" ")
+		}
+		raw := b.String()
+		if n.Type == html.ElementNode {
+			index[n] = Normalize(raw)
+		}
+		return raw
+	}
+	walk(root)
+	return index
+}
+
+// Normalize trims and collapses whitespace
+func Normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// documentOrder sorts nodes by their position in the tree below root
+func documentOrder(root *html.Node, nodes []*html.Node) []*html.Node {
+	if len(nodes) < 2 {
+		return nodes
+	}
+	want := map[*html.Node]bool{}
+	for _, n := range nodes {
+		want[n] = true
+	}
+	var out []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if want[n] {
+			out = append(out, n)
+			delete(want, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return out
+}
+
+// attr returns an attribute value and whether it is set
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
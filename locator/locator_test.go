@@ -0,0 +1,135 @@
+package locator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// matchPage mixes ids, classes, attributes and nesting so hints and the
+// shared walk are exercised on both matching and near-miss elements
+const matchPage = `<html><body>
+<div id="app" class="shell dark">
+	<nav class="menu"><a class="item active" href="/">Home</a><a class="item" href="/live">Live</a></nav>
+	<form id="login" class="form"><input id="user" class="field"><input id="pw" class="field secret" type="password">
+		<button class="btn btn-primary" type="submit">Log in</button></form>
+	<ul class="events">
+		<li class="event" data-id="1"><span class="team home">Arsenal</span><span class="team away">Chelsea</span><button class="odds">2.10</button></li>
+		<li class="event live" data-id="2"><span class="team home">Inter</span><span class="team away">Milan</span><button class="odds">1.90</button></li>
+	</ul>
+	<div class="slip"><p>Empty slip</p><DIV class="Item">Upper</DIV></div>
+</div></body></html>`
+
+// matchSelectors cover every locator type and the CSS forms cssHint handles
+// or deliberately skips
+var matchSelectors = []string{
+	"#login", "form#login", ".field", "input.field.secret", "#user.field", "li.event.live",
+	"nav > a.item", "ul .team.home", "div.slip p", ".item ~ .item", "a + a", "li:first-child .odds",
+	"a.item:not(.active)", "input[type=password]", "[data-id='2'] .odds", "span.team, button.odds",
+	"*", "div *", "button", "BUTTON.btn", ".Item", ".item", "#missing", "p.missing",
+	"css:.event", "xpath://li[@data-id='1']/button", "text:Log in", `text:"Chelsea"`, "text:/^1\\.\\d+$/",
+	`role:button[name="Log in"]`, "role:link", "role:textbox",
+}
+
+func TestMatchAllAgreesWithMatch(t *testing.T) {
+	root := parse(t, matchPage)
+	var locs []*Locator
+	for _, s := range matchSelectors {
+		l, err := Parse(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		locs = append(locs, l)
+	}
+
+	all := MatchAll(root, locs)
+	for i, l := range locs {
+		// The unindexed path: one walk per selector, without hints
+		want := l.Match(root)
+		if !reflect.DeepEqual(all[i], want) {
+			t.Errorf("%s: MatchAll = %s, Match = %s", matchSelectors[i], describe(all[i]), describe(want))
+		}
+	}
+}
+
+func TestCompileCaches(t *testing.T) {
+	a, err := Compile("li.event > .odds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Compile("li.event > .odds")
+	if a != b {
+		t.Error("Compile returned a new locator for a cached selector")
+	}
+
+	_, err1 := Compile("xpath://li[")
+	_, err2 := Compile("xpath://li[")
+	if err1 == nil || err1 != err2 {
+		t.Errorf("errors = %v, %v; want the same cached error", err1, err2)
+	}
+
+	// A cached locator matches as a freshly parsed one does
+	root := parse(t, matchPage)
+	fresh, _ := Parse("li.event > .odds")
+	if !reflect.DeepEqual(a.Match(root), fresh.Match(root)) {
+		t.Error("cached locator matches differently")
+	}
+}
+
+func TestCSSHint(t *testing.T) {
+	tests := []struct {
+		expr string
+		want hint
+	}{
+		{"button", hint{tag: "button"}},
+		{"BUTTON", hint{tag: "button"}},
+		{"#login", hint{id: "login"}},
+		{"form#login.form.wide", hint{tag: "form", id: "login", classes: []string{"form", "wide"}}},
+		{"nav > a.item", hint{tag: "a", classes: []string{"item"}}},
+		{"ul .team", hint{classes: []string{"team"}}},
+		{".item ~ li", hint{tag: "li"}},
+		{"a.item:hover", hint{tag: "a", classes: []string{"item"}}},
+		{"*", hint{}},
+		{"*.x", hint{classes: []string{"x"}}},
+		// attributes, arguments, groups and escapes get no hint
+		{"input[type=password]", hint{}},
+		{"li:nth-child(2)", hint{}},
+		{"a, button", hint{}},
+		{`#a\.b`, hint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := cssHint(tt.expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cssHint = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A hint must never reject an element its selector matches
+func TestCSSHintIsNecessary(t *testing.T) {
+	root := parse(t, matchPage)
+	for _, s := range matchSelectors {
+		l, err := Parse(s)
+		if err != nil || l.css == nil {
+			continue
+		}
+		for _, n := range l.css.MatchAll(root) {
+			if !l.hint.allows(n) {
+				t.Errorf("%s: hint %+v rejects matching <%s>", s, l.hint, n.Data)
+			}
+		}
+	}
+}
+
+func describe(nodes []*html.Node) string {
+	var out []string
+	for _, n := range nodes {
+		class, _ := attr(n, "class")
+		out = append(out, fmt.Sprintf("<%s class=%q>", n.Data, class))
+	}
+	return "[" + strings.Join(out, " ") + "]"
+}
@@ -0,0 +1,260 @@
+package locator
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// roleLocator matches elements by ARIA role and optionally accessible name
+type roleLocator struct {
+	role string
+	name *textMatcher
+}
+
+// parseRole reads "button", `button[name="Place Bet"]` (exact, ignoring
+// case), `button[name*="Bet"]` (substring) or "button[name=/bet/i]"
+func parseRole(expr string) (*roleLocator, error) {
+	r := &roleLocator{role: strings.ToLower(strings.TrimSpace(expr))}
+
+	open := strings.Index(expr, "[")
+	if open < 0 {
+		return r, nil
+	}
+	if !strings.HasSuffix(expr, "]") {
+		return nil, fmt.Errorf("unclosed [")
+	}
+	r.role = strings.ToLower(strings.TrimSpace(expr[:open]))
+	cond := strings.TrimSpace(expr[open+1 : len(expr)-1])
+
+	switch {
+	case strings.HasPrefix(cond, "name*="):
+		m, err := parseTextMatcher(unquote(cond[len("name*="):]))
+		if err != nil {
+			return nil, err
+		}
+		r.name = &m
+	case strings.HasPrefix(cond, "name="):
+		value := strings.TrimSpace(cond[len("name="):])
+		if strings.HasPrefix(value, "/") {
+			m, err := parseTextMatcher(value)
+			if err != nil {
+				return nil, err
+			}
+			r.name = &m
+		} else {
+			r.name = &textMatcher{exact: strings.ToLower(Normalize(unquote(value)))}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported condition [%s], use name=, name*= or name=/re/", cond)
+	}
+	if r.role == "" {
+		return nil, fmt.Errorf("missing role")
+	}
+	return r, nil
+}
+
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func (r *roleLocator) match(root *html.Node) []*html.Node {
+	ids, labels := indexNames(root)
+	texts := textIndex(root)
+
+	var out []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if skipped[n.Data] || hidden(n) {
+				return
+			}
+			if Role(n) == r.role && (r.name == nil || r.name.matchesName(accessibleName(n, ids, labels, texts))) {
+				out = append(out, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return out
+}
+
+// indexNames maps ids to elements and the ids of labelled controls to their
+// label[for], for the accessible name computation
+func indexNames(root *html.Node) (ids, labels map[string]*html.Node) {
+	ids, labels = map[string]*html.Node{}, map[string]*html.Node{}
+	var index func(n *html.Node)
+	index = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if id, ok := attr(n, "id"); ok {
+				ids[id] = n
+			}
+			if n.Data == "label" {
+				if target, ok := attr(n, "for"); ok {
+					labels[target] = n
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			index(c)
+		}
+	}
+	index(root)
+	return ids, labels
+}
+
+// matchesName compares accessible names; exact names ignore case
+func (m textMatcher) matchesName(name string) bool {
+	if m.re == nil && m.contains == "" {
+		return strings.ToLower(name) == m.exact
+	}
+	return m.matches(name)
+}
+
+// hidden reports elements removed from the accessibility tree
+func hidden(n *html.Node) bool {
+	if _, ok := attr(n, "hidden"); ok {
+		return true
+	}
+	if v, _ := attr(n, "aria-hidden"); v == "true" {
+		return true
+	}
+	style, _ := attr(n, "style")
+	style = strings.ReplaceAll(strings.ToLower(style), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// Role returns the explicit role attribute or the implicit ARIA role of an element
+func Role(n *html.Node) string {
+	if role, ok := attr(n, "role"); ok && role != "" {
+		return strings.ToLower(strings.Fields(role)[0])
+	}
+
+	switch n.Data {
+	case "button":
+		return "button"
+	case "a", "area":
+		if _, ok := attr(n, "href"); ok {
+			return "link"
+		}
+	case "input":
+		t, _ := attr(n, "type")
+		switch strings.ToLower(t) {
+		case "button", "submit", "reset", "image":
+			return "button"
+		case "checkbox":
+			return "checkbox"
+		case "radio":
+			return "radio"
+		case "range":
+			return "slider"
+		case "number":
+			return "spinbutton"
+		case "search":
+			return "searchbox"
+		case "hidden":
+			return ""
+		default:
+			return "textbox"
+		}
+	case "textarea":
+		return "textbox"
+	case "select":
+		return "combobox"
+	case "option":
+		return "option"
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return "heading"
+	case "ul", "ol":
+		return "list"
+	case "li":
+		return "listitem"
+	case "table":
+		return "table"
+	case "tr":
+		return "row"
+	case "td":
+		return "cell"
+	case "th":
+		return "columnheader"
+	case "img":
+		return "img"
+	case "nav":
+		return "navigation"
+	case "main":
+		return "main"
+	case "header":
+		return "banner"
+	case "footer":
+		return "contentinfo"
+	case "aside":
+		return "complementary"
+	case "form":
+		return "form"
+	case "dialog":
+		return "dialog"
+	case "section":
+		if _, ok := attr(n, "aria-label"); ok {
+			return "region"
+		}
+	}
+	return ""
+}
+
+// accessibleName approximates the accessible name computation: labelledby,
+// aria-label, associated labels, alt/value/placeholder, text and title
+func accessibleName(n *html.Node, ids, labels map[string]*html.Node, texts map[*html.Node]string) string {
+	if refs, ok := attr(n, "aria-labelledby"); ok {
+		var parts []string
+		for _, id := range strings.Fields(refs) {
+			if ref := ids[id]; ref != nil {
+				parts = append(parts, texts[ref])
+			}
+		}
+		if name := Normalize(strings.Join(parts, " ")); name != "" {
+			return name
+		}
+	}
+	if label, ok := attr(n, "aria-label"); ok && strings.TrimSpace(label) != "" {
+		return Normalize(label)
+	}
+
+	switch n.Data {
+	case "input", "select", "textarea":
+		if id, ok := attr(n, "id"); ok && labels[id] != nil {
+			return texts[labels[id]]
+		}
+		for p := n.Parent; p != nil; p = p.Parent {
+			if p.Type == html.ElementNode && p.Data == "label" {
+				return texts[p]
+			}
+		}
+		if t, _ := attr(n, "type"); t == "submit" || t == "button" || t == "reset" {
+			if v, ok := attr(n, "value"); ok {
+				return Normalize(v)
+			}
+		}
+		if v, ok := attr(n, "placeholder"); ok {
+			return Normalize(v)
+		}
+	case "img", "area":
+		if v, ok := attr(n, "alt"); ok {
+			return Normalize(v)
+		}
+	}
+
+	if text := texts[n]; text != "" {
+		return text
+	}
+	if v, ok := attr(n, "title"); ok {
+		return Normalize(v)
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package locator
+
+import (
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRole(t *testing.T) {
+	tests := []struct {
+		page string
+		want string
+	}{
+		{`<button id="x">Go</button>`, "button"},
+		{`<a id="x" href="/">Home</a>`, "link"},
+		{`<a id="x">Anchor</a>`, ""},
+		{`<input id="x">`, "textbox"},
+		{`<input id="x" type="email">`, "textbox"},
+		{`<input id="x" type="submit">`, "button"},
+		{`<input id="x" type="checkbox">`, "checkbox"},
+		{`<input id="x" type="radio">`, "radio"},
+		{`<input id="x" type="range">`, "slider"},
+		{`<input id="x" type="number">`, "spinbutton"},
+		{`<input id="x" type="search">`, "searchbox"},
+		{`<input id="x" type="hidden">`, ""},
+		{`<textarea id="x"></textarea>`, "textbox"},
+		{`<select id="x"><option>1</option></select>`, "combobox"},
+		{`<h3 id="x">Title</h3>`, "heading"},
+		{`<ul id="x"><li>a</li></ul>`, "list"},
+		{`<img id="x" src="a.png">`, "img"},
+		{`<nav id="x"></nav>`, "navigation"},
+		{`<header id="x"></header>`, "banner"},
+		{`<footer id="x"></footer>`, "contentinfo"},
+		{`<section id="x"></section>`, ""},
+		{`<section id="x" aria-label="Live"></section>`, "region"},
+		{`<div id="x" role="Button tab">Go</div>`, "button"},
+		{`<span id="x">plain</span>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			n := byID(parse(t, tt.page), "x")
+			if got := Role(n); got != tt.want {
+				t.Errorf("Role = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+const namePage = `<html><body>
+<span id="first">Your</span><span id="second">stake</span>
+<input id="stake" aria-labelledby="first second" aria-label="ignored">
+<input id="search" aria-label="  Search   events ">
+<label for="user">User name</label><input id="user">
+<label>Password <input id="pw" type="password"></label>
+<input id="go" type="submit" value="Log in">
+<input id="email" placeholder="you@example.com">
+<img id="logo" alt="Bookie logo">
+<button id="bet">Place <b>bet</b></button>
+<a id="help" href="/help" title="Get help"></a>
+<button id="empty-ref" aria-labelledby="missing">Fallback text</button>
+<div id="none"></div>
+</body></html>`
+
+func TestAccessibleName(t *testing.T) {
+	root := parse(t, namePage)
+	ids, labels := indexNames(root)
+	texts := textIndex(root)
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"stake", "Your stake"},
+		{"search", "Search events"},
+		{"user", "User name"},
+		{"pw", "Password"},
+		{"go", "Log in"},
+		{"email", "you@example.com"},
+		{"logo", "Bookie logo"},
+		{"bet", "Place bet"},
+		{"help", "Get help"},
+		{"empty-ref", "Fallback text"},
+		{"none", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := accessibleName(ids[tt.id], ids, labels, texts); got != tt.want {
+				t.Errorf("accessibleName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleLocator(t *testing.T) {
+	root := parse(t, namePage+`<div hidden><button id="hidden-btn">Place bet</button></div>
+<button id="aria-hidden" aria-hidden="true">Place bet</button>
+<button id="styled" style="display: none">Place bet</button>`)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"button", "go bet empty-ref"},
+		{`button[name="place BET"]`, "bet"},
+		{`button[name='Place']`, ""},
+		{`button[name*="place"]`, "bet"},
+		{"button[name=/^log/i]", "go"},
+		{`textbox[name="User name"]`, "user"},
+		{`textbox[name="Password"]`, "pw"},
+		{`textbox[name*=stake]`, "stake"},
+		{`link[name="Get help"]`, "help"},
+		{`img[name="Bookie logo"]`, "logo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			l, err := Parse("role:" + tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(l.Match(root)); got != tt.want {
+				t.Errorf("match = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRoleErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"button[name=x", "unclosed ["},
+		{"button[label=x]", "unsupported condition [label=x], use name=, name*= or name=/re/"},
+		{`[name="x"]`, "missing role"},
+		{"button[name=/(/]", "error parsing regexp: missing closing ): `(`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseRole(tt.expr)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// byID finds the element with an id
+func byID(root *html.Node, id string) *html.Node {
+	if v, ok := attr(root, "id"); ok && v == id {
+		return root
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if n := byID(c, id); n != nil {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,462 @@
+package locator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// xpath is a compiled location path. The supported subset covers / and //
+// steps, element names, *, . and .., and predicates with positions, last(),
+// @attr, text(), ., =, !=, and, or, not(), contains(), starts-with() and
+// normalize-space(). text() is the trimmed text directly inside an element.
+type xpath struct {
+	steps []xstep
+}
+
+type xstep struct {
+	descendant bool
+	test       string // element name, "*", "." or ".."
+	predicates []xexpr
+}
+
+// compileXPath parses an absolute (/ or //) or relative (./) location path
+func compileXPath(expr string) (*xpath, error) {
+	p := &xparser{src: expr}
+	if !strings.HasPrefix(expr, "/") && !strings.HasPrefix(expr, ".") {
+		return nil, fmt.Errorf("path must start with /, // or .")
+	}
+
+	x := &xpath{}
+	first := true
+	for p.pos < len(p.src) {
+		descendant := false
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			descendant = true
+			p.pos += 2
+		case p.src[p.pos] == '/':
+			p.pos++
+		case !first:
+			return nil, fmt.Errorf("unexpected %q at %d", p.src[p.pos:], p.pos)
+		}
+		first = false
+
+		step := xstep{descendant: descendant}
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], ".."):
+			step.test = ".."
+			p.pos += 2
+		case strings.HasPrefix(p.src[p.pos:], "."):
+			step.test = "."
+			p.pos++
+		case strings.HasPrefix(p.src[p.pos:], "*"):
+			step.test = "*"
+			p.pos++
+		default:
+			step.test = strings.ToLower(p.name())
+			if step.test == "" {
+				return nil, fmt.Errorf("expected an element name at %d", p.pos)
+			}
+			if axis, _, ok := strings.Cut(step.test, "::"); ok {
+				return nil, fmt.Errorf("axis %s:: is not supported, use /, //, . or ..", axis)
+			}
+		}
+
+		for p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.consume("]") {
+				return nil, fmt.Errorf("expected ] at %d", p.pos)
+			}
+			step.predicates = append(step.predicates, e)
+		}
+		x.steps = append(x.steps, step)
+	}
+	if len(x.steps) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return x, nil
+}
+
+// match evaluates the path from the document root
+func (x *xpath) match(root *html.Node) []*html.Node {
+	context := []*html.Node{root}
+	for _, step := range x.steps {
+		seen := map[*html.Node]bool{}
+		var next []*html.Node
+		add := func(nodes []*html.Node) {
+			for _, n := range nodes {
+				if !seen[n] {
+					seen[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+
+		for _, c := range context {
+			parents := []*html.Node{c}
+			if step.descendant {
+				parents = selfAndDescendants(c)
+			}
+			for _, p := range parents {
+				add(step.apply(p))
+			}
+		}
+		context = next
+	}
+
+	var out []*html.Node
+	for _, n := range context {
+		if n.Type == html.ElementNode {
+			out = append(out, n)
+		}
+	}
+	return documentOrder(root, out)
+}
+
+// apply selects the step's nodes relative to one context node, filtering by
+// predicates with positions counted among them
+func (s xstep) apply(c *html.Node) []*html.Node {
+	var candidates []*html.Node
+	switch s.test {
+	case ".":
+		candidates = []*html.Node{c}
+	case "..":
+		if c.Parent != nil {
+			candidates = []*html.Node{c.Parent}
+		}
+	default:
+		for ch := c.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.Type == html.ElementNode && (s.test == "*" || ch.Data == s.test) {
+				candidates = append(candidates, ch)
+			}
+		}
+	}
+
+	for _, pred := range s.predicates {
+		var kept []*html.Node
+		for i, n := range candidates {
+			ctx := xctx{node: n, position: i + 1, size: len(candidates)}
+			v := pred(ctx)
+			if v.isNum {
+				if int(v.num) == ctx.position {
+					kept = append(kept, n)
+				}
+			} else if v.truthy() {
+				kept = append(kept, n)
+			}
+		}
+		candidates = kept
+	}
+	return candidates
+}
+
+func selfAndDescendants(n *html.Node) []*html.Node {
+	out := []*html.Node{n}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			out = append(out, selfAndDescendants(c)...)
+		}
+	}
+	return out
+}
+
+// xctx is the context a predicate is evaluated in
+type xctx struct {
+	node     *html.Node
+	position int
+	size     int
+}
+
+// xval is a predicate value: a string, a number or a boolean. Present
+// attributes are true in [@attr] even when empty.
+type xval struct {
+	str     string
+	num     float64
+	b       bool
+	isNum   bool
+	isBool  bool
+	present bool
+}
+
+func (v xval) truthy() bool {
+	switch {
+	case v.isBool:
+		return v.b
+	case v.isNum:
+		return v.num != 0
+	default:
+		return v.str != "" || v.present
+	}
+}
+
+func (v xval) String() string {
+	switch {
+	case v.isBool:
+		return strconv.FormatBool(v.b)
+	case v.isNum:
+		return strconv.FormatFloat(v.num, 'f', -1, 64)
+	default:
+		return v.str
+	}
+}
+
+func boolVal(b bool) xval { return xval{b: b, isBool: true} }
+
+type xexpr func(xctx) xval
+
+// xparser is a recursive-descent parser for predicate expressions
+type xparser struct {
+	src string
+	pos int
+}
+
+func (p *xparser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *xparser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// keyword consumes a word such as "and" only when it is not part of a longer name
+func (p *xparser) keyword(word string) bool {
+	p.skipSpace()
+	rest := p.src[p.pos:]
+	if !strings.HasPrefix(rest, word) {
+		return false
+	}
+	if len(rest) > len(word) && isNameChar(rune(rest[len(word)])) {
+		return false
+	}
+	p.pos += len(word)
+	return true
+}
+
+func isNameChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == ':'
+}
+
+func (p *xparser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) && isNameChar(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *xparser) parseOr() (xexpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(c xctx) xval { return boolVal(l(c).truthy() || right(c).truthy()) }
+	}
+	return left, nil
+}
+
+func (p *xparser) parseAnd() (xexpr, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(c xctx) xval { return boolVal(l(c).truthy() && right(c).truthy()) }
+	}
+	return left, nil
+}
+
+func (p *xparser) parseCompare() (xexpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	negate := false
+	switch {
+	case p.consume("!="):
+		negate = true
+	case p.consume("="):
+	default:
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return func(c xctx) xval {
+		equal := left(c).String() == right(c).String()
+		return boolVal(equal != negate)
+	}, nil
+}
+
+func (p *xparser) parseOperand() (xexpr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	ch := p.src[p.pos]
+	switch {
+	case ch == '\'' || ch == '"':
+		end := strings.IndexByte(p.src[p.pos+1:], ch)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed string at %d", p.pos)
+		}
+		s := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return func(xctx) xval { return xval{str: s} }, nil
+
+	case ch >= '0' && ch <= '9':
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(xctx) xval { return xval{num: n, isNum: true} }, nil
+
+	case ch == '@':
+		p.pos++
+		name := strings.ToLower(p.name())
+		if name == "" {
+			return nil, fmt.Errorf("expected an attribute name at %d", p.pos)
+		}
+		return func(c xctx) xval {
+			v, ok := attr(c.node, name)
+			return xval{str: v, present: ok}
+		}, nil
+
+	case ch == '.':
+		p.pos++
+		return func(c xctx) xval { return xval{str: stringValue(c.node)} }, nil
+
+	case ch == '(':
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, fmt.Errorf("expected ) at %d", p.pos)
+		}
+		return e, nil
+	}
+
+	name := p.name()
+	if name == "" || !p.consume("(") {
+		return nil, fmt.Errorf("unexpected %q at %d", p.src[p.pos:], p.pos)
+	}
+	var args []xexpr
+	if !p.consume(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.consume(")") {
+				break
+			}
+			if !p.consume(",") {
+				return nil, fmt.Errorf("expected , or ) at %d", p.pos)
+			}
+		}
+	}
+	return function(name, args)
+}
+
+// function builds a call to one of the supported XPath functions
+func function(name string, args []xexpr) (xexpr, error) {
+	arity := map[string][2]int{
+		"text": {0, 0}, "position": {0, 0}, "last": {0, 0}, "not": {1, 1},
+		"contains": {2, 2}, "starts-with": {2, 2}, "normalize-space": {0, 1},
+	}
+	n, ok := arity[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported function %s()", name)
+	}
+	if len(args) < n[0] || len(args) > n[1] {
+		return nil, fmt.Errorf("%s() takes %d to %d arguments", name, n[0], n[1])
+	}
+
+	switch name {
+	case "text":
+		return func(c xctx) xval { return xval{str: ownText(c.node)} }, nil
+	case "position":
+		return func(c xctx) xval { return xval{num: float64(c.position), isNum: true} }, nil
+	case "last":
+		return func(c xctx) xval { return xval{num: float64(c.size), isNum: true} }, nil
+	case "not":
+		return func(c xctx) xval { return boolVal(!args[0](c).truthy()) }, nil
+	case "contains":
+		return func(c xctx) xval {
+			return boolVal(strings.Contains(args[0](c).String(), args[1](c).String()))
+		}, nil
+	case "starts-with":
+		return func(c xctx) xval {
+			return boolVal(strings.HasPrefix(args[0](c).String(), args[1](c).String()))
+		}, nil
+	default: // normalize-space
+		return func(c xctx) xval {
+			if len(args) == 0 {
+				return xval{str: Normalize(stringValue(c.node))}
+			}
+			return xval{str: Normalize(args[0](c).String())}
+		}, nil
+	}
+}
+
+// ownText is the trimmed text directly inside an element
+func ownText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// stringValue is all visible text below an element, as XPath's string(.)
+// without scripts and styles
+func stringValue(n *html.Node) string {
+	switch {
+	case n.Type == html.TextNode:
+		return n.Data
+	case n.Type == html.ElementNode && skipped[n.Data]:
+		return ""
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(stringValue(c))
+	}
+	return b.String()
+}
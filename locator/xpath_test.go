@@ -0,0 +1,142 @@
+package locator
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const xpathPage = `<html><head><title>Bookie</title><script>var x = "Place bet";</script></head><body>
+<div id="header"><a id="home" href="/">Home</a><a id="promo" class="promo big">Promotions</a></div>
+<form id="login">
+	<label id="user-label" for="user">Username</label><input id="user" name="user">
+	<input id="pw" type="password" name="pw" placeholder="">
+	<button id="login-btn" type="submit">  Log   <b id="in">in</b> </button>
+</form>
+<ul id="events">
+	<li id="ev1" data-sport="football">Arsenal v Chelsea</li>
+	<li id="ev2" data-sport="tennis">Alcaraz v Sinner</li>
+	<li id="ev3" data-sport="football">Inter v Milan</li>
+</ul>
+<div id="slip"><span id="odds">2.50</span><button id="place">Place bet</button></div>
+</body></html>`
+
+// parse reads a page into its root node
+func parse(t testing.TB, page string) *html.Node {
+	t.Helper()
+	root, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// ids names the matched elements by id, or by tag when they have none
+func ids(nodes []*html.Node) string {
+	var out []string
+	for _, n := range nodes {
+		id, ok := attr(n, "id")
+		if !ok {
+			id = "<" + n.Data + ">"
+		}
+		out = append(out, id)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestXPath(t *testing.T) {
+	root := parse(t, xpathPage)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// axes: child, descendant, self and parent
+		{"/html/body/form/input", "user pw"},
+		{"//form/input", "user pw"},
+		{"//form//b", "in"},
+		{"//ul/*", "ev1 ev2 ev3"},
+		{"//span/../button", "place"},
+		{"//b/..", "login-btn"},
+		{"//li/.", "ev1 ev2 ev3"},
+		{"/html/nosuch", ""},
+		// positions
+		{"//li[1]", "ev1"},
+		{"//li[last()]", "ev3"},
+		{"//li[position()=2]", "ev2"},
+		{"//li[2][@data-sport='football']", ""},
+		{"//li[@data-sport='football'][2]", "ev3"},
+		{"//a[2]", "promo"},
+		// attribute predicates
+		{"//input[@type='password']", "pw"},
+		{"//input[@placeholder]", "pw"},
+		{"//input[not(@type)]", "user"},
+		{"//li[@data-sport!='football']", "ev2"},
+		{`//a[@id="home" or @id="promo"]`, "home promo"},
+		{"//li[@data-sport='football' and contains(., 'Milan')]", "ev3"},
+		{"//a[contains(@class, 'promo')]", "promo"},
+		{"//*[starts-with(@id, 'ev')]", "events ev1 ev2 ev3"},
+		// text
+		{"//button[text()='Place bet']", "place"},
+		{"//button[text()='Log in']", ""},
+		{"//button[text()='Log']", "login-btn"},
+		{"//button[normalize-space()='Log in']", "login-btn"},
+		{"//button[normalize-space(.)='Log in']", "login-btn"},
+		{"//*[contains(text(), 'Alcaraz')]", "ev2"},
+		{"//span[.='2.50']", "odds"},
+		// script text is not part of the string value
+		{"//*[contains(., 'Place bet')]", "<html> <body> slip place"},
+		{"//li[(@data-sport='tennis')]", "ev2"},
+		{"./html/body/div[@id='slip']", "slip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			x, err := compileXPath(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(x.match(root)); got != tt.want {
+				t.Errorf("match = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"button", "path must start with /, // or ."},
+		{"//", "expected an element name at 2"},
+		{"//a/@href", "expected an element name at 4"},
+		{"//a/following-sibling::a", "axis following-sibling:: is not supported, use /, //, . or .."},
+		{"//li[1", "expected ] at 6"},
+		{"//li[", "unexpected end of expression"},
+		{"//li[@]", "expected an attribute name at 6"},
+		{"//li[text(]", `unexpected "]" at 10`},
+		{"//li[count(*)=3]", `unexpected "*)=3]" at 11`},
+		{"//li[string-length(.)]", "unsupported function string-length()"},
+		{"//li[contains(.)]", "contains() takes 2 to 2 arguments"},
+		{"//li[contains(., 'a' 'b')]", "expected , or ) at 21"},
+		{"//li['open]", "unclosed string at 5"},
+		{"//li[(@id]", "expected ) at 9"},
+		{"//li[@id='ev1'] | //a", `unexpected " | //a" at 15`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := compileXPath(tt.expr)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseXPathError(t *testing.T) {
+	_, err := Parse("xpath://a/ancestor::div")
+	want := `invalid xpath "//a/ancestor::div": axis ancestor:: is not supported, use /, //, . or ..`
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
@@ -1,26 +1,62 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"diago/api"
 	"diago/config"
+	"diago/daemon"
+	"diago/discover"
 	"diago/fetch"
+	"diago/har"
+	"diago/history"
+	"diago/lint"
+	"diago/metrics"
+	"diago/notify"
 	"diago/report"
+	"diago/server"
 	"diago/utils"
+	"diago/verify"
 
 	_ "diago/bookies"
+	"github.com/PuerkitoBio/goquery"
 	"gopkg.in/yaml.v3"
 )
 
 func main() {
-	mode := flag.String("mode", "fetch", "Mode: generate, fetch, or auto")
+	mode := flag.String("mode", "fetch", "Mode: generate, fetch, auto, promote, discover <bookie>, lint, validate, daemon, serve, metrics, or history [bookie] [selector]")
 	bookiesFile := flag.String("bookies-file", "bookies.txt", "Bookies file")
+	settingsFile := flag.String("settings", "diago.yaml", "Run-wide settings file (optional)")
 	outputDir := flag.String("output-dir", "EMC", "Output directory")
 	bakeOverrides := flag.Bool("bake-overrides", false, "Apply overrides and persist them to config.yaml, then delete overrides.yaml")
+	suggestFixes := flag.Bool("suggest", false, "Search fetched pages for replacement candidates of failing selectors")
+	applySuggestions := flag.Bool("apply-suggestions", false, "Write the best suggestion for each failing selector into overrides.yaml (implies --suggest)")
+	suggestMinConfidence := flag.Float64("suggest-min-confidence", 0.6, "Minimum confidence for --apply-suggestions")
+	discoverTarget := flag.String("discover-target", "overrides", "Where discover writes selectors: overrides (<bookie>/overrides.discovered.yaml) or config (<bookie>/config.yaml)")
+	discoverMaxPages := flag.Int("discover-max-pages", discover.DefaultMaxPages, "Maximum pages discover crawls per bookie")
+	discoverForce := flag.Bool("discover-force", false, "With --discover-target=config, also replace selectors that are already set")
+	lintFetch := flag.Bool("lint-fetch", false, "Fetch pages while linting to flag selectors that match inside ads or iframes")
+	offline := flag.Bool("offline", false, "Verify against the latest stored snapshot instead of fetching live pages")
+	fromSnapshot := flag.String("from-snapshot", "", "Verify against the stored snapshot of this run id (implies --offline)")
+	harRecord := flag.String("har-record", "", "Record all HTTP traffic of the run to this HAR file")
+	harReplay := flag.String("har-replay", "", "Answer all HTTP requests from this HAR file instead of the network")
+	formats := flag.String("format", "json,markdown", "Comma-separated report formats to write: json, markdown, junit, html")
+	diffAgainst := flag.String("diff-against", "", "Run id from the history to diff the report against (default: the previous run)")
+	failOnRegression := flag.Bool("fail-on-regression", false, "Exit with status 3 if a selector regressed or a bookie became unreachable since the diffed run")
+	historyDays := flag.Int("history-days", 0, "Only consider runs from the last N days in history mode (0 = all)")
+	minScore := flag.Float64("min-score", 0, "Exit with status 2 if any bookie's health score is below this value (0 disables the gate)")
 	flag.Parse()
 
 	// Load enabled bookies from bookies.txt
@@ -30,6 +66,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	reporters, err := verify.ParseReporters(*formats)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	settings, err := config.LoadSettings(*settingsFile)
+	if err != nil {
+		fmt.Printf("❌ Failed to load settings: %v\n", err)
+		os.Exit(1)
+	}
+
 	overridesPath := filepath.Join(*outputDir, "overrides.yaml")
 	var overrides config.OverrideMap
 	usingOverrides := false
@@ -41,6 +89,26 @@ func main() {
 		overrides = make(config.OverrideMap)
 	}
 
+	// Long-running modes would grow the recording without end and never save it
+	if *harRecord != "" && (*mode == "daemon" || *mode == "serve") {
+		fmt.Printf("❌ --har-record is not supported in %s mode\n", *mode)
+		os.Exit(1)
+	}
+	client, saveHAR := setupHAR(*harRecord, *harReplay)
+	verifyOpts := []verify.Option{
+		verify.WithOutput(os.Stdout),
+		verify.WithClient(client),
+		verify.WithOutputDir(*outputDir),
+		verify.WithSettings(settings),
+		verify.WithReporters(reporters...),
+		verify.WithSuggestions(*suggestFixes || *applySuggestions),
+		verify.WithDiffAgainst(*diffAgainst),
+	}
+	if *offline || *fromSnapshot != "" {
+		verifyOpts = append(verifyOpts, verify.Offline(*fromSnapshot))
+	}
+	verifier := verify.New(verifyOpts...)
+
 	switch *mode {
 	case "generate":
 		generateConfigs(enabledBookies, overrides, *outputDir)
@@ -49,8 +117,15 @@ func main() {
 		}
 
 	case "fetch":
-		fullReport := fetchConfigs(enabledBookies, *outputDir)
+		fullReport := runVerifier(verifier, enabledBookies)
 		createLatestSnippet(fullReport, *outputDir)
+		if *applySuggestions {
+			applySuggestedFixes(fullReport, *outputDir, overridesPath, *suggestMinConfidence)
+		}
+		saveHAR()
+		sendNotifications(fullReport, *outputDir, settings)
+		gateOnScore(fullReport, *minScore)
+		gateOnDiff(fullReport, *failOnRegression, settings.Flaky.AlertAfter)
 
 	case "auto":
 		if configsMissing(enabledBookies, *outputDir) {
@@ -60,8 +135,44 @@ func main() {
 				bakeOverridesFile(*outputDir, overridesPath)
 			}
 		}
-		fullReport := fetchConfigs(enabledBookies, *outputDir)
+		fullReport := runVerifier(verifier, enabledBookies)
 		createLatestSnippet(fullReport, *outputDir)
+		if *applySuggestions {
+			applySuggestedFixes(fullReport, *outputDir, overridesPath, *suggestMinConfidence)
+		}
+		saveHAR()
+		sendNotifications(fullReport, *outputDir, settings)
+		gateOnScore(fullReport, *minScore)
+		gateOnDiff(fullReport, *failOnRegression, settings.Flaky.AlertAfter)
+
+	case "promote":
+		promoteFallbacks(enabledBookies, *outputDir, settings.History)
+
+	case "daemon":
+		runDaemon(*bookiesFile, *settingsFile, *outputDir, *suggestFixes, reporters, client)
+
+	case "serve":
+		serveAPI(*bookiesFile, *outputDir, settings, *suggestFixes, reporters, client)
+
+	case "metrics":
+		serveMetrics(*outputDir, settings.Metrics)
+
+	case "history":
+		showHistory(*outputDir, flag.Arg(0), flag.Arg(1), *historyDays)
+
+	case "discover":
+		discoverSelectors(flag.Arg(0), enabledBookies, *outputDir, *discoverTarget, *discoverMaxPages, *suggestMinConfidence, *discoverForce, client)
+		saveHAR()
+
+	case "lint":
+		if !lintConfigs(enabledBookies, *outputDir, lint.Levels(settings.Lint), *lintFetch, client) {
+			os.Exit(1)
+		}
+
+	case "validate":
+		if !validateConfigs(enabledBookies, *outputDir, settings) {
+			os.Exit(1)
+		}
 
 	default:
 		fmt.Printf("❌ Unknown mode: %s\n", *mode)
@@ -69,11 +180,237 @@ func main() {
 	}
 }
 
+// gateOnDiff exits with status 3 when enabled and a check regressed or a
+// bookie became unreachable, after failing alertAfter runs in a row
+func gateOnDiff(fullReport report.FullReport, enabled bool, alertAfter int) {
+	if !enabled {
+		return
+	}
+	regressions, unreachable := report.Alerts(fullReport, alertAfter)
+	if len(regressions) == 0 && len(unreachable) == 0 {
+		fmt.Println("🚦 No regressions")
+		return
+	}
+	for _, c := range regressions {
+		fmt.Printf("🚦 %s %s regressed: %s\n", c.Bookie, c.Label, c.Message)
+	}
+	for _, c := range unreachable {
+		fmt.Printf("🚦 %s became unreachable: %s\n", c.Bookie, c.Message)
+	}
+	os.Exit(3)
+}
+
+// sendNotifications sends the regressions, outages and fixes of a live run
+// to the configured sinks. Failing sinks are reported but never fail the run.
+func sendNotifications(fullReport report.FullReport, outputDir string, settings config.Settings) {
+	if fullReport.Offline || len(settings.Notify.Sinks) == 0 {
+		return
+	}
+	msg := notify.FromReport(fullReport, settings.Flaky.AlertAfter)
+	statePath := notify.StatePath(outputDir, settings.Notify)
+	if err := notify.Send(settings.Notify, statePath, msg, time.Now()); err != nil {
+		fmt.Printf("⚠️ Failed to send notifications: %v\n", err)
+	}
+}
+
+// runDaemon checks bookies on their schedules until interrupted, writing
+// the reports, history, notifications and metrics after every cycle. The
+// manifest and settings are reloaded when they change, and configs are
+// read afresh every cycle. A first interrupt finishes the running cycle,
+// a second one exits at once.
+func runDaemon(bookiesFile, settingsFile, outputDir string, suggest bool, reporters []verify.Reporter, client *http.Client) {
+	var settings config.Settings
+	var bookies []utils.Bookie
+	var exporter *metrics.Exporter
+	load := func() (daemon.Source, error) {
+		s, err := config.LoadSettings(settingsFile)
+		if err != nil {
+			return daemon.Source{}, fmt.Errorf("failed to load settings: %w", err)
+		}
+		b, err := utils.EnabledBookies(bookiesFile)
+		if err != nil {
+			return daemon.Source{}, fmt.Errorf("failed to load bookies: %w", err)
+		}
+		settings, bookies = s, b
+		if exporter != nil {
+			exporter.SetSettings(s.Metrics)
+		}
+		return daemon.Source{Bookies: bookieNames(b), Settings: s.Daemon, Files: []string{bookiesFile, settingsFile}}, nil
+	}
+	if _, err := load(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	exporter = metrics.NewExporter(settings.Metrics)
+	if runs, err := history.Load(history.Path(outputDir)); err == nil {
+		for _, run := range runs {
+			exporter.Observe(run.Report)
+		}
+	}
+	var server *http.Server
+	if settings.Metrics.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter)
+		server = &http.Server{Addr: settings.Metrics.Listen, Handler: mux}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("⚠️ Metrics server failed: %v\n", err)
+			}
+		}()
+		fmt.Printf("📏 Serving metrics on %s/metrics\n", settings.Metrics.Listen)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Println("🛑 Shutting down after the current cycle (interrupt again to exit now)...")
+		cancel()
+		<-signals
+		os.Exit(1)
+	}()
+
+	cycle := func(ctx context.Context, tier string, names []string) {
+		var due []utils.Bookie
+		for _, b := range bookies {
+			if slices.Contains(names, b.Name()) {
+				due = append(due, b)
+			}
+		}
+		// The cycle finishes on a first interrupt, so it is not cancelled with ctx
+		verifier := verify.New(
+			verify.WithOutput(os.Stdout),
+			verify.WithClient(client),
+			verify.WithOutputDir(outputDir),
+			verify.WithSettings(settings),
+			verify.WithReporters(reporters...),
+			verify.WithSuggestions(suggest),
+			verify.Partial(len(due) < len(bookies)),
+		)
+		fullReport := runVerifier(verifier, due)
+		createLatestSnippet(fullReport, outputDir)
+		sendNotifications(fullReport, outputDir, settings)
+		exporter.Observe(fullReport)
+	}
+
+	statePath := daemon.StatePath(outputDir, settings.Daemon)
+	fmt.Printf("🕒 Daemon started, state in %s\n", statePath)
+	err := daemon.Run(ctx, daemon.Options{Load: load, Run: cycle, StatePath: statePath})
+	if server != nil {
+		server.Shutdown(context.Background())
+	}
+	if err != nil {
+		fmt.Printf("❌ Daemon failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("👋 Daemon stopped")
+}
+
+// serveAPI runs the HTTP API until interrupted. Runs triggered through it
+// go through the same fetch as the CLI, writing reports, history,
+// notifications and metrics, and stream their progress to subscribers.
+func serveAPI(bookiesFile, outputDir string, settings config.Settings, suggest bool, reporters []verify.Reporter, client *http.Client) {
+	enabled := func() ([]utils.Bookie, error) { return utils.EnabledBookies(bookiesFile) }
+
+	run := func(ctx context.Context, names []string, progress func(server.Event)) (report.FullReport, error) {
+		all, err := enabled()
+		if err != nil {
+			return report.FullReport{}, err
+		}
+		var bookies []utils.Bookie
+		for _, b := range all {
+			if slices.Contains(names, b.Name()) {
+				bookies = append(bookies, b)
+			}
+		}
+
+		verifier := verify.New(
+			verify.WithOutput(os.Stdout),
+			verify.WithClient(client),
+			verify.WithOutputDir(outputDir),
+			verify.WithSettings(settings),
+			verify.WithReporters(reporters...),
+			verify.WithSuggestions(suggest),
+			verify.Partial(len(bookies) < len(all)),
+			verify.OnBookieStart(func(bookie string) { progress(server.Event{Type: server.EventBookieStarted, Bookie: bookie}) }),
+			verify.OnSelectorResult(func(bookie string, r report.SelectorResult) {
+				progress(server.Event{Type: server.EventSelectorResult, Bookie: bookie, Result: &r})
+			}),
+			verify.OnBookieDone(func(r report.BookieReport) {
+				progress(server.Event{Type: server.EventBookieFinished, Bookie: r.Name, Summary: server.Summarize(r)})
+			}),
+		)
+		// Shutdown waits for running runs to finish rather than cancelling them
+		fullReport, err := verifier.Run(context.WithoutCancel(ctx), bookieNames(bookies))
+		if err != nil {
+			return fullReport, err
+		}
+		createLatestSnippet(fullReport, outputDir)
+		sendNotifications(fullReport, outputDir, settings)
+		return fullReport, nil
+	}
+
+	srv, err := server.New(server.Options{Settings: settings.Server, OutputDir: outputDir, Bookies: enabled, Run: run})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("🛰️ Serving the API on %s\n", settings.Server.Listen)
+	if err := srv.ListenAndServe(ctx); err != nil && err != http.ErrServerClosed {
+		fmt.Printf("❌ API server failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("👋 API server stopped")
+}
+
+// bookieNames lists the names of the bookies, in order
+func bookieNames(bookies []utils.Bookie) []string {
+	names := make([]string, len(bookies))
+	for i, b := range bookies {
+		names[i] = b.Name()
+	}
+	return names
+}
+
+// runVerifier verifies the bookies and saves the run, exiting when it cannot be saved
+func runVerifier(verifier *verify.Verifier, bookies []utils.Bookie) report.FullReport {
+	fullReport, err := verifier.Run(context.Background(), bookieNames(bookies))
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	return fullReport
+}
+
+// createLatestSnippet generates latest_report.md from full report
+func createLatestSnippet(fullReport report.FullReport, outputDir string) {
+	path, err := verify.WriteLatestSnippet(fullReport, outputDir)
+	if err != nil {
+		fmt.Printf("❌ Failed to create latest_report.md: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Created latest report snippet: %s\n", path)
+}
+
+// bakeOverridesFile merges overrides into the base config and renames the original overrides.yaml
+func bakeOverridesFile(outputDir, overridesPath string) {
+	bakedPath, err := verify.BakeOverrides(outputDir, overridesPath, os.Stdout)
+	if err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+		return
+	}
+	fmt.Printf("🍞 All overrides baked. Original overrides.yaml renamed to %s\n", bakedPath)
+}
+
 // configsMissing checks if any bookie's config.yaml is missing
 func configsMissing(bookies []utils.Bookie, outputDir string) bool {
 	for _, b := range bookies {
-		cfgPath := filepath.Join(outputDir, strings.ToLower(b.Name()), "config.yaml")
-		if _, err := os.Stat(cfgPath); os.IsNotExist(err) {
+		if _, err := os.Stat(verify.ConfigPath(outputDir, b.Name())); os.IsNotExist(err) {
 			return true
 		}
 	}
@@ -109,139 +446,581 @@ func generateConfigs(bookies []utils.Bookie, overrides config.OverrideMap, outpu
     }
 }
 
-// fetchConfigs fetches all bookies and returns the full report
-func fetchConfigs(bookies []utils.Bookie, outputDir string) report.FullReport {
-	fmt.Println("🌐 Fetching and verifying bookies...")
+// serveMetrics serves /metrics from the run history, reloading it whenever a
+// fetch appends a run, so cron-driven fetches can be scraped
+func serveMetrics(outputDir string, settings config.MetricsSettings) {
+	path := history.Path(outputDir)
+	var (
+		mu       sync.Mutex
+		exporter = metrics.NewExporter(settings)
+		loadedAt time.Time
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(loadedAt) {
+			if runs, err := history.Load(path); err != nil {
+				fmt.Printf("⚠️ Failed to reload run history: %v\n", err)
+			} else {
+				exporter = metrics.NewExporter(settings)
+				for _, run := range runs {
+					exporter.Observe(run.Report)
+				}
+				loadedAt = info.ModTime()
+			}
+		}
+		current := exporter
+		mu.Unlock()
+		current.ServeHTTP(w, req)
+	})
 
-	var summary []report.BookieReport
-	var details []report.BookieReport
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	fmt.Printf("📏 Serving metrics of %s on %s/metrics\n", path, settings.Listen)
+	if err := http.ListenAndServe(settings.Listen, mux); err != nil {
+		fmt.Printf("❌ Metrics server failed: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-	for _, b := range bookies {
-		cfgPath := filepath.Join(outputDir, strings.ToLower(b.Name()), "config.yaml")
-		cfg, err := loadConfig(cfgPath)
+// setupHAR returns a client that records or replays traffic through a HAR,
+// nil when neither is asked for, and the function that writes the
+// recording once the run is done
+func setupHAR(recordPath, replayPath string) (*http.Client, func()) {
+	if recordPath == "" && replayPath == "" {
+		return nil, func() {}
+	}
+
+	var transport http.RoundTripper
+	if replayPath != "" {
+		f, err := har.Load(replayPath)
+		if err != nil {
+			fmt.Printf("❌ Failed to load HAR: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("📼 Replaying %d recorded requests from %s\n", len(f.Log.Entries), replayPath)
+		transport = har.NewReplayer(f)
+	}
+	client := &http.Client{Timeout: fetch.Client.Timeout, Transport: transport}
+	if recordPath == "" {
+		return client, func() {}
+	}
+
+	recorder := har.NewRecorder(transport)
+	client.Transport = recorder
+	return client, func() {
+		f := recorder.HAR()
+		if err := har.Save(recordPath, f); err != nil {
+			fmt.Printf("❌ Failed to save HAR: %v\n", err)
+			return
+		}
+		fmt.Printf("📼 Recorded %d requests to %s\n", len(f.Log.Entries), recordPath)
+	}
+}
+
+// applySuggestedFixes writes the most confident suggestion for each failing
+// selector into overrides.yaml so it can be baked into config.yaml
+func applySuggestedFixes(fullReport report.FullReport, outputDir, overridesPath string, minConfidence float64) {
+	overrides := make(config.OverrideMap)
+	if _, err := os.Stat(overridesPath); err == nil {
+		loaded, err := config.LoadOverrides(overridesPath)
+		if err != nil {
+			fmt.Printf("❌ Failed to load overrides file: %v\n", err)
+			return
+		}
+		overrides = loaded
+	}
+
+	applied := 0
+	for _, d := range fullReport.Details {
+		cfg, err := verify.LoadConfig(outputDir, d.Name)
 		if err != nil {
-			fmt.Printf("⚠️ Skipping %s, failed to load config: %v\n", b.Name(), err)
 			continue
 		}
+		for _, res := range d.Results {
+			if len(res.Suggestions) == 0 || res.Suggestions[0].Confidence < minConfidence {
+				continue
+			}
+			field, ok := cfg.FindSelector(res.Label)
+			if !ok {
+				continue
+			}
+			best := res.Suggestions[0]
+			overrides.Set(d.Name, field.CSSPath(), best.Selector)
+			fmt.Printf("💡 %s %s → %s (confidence %.2f)\n", d.Name, res.Label, best.Selector, best.Confidence)
+			applied++
+		}
+	}
 
-		r := fetch.VerifyBookieWithConfig(cfg.Name, cfg.BaseURL, cfg)
-		details = append(details, r)
-		summary = append(summary, r)
+	if applied == 0 {
+		fmt.Println("💡 No suggestions above the confidence threshold")
+		return
+	}
+	if err := config.SaveOverrides(overridesPath, overrides); err != nil {
+		fmt.Printf("❌ Failed to write overrides: %v\n", err)
+		return
 	}
+	fmt.Printf("✅ Wrote %d suggestions to %s – run --mode=generate --bake-overrides to persist them\n", applied, overridesPath)
+}
 
-	fullReport := report.FullReport{
-		Summary: summary,
-		Details: details,
+// gateOnScore exits with status 2 when any bookie scores below minScore
+func gateOnScore(fullReport report.FullReport, minScore float64) {
+	if minScore <= 0 {
+		return
 	}
 
-	jsonPath := filepath.Join(outputDir, "report.json")
-	mdPath := filepath.Join(outputDir, "report.md")
+	failed := false
+	for _, b := range fullReport.Summary {
+		for _, s := range b.WithVariants() {
+			if s.Score < minScore {
+				fmt.Printf("🚦 %s health score %.1f is below %.1f\n", s.Key(), s.Score, minScore)
+				failed = true
+			}
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
+	fmt.Printf("🚦 All bookies scored at least %.1f\n", minScore)
+}
 
-	if err := report.SaveJSON(fullReport, jsonPath); err != nil {
-		fmt.Printf("❌ Failed to save JSON report: %v\n", err)
+// discoverSelectors crawls one bookie and fills its selectors with the
+// candidates found heuristically, leaving unconvincing fields empty. Written
+// to the config, only empty and placeholder selectors are replaced unless
+// force is set.
+func discoverSelectors(name string, bookies []utils.Bookie, outputDir, target string, maxPages int, minConfidence float64, force bool, client *http.Client) {
+	if name == "" {
+		fmt.Println("❌ Usage: --mode=discover <bookie>")
 		os.Exit(1)
 	}
-	if err := report.SaveMarkdown(fullReport, mdPath); err != nil {
-		fmt.Printf("❌ Failed to save Markdown report: %v\n", err)
+
+	var bookie utils.Bookie
+	for _, b := range bookies {
+		if strings.EqualFold(b.Name(), name) {
+			bookie = b
+		}
+	}
+	if bookie == nil {
+		fmt.Printf("❌ Bookie %s is not enabled in the bookies file\n", name)
 		os.Exit(1)
 	}
 
-	return fullReport
-}
+	folder := filepath.Join(outputDir, strings.ToLower(bookie.Name()))
+	cfgPath := filepath.Join(folder, "config.yaml")
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		if target == "config" {
+			fmt.Printf("❌ Failed to load config for %s (run --mode=generate first): %v\n", bookie.Name(), err)
+			os.Exit(1)
+		}
+		cfg = &config.Sportsbook{Name: bookie.Name(), BaseURL: bookie.URL()}
+	}
 
-// createLatestSnippet generates latest_report.md from full report
-func createLatestSnippet(fullReport report.FullReport, outputDir string) {
-	latestMD := filepath.Join(outputDir, "latest_report.md")
+	fmt.Printf("🧭 Discovering selectors for %s at %s...\n", bookie.Name(), cfg.BaseURL)
+	pages, err := discover.Crawl(fetch.Options{Client: client, Output: os.Stdout}, cfg.BaseURL, maxPages)
+	if err != nil {
+		fmt.Printf("❌ Failed to crawl %s: %v\n", bookie.Name(), err)
+		os.Exit(1)
+	}
+	findings := discover.Discover(pages, cfg.SelectorFields(), minConfidence)
 
-	f, err := os.Create(latestMD)
+	var doc *yaml.Node
+	var kept []string
+	outPath := filepath.Join(folder, "overrides.discovered.yaml")
+	switch target {
+	case "overrides":
+		doc = discover.OverridesNode(bookie.Name(), findings)
+	case "config":
+		doc = &yaml.Node{}
+		if err := doc.Encode(cfg); err != nil {
+			fmt.Printf("❌ Failed to encode config: %v\n", err)
+			os.Exit(1)
+		}
+		kept = discover.AnnotateConfig(doc, findings, force)
+		outPath = cfgPath
+	default:
+		fmt.Printf("❌ Unknown discover target: %s\n", target)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		fmt.Printf("❌ Failed to create folder %s: %v\n", folder, err)
+		os.Exit(1)
+	}
+	f, err := os.Create(outPath)
 	if err != nil {
-		fmt.Printf("❌ Failed to create latest_report.md: %v\n", err)
-		return
+		fmt.Printf("❌ Failed to create %s: %v\n", outPath, err)
+		os.Exit(1)
 	}
 	defer f.Close()
+	encoder := yaml.NewEncoder(f)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		fmt.Printf("❌ Failed to write %s: %v\n", outPath, err)
+		os.Exit(1)
+	}
 
-	fmt.Fprintf(f, "## 📊 Summary\n")
-	fmt.Fprintf(f, "| Bookie | URL | Status |\n")
-	fmt.Fprintf(f, "|--------|-----|--------|\n")
-	for _, s := range fullReport.Summary {
-		status := "✅"
-		if !s.AllPass {
-			status = "❌"
+	found := 0
+	for _, fd := range findings {
+		if fd.Selector != "" {
+			found++
 		}
-		fmt.Fprintf(f, "| %s | %s | %s |\n", s.Name, s.URL, status)
 	}
+	fmt.Printf("✅ Discovered %d of %d selectors across %d pages, written to %s\n", found, len(findings), len(pages), outPath)
+	if len(kept) > 0 {
+		fmt.Printf("⏭️ Kept %d selectors that were already set (use --discover-force to replace them)\n", len(kept))
+	}
+}
+
+// lintConfigs scores every selector for robustness and prints the findings.
+// It returns false if any error-level finding was reported.
+func lintConfigs(bookies []utils.Bookie, outputDir string, levels map[string]string, fetchPages bool, client *http.Client) bool {
+	fmt.Println("🧹 Linting selectors...")
+
+	ok := true
+	for _, b := range bookies {
+		cfg, err := verify.LoadConfig(outputDir, b.Name())
+		if err != nil {
+			fmt.Printf("⚠️ Skipping %s, failed to load config: %v\n", b.Name(), err)
+			continue
+		}
 
-	fmt.Fprintf(f, "\n_Updated automatically via GitHub Actions_\n")
-	fmt.Printf("✅ Created latest report snippet: %s\n", latestMD)
+		var doc *goquery.Document
+		if fetchPages {
+			page, err := fetch.Options{Client: client}.Get(cfg.BaseURL)
+			if err != nil {
+				fmt.Printf("⚠️ Linting %s without page: %v\n", cfg.Name, err)
+			} else {
+				doc = page.Doc
+			}
+		}
+
+		if !printLint(cfg.Name, lint.Config(cfg, levels, doc)) {
+			ok = false
+		}
+	}
+	return ok
 }
 
-// loadConfig reads a YAML config for a single bookie
-func loadConfig(path string) (*config.Sportsbook, error) {
-	data, err := os.ReadFile(path)
+// printLint prints one bookie's lint results, worst first, and reports
+// whether it is free of error-level findings
+func printLint(name string, results []lint.Result) bool {
+	errors, warnings, total := 0, 0, 0
+	for _, r := range results {
+		total += r.Score
+		for _, f := range r.Findings {
+			if f.Level == lint.LevelError {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+	}
+
+	avg := 100
+	if len(results) > 0 {
+		avg = total / len(results)
+	}
+	fmt.Printf("\n## %s: %d selectors, average robustness %d, %d errors, %d warnings\n", name, len(results), avg, errors, warnings)
+	for _, r := range lint.Worst(results) {
+		for _, f := range r.Findings {
+			icon := "⚠️"
+			if f.Level == lint.LevelError {
+				icon = "❌"
+			}
+			fmt.Printf("%s %s `%s` (score %d) %s: %s\n", icon, r.Label, r.Selector, r.Score, f.Rule, f.Message)
+		}
+	}
+	return errors == 0
+}
+
+// validateConfigs checks that every enabled bookie has a loadable config
+// with valid severities, capability sections and selectors, then lints it.
+// It returns false if anything is invalid.
+func validateConfigs(bookies []utils.Bookie, outputDir string, settings config.Settings) bool {
+	fmt.Println("🔎 Validating configs...")
+
+	levels := lint.Levels(settings.Lint)
+	levels["invalid"] = lint.LevelError // a selector that does not compile is always an error
+
+	ok := true
+	for _, b := range bookies {
+		cfgPath := verify.ConfigPath(outputDir, b.Name())
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", cfgPath, err)
+			ok = false
+			continue
+		}
+
+		sections := map[string]bool{}
+		for _, field := range cfg.SelectorFields() {
+			sections[field.Section()] = true
+			if sev := field.Selector.Severity; sev != "" && !sev.Valid() {
+				fmt.Printf("❌ %s %s: unknown severity %q\n", cfg.Name, field.Label, sev)
+				ok = false
+			}
+		}
+		for section := range cfg.Capabilities {
+			if !sections[section] {
+				fmt.Printf("❌ %s: unknown capability section %q\n", cfg.Name, section)
+				ok = false
+			}
+		}
+
+		for _, device := range cfg.DeviceNames() {
+			if _, err := cfg.Variant(device); err != nil {
+				fmt.Printf("❌ %s device %s: %v\n", cfg.Name, device, err)
+				ok = false
+			}
+			if vp := cfg.Device(device).Viewport; vp != "" && !strings.Contains(vp, "x") {
+				fmt.Printf("❌ %s device %s: viewport %q must look like 390x844\n", cfg.Name, device, vp)
+				ok = false
+			}
+		}
+
+		probes := map[string]bool{}
+		for _, probe := range cfg.APIProbes {
+			if probe.Name == "" || probe.URL == "" || probes[probe.Name] {
+				fmt.Printf("❌ %s: API probes need a unique name and a url (%q)\n", cfg.Name, probe.Name)
+				ok = false
+			}
+			probes[probe.Name] = true
+			if sev := probe.Severity; sev != "" && !sev.Valid() {
+				fmt.Printf("❌ %s %s: unknown severity %q\n", cfg.Name, probe.Label(), sev)
+				ok = false
+			}
+			for _, a := range probe.Assert {
+				if err := api.Validate(a.Path); err != nil {
+					fmt.Printf("❌ %s %s: %v\n", cfg.Name, probe.Label(), err)
+					ok = false
+				}
+			}
+		}
+
+		if !printLint(cfg.Name, lint.Config(cfg, levels, nil)) {
+			ok = false
+		}
+	}
+
+	if err := daemon.Validate(settings.Daemon); err != nil {
+		fmt.Printf("❌ daemon: %v\n", err)
+		ok = false
+	}
+	if err := metrics.Validate(settings.Metrics); err != nil {
+		fmt.Printf("❌ metrics: %v\n", err)
+		ok = false
+	}
+	for _, sink := range settings.Notify.Sinks {
+		if err := notify.Validate(sink); err != nil {
+			fmt.Printf("❌ notify sink %q: %v\n", sink.Name, err)
+			ok = false
+		}
+	}
+
+	if ok {
+		fmt.Println("✅ All configs are valid")
+	}
+	return ok
+}
+
+// promoteFallbacks reorders selector fallback chains so the alternative that
+// matched most often in recent reports becomes the primary selector
+func promoteFallbacks(bookies []utils.Bookie, outputDir string, settings config.HistorySettings) {
+	reports, err := promotionReports(outputDir, settings.PromoteRuns)
 	if err != nil {
-		return nil, err
+		fmt.Printf("❌ Failed to load report: %v\n", err)
+		os.Exit(1)
+	}
+
+	// wins[bookie][label][selector] counts how often each alternative matched
+	wins := map[string]map[string]map[string]int{}
+	for _, d := range reports {
+		for _, res := range d.Results {
+			if res.Matched == "" {
+				continue
+			}
+			if wins[d.Name] == nil {
+				wins[d.Name] = map[string]map[string]int{}
+			}
+			if wins[d.Name][res.Label] == nil {
+				wins[d.Name][res.Label] = map[string]int{}
+			}
+			wins[d.Name][res.Label][res.Matched]++
+		}
+	}
+
+	for _, b := range bookies {
+		cfgPath := verify.ConfigPath(outputDir, b.Name())
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			fmt.Printf("⚠️ Skipping %s, failed to load config: %v\n", b.Name(), err)
+			continue
+		}
+
+		changed := false
+		for _, field := range cfg.SelectorFields() {
+			if field.Selector.Promote(wins[cfg.Name][field.Label]) {
+				fmt.Printf("🔁 %s %s: promoted %q to primary\n", cfg.Name, field.Label, field.Selector.CSS)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		if err := config.Save(cfgPath, cfg); err != nil {
+			fmt.Printf("❌ Failed to save config for %s: %v\n", cfg.Name, err)
+			continue
+		}
+		fmt.Printf("✅ Updated fallback order for %s\n", cfg.Name)
 	}
+}
 
-	var sb config.Sportsbook
-	if err := yaml.Unmarshal(data, &sb); err != nil {
+// promotionReports returns the desktop bookie reports of the last n runs in
+// the history, or of report.json when there is no history yet
+func promotionReports(outputDir string, n int) ([]report.BookieReport, error) {
+	runs, err := history.Load(history.Path(outputDir))
+	if err != nil {
 		return nil, err
 	}
-	return &sb, nil
+	if len(runs) == 0 {
+		fullReport, err := report.LoadJSON(filepath.Join(outputDir, "report.json"))
+		if err != nil {
+			return nil, err
+		}
+		return fullReport.Details, nil
+	}
+
+	if n > 0 && len(runs) > n {
+		runs = runs[len(runs)-n:]
+	}
+	fmt.Printf("📈 Counting matches over the last %d run(s)\n", len(runs))
+	var reports []report.BookieReport
+	for _, run := range runs {
+		reports = append(reports, run.Report.Details...)
+	}
+	return reports, nil
 }
 
-// bakeOverridesFile merges overrides into the base config and renames the original overrides.yaml
-func bakeOverridesFile(outputDir, overridesPath string) {
-    // Load the overrides
-    overrideMap, err := config.LoadOverrides(overridesPath)
-    if err != nil {
-        fmt.Printf("⚠️ Failed to load overrides file: %v\n", err)
-        return
-    }
+// showHistory prints trends from the run history: every bookie's uptime,
+// one bookie's selectors, or one selector's status per run
+func showHistory(outputDir, bookie, label string, days int) {
+	runs, err := history.Load(history.Path(outputDir))
+	if err != nil {
+		fmt.Printf("❌ Failed to load history: %v\n", err)
+		os.Exit(1)
+	}
+	if days > 0 {
+		runs = history.Since(runs, time.Now().AddDate(0, 0, -days))
+	}
+	if len(runs) == 0 {
+		fmt.Println("📭 No runs in history yet")
+		return
+	}
+	fmt.Printf("📈 %d run(s) from %s to %s\n\n", len(runs), runs[0].StartedAt.Format(time.RFC3339), runs[len(runs)-1].StartedAt.Format(time.RFC3339))
 
-    // Iterate over all bookies in overrideMap
-    for bookieName, overridesForBookie := range overrideMap {
-        bookieDir := filepath.Join(outputDir, strings.ToLower(bookieName))
-        configPath := filepath.Join(bookieDir, "config.yaml")
+	if bookie == "" {
+		fmt.Println("| Bookie | Runs | Uptime | Trend | First failure | Last failure |")
+		fmt.Println("|---|---|---|---|---|---|")
+		for _, key := range history.Keys(runs) {
+			points := history.BookieTimeline(runs, key)
+			st := history.Summarize(points)
+			fmt.Printf("| %s | %d | %.1f%% | %s | %s | %s |\n", key, st.Checked, st.Uptime, trend(points), pointTime(st.FirstFailure), pointTime(st.LastFailure))
+		}
+		return
+	}
 
-        // Read existing config
-        baseConfigData, err := os.ReadFile(configPath)
-        if err != nil {
-            fmt.Printf("⚠️ Failed to read config for %s: %v\n", bookieName, err)
-            continue
-        }
+	key := historyKey(runs, bookie)
+	if key == "" {
+		fmt.Printf("❌ %s is not in the history\n", bookie)
+		os.Exit(1)
+	}
 
-        // Unmarshal YAML into Sportsbook struct
-        var baseConfig config.Sportsbook
-        if err := yaml.Unmarshal(baseConfigData, &baseConfig); err != nil {
-            fmt.Printf("⚠️ Failed to unmarshal config for %s: %v\n", bookieName, err)
-            continue
-        }
+	if label != "" {
+		points := history.SelectorTimeline(runs, key, label)
+		if len(points) == 0 {
+			fmt.Printf("❌ %s has no selector %s in the history\n", key, label)
+			os.Exit(1)
+		}
+		printTimeline(key+" "+label, points, false)
+		return
+	}
 
-        // Apply overrides
-        baseConfig.ApplyOverrides(overridesForBookie)
+	printTimeline(key, history.BookieTimeline(runs, key), true)
+	fmt.Println()
+	fmt.Println("| Selector | Runs | Uptime | Trend | First failure | Last failure |")
+	fmt.Println("|---|---|---|---|---|---|")
 
-        // Marshal updated config back to YAML
-        updatedConfigData, err := yaml.Marshal(baseConfig)
-        if err != nil {
-            fmt.Printf("⚠️ Failed to marshal updated config for %s: %v\n", bookieName, err)
-            continue
-        }
+	// Least reliable selectors first
+	type row struct {
+		label  string
+		points []history.Point
+		stats  history.Stats
+	}
+	var rows []row
+	for _, l := range history.Labels(runs, key) {
+		points := history.SelectorTimeline(runs, key, l)
+		rows = append(rows, row{l, points, history.Summarize(points)})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].stats.Uptime < rows[j].stats.Uptime })
+	for _, r := range rows {
+		fmt.Printf("| %s | %d | %.1f%% | %s | %s | %s |\n", r.label, r.stats.Checked, r.stats.Uptime, trend(r.points), pointTime(r.stats.FirstFailure), pointTime(r.stats.LastFailure))
+	}
+}
 
-        // Save updated config
-        if err := os.WriteFile(configPath, updatedConfigData, 0644); err != nil {
-            fmt.Printf("⚠️ Failed to write updated config for %s: %v\n", bookieName, err)
-            continue
-        }
+// historyKey finds a bookie key in the history, ignoring case
+func historyKey(runs []history.Run, bookie string) string {
+	for _, key := range history.Keys(runs) {
+		if strings.EqualFold(key, bookie) {
+			return key
+		}
+	}
+	return ""
+}
 
-        fmt.Printf("✅ Baked overrides into config for %s\n", bookieName)
-    }
+// printTimeline prints the uptime summary and every run of one timeline,
+// with the health score for bookie timelines
+func printTimeline(name string, points []history.Point, scored bool) {
+	st := history.Summarize(points)
+	fmt.Printf("🔎 %s: uptime %.1f%% over %d run(s)\n", name, st.Uptime, st.Checked)
+	fmt.Printf("   First failure: %s\n", pointTime(st.FirstFailure))
+	fmt.Printf("   Last failure:  %s\n\n", pointTime(st.LastFailure))
+	for _, p := range points {
+		line := fmt.Sprintf("   %s  %s  %s", p.RunID, p.At.Format(time.RFC3339), p.Status)
+		if scored {
+			line += fmt.Sprintf("  score %.1f", p.Score)
+		}
+		if p.Message != "" {
+			line += "  " + p.Message
+		}
+		fmt.Println(line)
+	}
+}
 
-    // Rename overrides.yaml → overrides.baked.yaml
-    bakedPath := filepath.Join(outputDir, "overrides.baked.yaml")
-    if err := os.Rename(overridesPath, bakedPath); err != nil {
-        fmt.Printf("⚠️ Failed to rename overrides.yaml: %v\n", err)
-    } else {
-        fmt.Printf("🍞 All overrides baked. Original overrides.yaml renamed to %s\n", bakedPath)
-    }
+// trend renders the last statuses of a timeline, oldest first
+func trend(points []history.Point) string {
+	const width = 20
+	if len(points) > width {
+		points = points[len(points)-width:]
+	}
+	var b strings.Builder
+	for _, p := range points {
+		switch {
+		case p.Status == "➖" || p.Status == "💥":
+			b.WriteString(p.Status)
+		case p.Healthy():
+			b.WriteString("✅")
+		default:
+			b.WriteString("❌")
+		}
+	}
+	return b.String()
 }
 
+// pointTime renders when a point happened, or a dash for none
+func pointTime(p *history.Point) string {
+	if p == nil {
+		return "–"
+	}
+	return p.At.Format("2006-01-02 15:04")
+}
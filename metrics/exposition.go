@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// family is one metric with its HELP and TYPE lines and all its samples,
+// which the text format requires to be written together
+type family struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+type sample struct {
+	labels []label
+	value  float64
+}
+
+type label struct {
+	name, value string
+}
+
+// families collects samples in the order their metrics are first added
+type families struct {
+	order []*family
+	index map[string]*family
+}
+
+func newFamilies() *families {
+	return &families{index: map[string]*family{}}
+}
+
+// add records a gauge sample; labels are given as name, value pairs
+func (fs *families) add(name, help string, value float64, labels ...string) {
+	f, ok := fs.index[name]
+	if !ok {
+		f = &family{name: name, help: help}
+		fs.index[name] = f
+		fs.order = append(fs.order, f)
+	}
+	s := sample{value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i+1] != "" {
+			s.labels = append(s.labels, label{labels[i], labels[i+1]})
+		}
+	}
+	f.samples = append(f.samples, s)
+}
+
+// write renders the families in the Prometheus text exposition format
+func (fs *families) write(w io.Writer) error {
+	var b strings.Builder
+	for _, f := range fs.order {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", f.name)
+		for _, s := range f.samples {
+			b.WriteString(f.name)
+			if len(s.labels) > 0 {
+				b.WriteByte('{')
+				for i, l := range s.labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", l.name, escape(l.value))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.value))
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"diago/config"
+	"diago/report"
+)
+
+// ContentType is the Prometheus text exposition format served on /metrics
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// checkStates name result statuses in the checks metrics
+var checkStates = map[string]string{"✅": "passed", "⚠️": "fallback", "❌": "failed", "➖": "na"}
+
+// Exporter turns the latest report into Prometheus metrics. It remembers
+// when each bookie and selector last succeeded across the runs it observed.
+type Exporter struct {
+	settings config.MetricsSettings
+
+	mu          sync.RWMutex
+	observed    bool
+	latest      []report.BookieReport
+	latestAt    time.Time
+	lastSuccess map[string]time.Time
+}
+
+// NewExporter creates an exporter with no runs observed
+func NewExporter(settings config.MetricsSettings) *Exporter {
+	return &Exporter{settings: settings, lastSuccess: map[string]time.Time{}}
+}
+
+// SetSettings replaces the settings, e.g. after they were reloaded; the
+// next scrape uses them
+func (e *Exporter) SetSettings(settings config.MetricsSettings) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.settings = settings
+}
+
+// Observe records a run, which becomes the one the metrics describe; the
+// bookies of a partial scheduled cycle replace only their own series.
+// Feed older runs first, e.g. from the history, to seed the last success
+// timestamps.
+func (e *Exporter) Observe(r report.FullReport) {
+	at, err := time.Parse(time.RFC3339, r.StartedAt)
+	if err != nil {
+		at = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r.Partial {
+		e.latest = merge(e.latest, r.Details)
+	} else {
+		e.latest = r.Details
+	}
+	e.observed, e.latestAt = true, at
+	for _, b := range e.bookies(r.Details) {
+		if reachable(b) && b.AllPass {
+			e.lastSuccess[b.Key()] = at
+		}
+		for _, res := range b.Results {
+			if res.Healthy() {
+				e.lastSuccess[b.Key()+"|"+res.Label] = at
+			}
+		}
+	}
+}
+
+// bookies are the reports the metrics cover: every bookie, with its device
+// variants unless they are turned off
+func (e *Exporter) bookies(details []report.BookieReport) []report.BookieReport {
+	var out []report.BookieReport
+	for _, b := range details {
+		if e.settings.Variants {
+			out = append(out, b.WithVariants()...)
+		} else {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// Write renders the metrics of the latest run; nothing is written before
+// the first run is observed
+func (e *Exporter) Write(w io.Writer) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if !e.observed {
+		return nil
+	}
+
+	fs := newFamilies()
+	fs.add("diago_last_run_timestamp_seconds", "When the latest run started.", unix(e.latestAt))
+
+	detail := e.settings.Detail
+	for _, b := range e.bookies(e.latest) {
+		bl := []string{"bookie", b.Name, "device", b.Device}
+
+		up := 0.0
+		if reachable(b) {
+			up = 1
+		}
+		fs.add("diago_bookie_up", "Whether the bookie's page could be fetched.", up, bl...)
+		fs.add("diago_bookie_http_status", "HTTP status the bookie's page answered with; 0 when it did not answer.", float64(b.HTTPStatus), bl...)
+		fs.add("diago_bookie_duration_seconds", "Time spent fetching and verifying the bookie.", b.DurationMS/1000, bl...)
+		fs.add("diago_bookie_health_score", "Severity-weighted health score of the bookie (0-100).", b.Score, bl...)
+
+		counts := map[string]int{}
+		for _, res := range b.Results {
+			counts[checkState(res.Status)]++
+		}
+		for _, state := range []string{"passed", "fallback", "failed", "na", "error"} {
+			fs.add("diago_bookie_checks", "Selector and API checks of the bookie by result.", float64(counts[state]), append(bl, "result", state)...)
+		}
+		if at, ok := e.lastSuccess[b.Key()]; ok {
+			fs.add("diago_bookie_last_success_timestamp_seconds", "When the bookie last passed all its checks.", unix(at), bl...)
+		}
+
+		if detail != config.MetricsSection && detail != config.MetricsSelector {
+			continue
+		}
+		for _, s := range b.Sections {
+			sl := append(bl, "section", s.Name)
+			fs.add("diago_section_health_score", "Severity-weighted health score of a section (0-100).", s.Score, sl...)
+			fs.add("diago_section_checks", "Checks of a section by result.", float64(s.Passed), append(sl, "result", "passed")...)
+			fs.add("diago_section_checks", "Checks of a section by result.", float64(s.Failed), append(sl, "result", "failed")...)
+		}
+
+		if detail != config.MetricsSelector {
+			continue
+		}
+		for _, res := range b.Results {
+			if res.Label == report.FetchErrorLabel || res.Status == "➖" {
+				continue
+			}
+			rl := append(bl, "selector", res.Label, "severity", res.Severity)
+			ok := 0.0
+			if res.Healthy() {
+				ok = 1
+			}
+			fs.add("diago_selector_up", "Whether the selector matched, by its primary selector or a fallback.", ok, rl...)
+			fs.add("diago_selector_duration_seconds", "Time spent checking the selector.", res.DurationMS/1000, rl...)
+			if at, ok := e.lastSuccess[b.Key()+"|"+res.Label]; ok {
+				fs.add("diago_selector_last_success_timestamp_seconds", "When the selector last matched.", unix(at), rl...)
+			}
+		}
+	}
+	return fs.write(w)
+}
+
+// ServeHTTP serves the metrics on a scrape
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+	if err := e.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(buf.Bytes())
+}
+
+// WriteTextfile writes the metrics for node-exporter's textfile collector.
+// The file is written next to its destination and renamed, so the collector
+// never reads half of it.
+func (e *Exporter) WriteTextfile(path string) error {
+	var buf bytes.Buffer
+	if err := e.Write(&buf); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Validate checks the detail level
+func Validate(settings config.MetricsSettings) error {
+	switch settings.Detail {
+	case config.MetricsBookie, config.MetricsSection, config.MetricsSelector:
+	default:
+		return fmt.Errorf("unknown detail %q, use %s", settings.Detail, strings.Join([]string{config.MetricsBookie, config.MetricsSection, config.MetricsSelector}, ", "))
+	}
+	if settings.Textfile != "" && !strings.HasSuffix(settings.Textfile, ".prom") {
+		return fmt.Errorf("textfile %q must end in .prom for the textfile collector", settings.Textfile)
+	}
+	return nil
+}
+
+// merge replaces the reports of the given bookies and appends new ones
+func merge(latest, details []report.BookieReport) []report.BookieReport {
+	out := append([]report.BookieReport{}, latest...)
+	index := map[string]int{}
+	for i, b := range out {
+		index[b.Name] = i
+	}
+	for _, b := range details {
+		if i, ok := index[b.Name]; ok {
+			out[i] = b
+		} else {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// reachable reports whether the bookie's page was fetched
+func reachable(b report.BookieReport) bool {
+	for _, r := range b.Results {
+		if r.Label == report.FetchErrorLabel {
+			return false
+		}
+	}
+	return true
+}
+
+func checkState(status string) string {
+	if s, ok := checkStates[status]; ok {
+		return s
+	}
+	return "error"
+}
+
+func unix(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"diago/config"
+	"diago/report"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// runs are two runs of betway, with a mobile variant, and an unreachable
+// bookie; the first one seeds the last success timestamps
+func runs() []report.FullReport {
+	betway := func(otp string) report.BookieReport {
+		b := report.BookieReport{
+			Name:       "betway",
+			Device:     "desktop",
+			HTTPStatus: 200,
+			DurationMS: 1250,
+			AllPass:    otp == "✅",
+			Results: []report.SelectorResult{
+				{Label: "Login.UsernameInput", Status: "✅", Severity: "critical", DurationMS: 2.5},
+				{Label: "Login.LoginButton", Status: "⚠️", Severity: "critical", Alternative: 2, DurationMS: 4},
+				{Label: "Login.OtpInput", Status: otp, Severity: "major", DurationMS: 10},
+				{Label: "LiveBetting.LiveScore", Status: "➖", Severity: "n/a"},
+			},
+		}
+		b.ApplyScores()
+		mobile := report.BookieReport{
+			Name:       "betway",
+			Device:     "mobile",
+			HTTPStatus: 200,
+			DurationMS: 800,
+			AllPass:    true,
+			Results:    []report.SelectorResult{{Label: "Login.UsernameInput", Status: "✅", Severity: "critical", DurationMS: 1}},
+		}
+		mobile.ApplyScores()
+		b.Variants = []report.BookieReport{mobile}
+		return b
+	}
+	down := report.BookieReport{
+		Name:    "sportpesa",
+		Results: []report.SelectorResult{{Label: report.FetchErrorLabel, Status: "dial tcp: i/o timeout"}},
+	}
+	down.ApplyScores()
+
+	return []report.FullReport{
+		{StartedAt: "2026-10-18T12:00:00Z", Details: []report.BookieReport{betway("✅"), down}},
+		{StartedAt: "2026-10-19T12:00:00Z", Details: []report.BookieReport{betway("❌"), down}},
+	}
+}
+
+func TestWriteGolden(t *testing.T) {
+	tests := []struct {
+		golden   string
+		settings config.MetricsSettings
+	}{
+		{"bookie.prom", config.MetricsSettings{Detail: config.MetricsBookie}},
+		{"section.prom", config.MetricsSettings{Detail: config.MetricsSection, Variants: true}},
+		{"selector.prom", config.MetricsSettings{Detail: config.MetricsSelector, Variants: true}},
+	}
+	for _, tt := range tests {
+		e := NewExporter(tt.settings)
+		for _, r := range runs() {
+			e.Observe(r)
+		}
+		var buf bytes.Buffer
+		if err := e.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join("testdata", tt.golden)
+		if *update {
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != string(want) {
+			t.Errorf("%s: metrics differ from the golden file:\n%s", tt.golden, buf.String())
+		}
+	}
+}
+
+// Nothing is exposed before the first run
+func TestWriteBeforeObserve(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewExporter(config.DefaultSettings().Metrics).Write(&buf); err != nil || buf.Len() != 0 {
+		t.Errorf("Write = %q, %v; want nothing", buf.String(), err)
+	}
+}
+
+func TestExposition(t *testing.T) {
+	fs := newFamilies()
+	fs.add("diago_test", "A test metric.", 1.5, "bookie", `bet"way`, "device", "")
+	fs.add("diago_other", "Another metric.", 0)
+	fs.add("diago_test", "A test metric.", 2, "bookie", "C:\\bets\nline")
+
+	var buf bytes.Buffer
+	if err := fs.write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// Samples stay grouped under their family, empty labels are dropped and
+	// label values escape backslashes, quotes and newlines
+	want := `# HELP diago_test A test metric.
+# TYPE diago_test gauge
+diago_test{bookie="bet\"way"} 1.5
+diago_test{bookie="C:\\bets\nline"} 2
+# HELP diago_other Another metric.
+# TYPE diago_other gauge
+diago_other 0
+`
+	if buf.String() != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	e := NewExporter(config.MetricsSettings{Detail: config.MetricsBookie})
+	e.Observe(runs()[0])
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), `diago_bookie_up{bookie="sportpesa"} 0`) {
+		t.Errorf("body =\n%s", rec.Body.String())
+	}
+}
+
+// The textfile is replaced whole and no temporary file is left behind
+func TestWriteTextfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "node", "diago.prom")
+	e := NewExporter(config.MetricsSettings{Detail: config.MetricsBookie})
+
+	for _, r := range runs() {
+		e.Observe(r)
+		if err := e.WriteTextfile(path); err != nil {
+			t.Fatal(err)
+		}
+		var want bytes.Buffer
+		e.Write(&want)
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want.String() {
+			t.Errorf("textfile =\n%s\nwant\n%s", got, want.String())
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "diago.prom" {
+		t.Errorf("textfile directory holds %v", entries)
+	}
+
+	// A failed write leaves the previous file in place
+	if err := os.Mkdir(path+".tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+	if err := e.WriteTextfile(path); err == nil {
+		t.Error("WriteTextfile succeeded over a directory")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("a failed write changed the textfile")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		settings config.MetricsSettings
+		ok       bool
+	}{
+		{config.DefaultSettings().Metrics, true},
+		{config.MetricsSettings{Detail: config.MetricsSelector, Textfile: "/var/lib/node/diago.prom"}, true},
+		{config.MetricsSettings{Detail: "label"}, false},
+		{config.MetricsSettings{Detail: config.MetricsBookie, Textfile: "diago.txt"}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.settings); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v, want ok %v", tt.settings, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"diago/config"
+	"diago/report"
+)
+
+// Event kinds
+const (
+	KindRegression  = "regression"
+	KindUnreachable = "unreachable"
+	KindFixed       = "fixed"
+	KindRecovered   = "recovered"
+)
+
+// defaultEvents are sent when a sink's filter names none
+var defaultEvents = []string{KindRegression, KindUnreachable}
+
+// severityRank orders severities for min_severity filters
+var severityRank = map[string]int{"minor": 1, "major": 2, "critical": 3}
+
+// Event is one change worth telling someone about
+type Event struct {
+	Kind     string `json:"kind"`
+	Bookie   string `json:"bookie"`
+	Label    string `json:"label,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Key identifies an event across runs for dedupe and throttling
+func (e Event) Key() string {
+	return strings.Join([]string{e.Kind, e.Bookie, e.Label}, "|")
+}
+
+// Message is what a sink is sent at the end of a run
+type Message struct {
+	RunID     string  `json:"run_id,omitempty"`
+	StartedAt string  `json:"started_at,omitempty"`
+	Events    []Event `json:"events"`
+}
+
+// FromReport collects the events of a run: regressions and unreachable
+// bookies once they failed alertAfter runs in a row, and the fixes and
+// recoveries since the previous run
+func FromReport(r report.FullReport, alertAfter int) Message {
+	m := Message{RunID: r.RunID, StartedAt: r.StartedAt}
+	regressions, unreachable := report.Alerts(r, alertAfter)
+	for _, c := range regressions {
+		m.Events = append(m.Events, Event{Kind: KindRegression, Bookie: c.Bookie, Label: c.Label, Severity: c.Severity, Message: c.Message})
+	}
+	for _, c := range unreachable {
+		m.Events = append(m.Events, Event{Kind: KindUnreachable, Bookie: c.Bookie, Message: c.Message})
+	}
+	if r.Diff != nil {
+		for _, c := range r.Diff.Fixes {
+			m.Events = append(m.Events, Event{Kind: KindFixed, Bookie: c.Bookie, Label: c.Label, Severity: c.Severity, Message: c.Message})
+		}
+		for _, c := range r.Diff.Recovered {
+			m.Events = append(m.Events, Event{Kind: KindRecovered, Bookie: c.Bookie, Message: c.Message})
+		}
+	}
+	return m
+}
+
+// Title summarises a message in one line, e.g. "🚨 diago: 2 regression(s), 1 unreachable"
+func (m Message) Title() string {
+	counts := map[string]int{}
+	for _, e := range m.Events {
+		counts[e.Kind]++
+	}
+	var parts []string
+	for _, k := range []struct{ kind, name string }{
+		{KindRegression, "regression(s)"}, {KindUnreachable, "unreachable"},
+		{KindFixed, "fixed"}, {KindRecovered, "recovered"},
+	} {
+		if counts[k.kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[k.kind], k.name))
+		}
+	}
+	icon := "✅"
+	if counts[KindRegression]+counts[KindUnreachable] > 0 {
+		icon = "🚨"
+	}
+	return fmt.Sprintf("%s diago: %s", icon, strings.Join(parts, ", "))
+}
+
+// Lines renders one line per event
+func (m Message) Lines() []string {
+	icons := map[string]string{KindRegression: "🔴", KindUnreachable: "📵", KindFixed: "🟢", KindRecovered: "📶"}
+	var lines []string
+	for _, e := range m.Events {
+		line := icons[e.Kind] + " " + e.Bookie
+		if e.Label != "" {
+			line += " " + e.Label
+		}
+		if e.Severity != "" {
+			line += " (" + e.Severity + ")"
+		}
+		if e.Message != "" {
+			line += ": " + e.Message
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// State remembers when each sink last sent each event
+type State struct {
+	Sent map[string]time.Time `json:"sent"`
+}
+
+// StatePath resolves where the state is kept; relative paths are below the output dir
+func StatePath(outputDir string, settings config.NotifySettings) string {
+	switch {
+	case settings.StateFile == "":
+		return filepath.Join(outputDir, "notify", "state.json")
+	case filepath.IsAbs(settings.StateFile):
+		return settings.StateFile
+	default:
+		return filepath.Join(outputDir, settings.StateFile)
+	}
+}
+
+// LoadState reads the state; a missing file is an empty state
+func LoadState(path string) (State, error) {
+	s := State{Sent: map[string]time.Time{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if s.Sent == nil {
+		s.Sent = map[string]time.Time{}
+	}
+	return s, nil
+}
+
+// SaveState writes the state
+func SaveState(path string, s State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Send delivers the message to every sink, after each sink's filter and
+// throttle, and records what was sent in the state file. A failing sink
+// does not stop the others; their errors are joined.
+func Send(settings config.NotifySettings, statePath string, m Message, now time.Time) error {
+	if len(settings.Sinks) == 0 || len(m.Events) == 0 {
+		return nil
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sink := range settings.Sinks {
+		throttle, _ := time.ParseDuration(sink.Throttle)
+		out := Message{RunID: m.RunID, StartedAt: m.StartedAt}
+		for _, e := range m.Events {
+			key := sink.Name + "|" + e.Key()
+			if !Matches(sink.Filter, e) {
+				continue
+			}
+			if last, ok := state.Sent[key]; ok && throttle > 0 && now.Sub(last) < throttle {
+				continue
+			}
+			out.Events = append(out.Events, e)
+		}
+		if len(out.Events) == 0 {
+			continue
+		}
+
+		if err := deliver(sink, out); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name, err))
+			continue
+		}
+		fmt.Printf("📣 Sent %d event(s) to %s\n", len(out.Events), sink.Name)
+		for _, e := range out.Events {
+			state.Sent[sink.Name+"|"+e.Key()] = now
+			forgetResolved(state, sink.Name, e)
+		}
+	}
+
+	if err := SaveState(statePath, state); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// forgetResolved clears the throttle of a failure once it is fixed, so the
+// next breakage is sent right away
+func forgetResolved(state State, sink string, e Event) {
+	switch e.Kind {
+	case KindFixed:
+		delete(state.Sent, sink+"|"+Event{Kind: KindRegression, Bookie: e.Bookie, Label: e.Label}.Key())
+	case KindRecovered:
+		delete(state.Sent, sink+"|"+Event{Kind: KindUnreachable, Bookie: e.Bookie}.Key())
+	}
+}
+
+// Matches reports whether a sink's filter lets an event through
+func Matches(f config.NotifyFilter, e Event) bool {
+	kinds := f.Events
+	if len(kinds) == 0 {
+		kinds = defaultEvents
+	}
+	if !contains(kinds, e.Kind) {
+		return false
+	}
+	if len(f.Bookies) > 0 && !contains(f.Bookies, strings.SplitN(e.Bookie, "@", 2)[0]) && !contains(f.Bookies, e.Bookie) {
+		return false
+	}
+	if f.MinSeverity != "" && e.Severity != "" && severityRank[e.Severity] < severityRank[f.MinSeverity] {
+		return false
+	}
+	return true
+}
+
+// Validate checks a sink's type, target and filter
+func Validate(s config.Sink) error {
+	switch s.Type {
+	case config.SinkWebhook, config.SinkSlack, config.SinkTeams:
+		if s.URL == "" {
+			return fmt.Errorf("%s sink needs a url", s.Type)
+		}
+	case config.SinkEmail:
+		if s.SMTP.Host == "" || s.SMTP.From == "" || len(s.SMTP.To) == 0 {
+			return fmt.Errorf("email sink needs smtp host, from and to")
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q", s.Type)
+	}
+	if s.Name == "" {
+		return fmt.Errorf("sink needs a name")
+	}
+	if s.Throttle != "" {
+		if _, err := time.ParseDuration(s.Throttle); err != nil {
+			return fmt.Errorf("invalid throttle %q: %w", s.Throttle, err)
+		}
+	}
+	for _, k := range s.Filter.Events {
+		if !contains([]string{KindRegression, KindUnreachable, KindFixed, KindRecovered}, k) {
+			return fmt.Errorf("unknown event %q", k)
+		}
+	}
+	if s.Filter.MinSeverity != "" && severityRank[s.Filter.MinSeverity] == 0 {
+		return fmt.Errorf("unknown min_severity %q", s.Filter.MinSeverity)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
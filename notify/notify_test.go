@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"diago/config"
+)
+
+// collector is a webhook sink that records the events it is sent
+type collector struct {
+	mu     sync.Mutex
+	events [][]Event
+}
+
+func newCollector(t *testing.T) (*collector, string) {
+	t.Helper()
+	c := &collector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var m Message
+		data, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Errorf("bad body: %v", err)
+		}
+		c.mu.Lock()
+		c.events = append(c.events, m.Events)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv.URL
+}
+
+// sent returns how many events each delivery carried
+func (c *collector) sent() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n []int
+	for _, e := range c.events {
+		n = append(n, len(e))
+	}
+	return n
+}
+
+func regression() Event {
+	return Event{Kind: KindRegression, Bookie: "betway", Label: "Login.LoginButton", Severity: "critical"}
+}
+
+func TestSendThrottles(t *testing.T) {
+	c, url := newCollector(t)
+	settings := config.NotifySettings{Sinks: []config.Sink{{Name: "ops", Type: config.SinkWebhook, URL: url, Throttle: "6h"}}}
+	statePath := filepath.Join(t.TempDir(), "notify", "state.json")
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	m := Message{Events: []Event{regression()}}
+
+	steps := []struct {
+		at   time.Duration
+		want int
+	}{
+		{0, 1},
+		{time.Hour, 1},        // throttled
+		{7 * time.Hour, 2},    // throttle elapsed
+		{8 * time.Hour, 2},    // throttled again
+		{13 * time.Hour, 3},   // 6h after the last send
+		{13*time.Hour + 1, 3}, // throttled
+	}
+	for _, s := range steps {
+		if err := Send(settings, statePath, m, now.Add(s.at)); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(c.sent()); got != s.want {
+			t.Fatalf("after %v: %d deliveries, want %d", s.at, got, s.want)
+		}
+	}
+}
+
+// The state file is what carries the throttle from one run to the next
+func TestStatePersists(t *testing.T) {
+	c, url := newCollector(t)
+	settings := config.NotifySettings{Sinks: []config.Sink{{Name: "ops", Type: config.SinkWebhook, URL: url, Throttle: "1h"}}}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	if err := Send(settings, statePath, Message{Events: []Event{regression()}}, now); err != nil {
+		t.Fatal(err)
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "ops|" + regression().Key()
+	if !state.Sent[key].Equal(now) || len(state.Sent) != 1 {
+		t.Fatalf("state = %v, want %s sent at %v", state.Sent, key, now)
+	}
+
+	// A fix clears the regression's throttle, so a new breakage goes out at once
+	fixed := regression()
+	fixed.Kind = KindFixed
+	settings.Sinks[0].Filter.Events = []string{KindRegression, KindFixed}
+	if err := Send(settings, statePath, Message{Events: []Event{fixed}}, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	state, _ = LoadState(statePath)
+	if _, ok := state.Sent[key]; ok {
+		t.Errorf("state still throttles the fixed regression: %v", state.Sent)
+	}
+	if err := Send(settings, statePath, Message{Events: []Event{regression()}}, now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.sent(); len(got) != 3 {
+		t.Errorf("deliveries = %v, want the regression, the fix and the regression again", got)
+	}
+}
+
+func TestLoadStateMissingFile(t *testing.T) {
+	s, err := LoadState(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil || s.Sent == nil || len(s.Sent) != 0 {
+		t.Errorf("LoadState = %v, %v; want an empty state", s, err)
+	}
+}
+
+func TestSendKeepsGoingPastAFailingSink(t *testing.T) {
+	c, url := newCollector(t)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	settings := config.NotifySettings{Sinks: []config.Sink{
+		{Name: "down", Type: config.SinkSlack, URL: down.URL},
+		{Name: "ops", Type: config.SinkWebhook, URL: url},
+	}}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	err := Send(settings, statePath, Message{Events: []Event{regression()}}, time.Now())
+	if err == nil {
+		t.Fatal("want the failing sink's error")
+	}
+	if got := c.sent(); len(got) != 1 {
+		t.Errorf("deliveries = %v, want the second sink served", got)
+	}
+	state, _ := LoadState(statePath)
+	if _, ok := state.Sent["down|"+regression().Key()]; ok {
+		t.Errorf("a failed delivery was recorded as sent")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter config.NotifyFilter
+		event  Event
+		want   bool
+	}{
+		{"default kinds send regressions", config.NotifyFilter{}, regression(), true},
+		{"default kinds skip fixes", config.NotifyFilter{}, Event{Kind: KindFixed, Bookie: "betway"}, false},
+		{"listed kind", config.NotifyFilter{Events: []string{"fixed"}}, Event{Kind: KindFixed, Bookie: "betway"}, true},
+		{"bookie matches its devices", config.NotifyFilter{Bookies: []string{"Betway"}}, Event{Kind: KindUnreachable, Bookie: "betway@mobile"}, true},
+		{"other bookie", config.NotifyFilter{Bookies: []string{"sportpesa"}}, regression(), false},
+		{"below min severity", config.NotifyFilter{MinSeverity: "critical"}, Event{Kind: KindRegression, Bookie: "betway", Severity: "major"}, false},
+		{"at min severity", config.NotifyFilter{MinSeverity: "major"}, regression(), true},
+		{"unreachable has no severity", config.NotifyFilter{MinSeverity: "critical"}, Event{Kind: KindUnreachable, Bookie: "betway"}, true},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.filter, tt.event); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
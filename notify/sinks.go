@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"diago/config"
+)
+
+// Client posts webhook, Slack and Teams messages
+var Client = &http.Client{Timeout: 15 * time.Second}
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook body when the sink has a secret
+const SignatureHeader = "X-Diago-Signature-256"
+
+// deliver sends a message through one sink
+func deliver(s config.Sink, m Message) error {
+	switch s.Type {
+	case config.SinkWebhook:
+		return post(s, webhookBody(m), os.ExpandEnv(s.Secret))
+	case config.SinkSlack:
+		return post(s, slackBody(m), "")
+	case config.SinkTeams:
+		return post(s, teamsBody(m), "")
+	case config.SinkEmail:
+		return sendMail(s.SMTP, m)
+	default:
+		return fmt.Errorf("unknown sink type %q", s.Type)
+	}
+}
+
+// webhookBody is the message itself with a title
+func webhookBody(m Message) any {
+	return struct {
+		Title string `json:"title"`
+		Message
+	}{m.Title(), m}
+}
+
+// slackBody is an incoming-webhook message in mrkdwn
+func slackBody(m Message) any {
+	return map[string]string{"text": "*" + m.Title() + "*\n" + strings.Join(m.Lines(), "\n")}
+}
+
+// teamsBody is an Adaptive Card posted to a Teams workflow or incoming webhook
+func teamsBody(m Message) any {
+	body := []map[string]any{{"type": "TextBlock", "text": m.Title(), "weight": "Bolder", "size": "Medium", "wrap": true}}
+	for _, line := range m.Lines() {
+		body = append(body, map[string]any{"type": "TextBlock", "text": line, "wrap": true, "spacing": "Small"})
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// post sends a JSON body, signed when a secret is given
+func post(s config.Sink, body any, secret string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, os.ExpandEnv(s.URL), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "diago")
+	for k, v := range s.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(data)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// sendMail sends the message as a plain text email. Authentication is only
+// used when a username is set, so a local SMTP stand-in works without it.
+func sendMail(cfg config.SMTP, m Message) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, os.ExpandEnv(cfg.Password), cfg.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Title()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	if m.RunID != "" {
+		fmt.Fprintf(&msg, "Run %s\r\n\r\n", m.RunID)
+	}
+	for _, line := range m.Lines() {
+		msg.WriteString(line + "\r\n")
+	}
+
+	return smtp.SendMail(addr, auth, cfg.From, cfg.To, []byte(msg.String()))
+}
@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"diago/config"
+)
+
+func testMessage() Message {
+	return Message{RunID: "20261019-120000", Events: []Event{
+		{Kind: KindRegression, Bookie: "betway", Label: "Login.LoginButton", Severity: "critical", Message: "no match for #login"},
+		{Kind: KindUnreachable, Bookie: "sportpesa@mobile", Message: "received HTTP 503 (3 runs in a row)"},
+	}}
+}
+
+// request is what a test sink server received
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// sinkServer records every request and answers with status
+func sinkServer(t *testing.T, status int) (*httptest.Server, <-chan request) {
+	t.Helper()
+	got := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		got <- request{req.Header, body}
+		w.WriteHeader(status)
+		io.WriteString(w, "nope")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func TestWebhookSignature(t *testing.T) {
+	srv, got := sinkServer(t, http.StatusNoContent)
+	t.Setenv("DIAGO_TEST_SECRET", "s3cret")
+	sink := config.Sink{Name: "ops", Type: config.SinkWebhook, URL: srv.URL, Secret: "$DIAGO_TEST_SECRET",
+		Headers: map[string]string{"X-Team": "oncall"}}
+
+	if err := deliver(sink, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	req := <-got
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(req.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.header.Get(SignatureHeader) != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, req.header.Get(SignatureHeader), want)
+	}
+	if req.header.Get("Content-Type") != "application/json" || req.header.Get("X-Team") != "oncall" {
+		t.Errorf("headers = %v", req.header)
+	}
+
+	var body struct {
+		Title  string  `json:"title"`
+		RunID  string  `json:"run_id"`
+		Events []Event `json:"events"`
+	}
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Title != "🚨 diago: 1 regression(s), 1 unreachable" || body.RunID != "20261019-120000" || len(body.Events) != 2 {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestWebhookUnsignedWithoutSecret(t *testing.T) {
+	srv, got := sinkServer(t, http.StatusOK)
+	if err := deliver(config.Sink{Name: "ops", Type: config.SinkWebhook, URL: srv.URL}, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	if sig := (<-got).header.Get(SignatureHeader); sig != "" {
+		t.Errorf("%s = %q, want none", SignatureHeader, sig)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	srv, got := sinkServer(t, http.StatusOK)
+	if err := deliver(config.Sink{Name: "slack", Type: config.SinkSlack, URL: srv.URL}, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal((<-got).body, &body); err != nil {
+		t.Fatal(err)
+	}
+	want := "*🚨 diago: 1 regression(s), 1 unreachable*\n" +
+		"🔴 betway Login.LoginButton (critical): no match for #login\n" +
+		"📵 sportpesa@mobile: received HTTP 503 (3 runs in a row)"
+	if len(body) != 1 || body["text"] != want {
+		t.Errorf("body = %q, want only text %q", body, want)
+	}
+}
+
+func TestTeamsPayload(t *testing.T) {
+	srv, got := sinkServer(t, http.StatusAccepted)
+	if err := deliver(config.Sink{Name: "teams", Type: config.SinkTeams, URL: srv.URL}, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Schema  string `json:"$schema"`
+				Type    string `json:"type"`
+				Version string `json:"version"`
+				Body    []struct {
+					Type   string `json:"type"`
+					Text   string `json:"text"`
+					Weight string `json:"weight"`
+				} `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal((<-got).body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Type != "message" || len(body.Attachments) != 1 {
+		t.Fatalf("body = %+v", body)
+	}
+	card := body.Attachments[0]
+	if card.ContentType != "application/vnd.microsoft.card.adaptive" || card.Content.Type != "AdaptiveCard" || card.Content.Version != "1.4" {
+		t.Errorf("attachment = %+v", card)
+	}
+	blocks := card.Content.Body
+	if len(blocks) != 3 {
+		t.Fatalf("blocks = %+v, want a title and two lines", blocks)
+	}
+	if blocks[0].Weight != "Bolder" || !strings.HasPrefix(blocks[0].Text, "🚨 diago") {
+		t.Errorf("title block = %+v", blocks[0])
+	}
+	for _, b := range blocks {
+		if b.Type != "TextBlock" {
+			t.Errorf("block type = %q", b.Type)
+		}
+	}
+	if blocks[2].Text != "📵 sportpesa@mobile: received HTTP 503 (3 runs in a row)" {
+		t.Errorf("last block = %q", blocks[2].Text)
+	}
+}
+
+func TestPostFailsOnErrorStatus(t *testing.T) {
+	srv, _ := sinkServer(t, http.StatusForbidden)
+	err := deliver(config.Sink{Name: "slack", Type: config.SinkSlack, URL: srv.URL}, testMessage())
+	if err == nil || err.Error() != "HTTP 403: nope" {
+		t.Errorf("err = %v, want HTTP 403: nope", err)
+	}
+}
+
+// mail is what the SMTP stand-in accepted
+type mail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a minimal SMTP stand-in that accepts one message without
+// TLS or authentication
+func smtpServer(t *testing.T) (host string, port int, got <-chan mail) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan mail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var m mail
+		tp.PrintfLine("220 localhost ESMTP stand-in")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL":
+				m.from = strings.TrimSuffix(strings.TrimPrefix(line[len("MAIL FROM:"):], "<"), ">")
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				m.to = append(m.to, strings.TrimSuffix(strings.TrimPrefix(line[len("RCPT TO:"):], "<"), ">"))
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				m.data = string(data)
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				out <- m
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	h, p, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ = strconv.Atoi(p)
+	return h, port, out
+}
+
+func TestEmail(t *testing.T) {
+	host, port, got := smtpServer(t)
+	sink := config.Sink{Name: "mail", Type: config.SinkEmail, SMTP: config.SMTP{
+		Host: host, Port: port, From: "diago@example.com", To: []string{"ops@example.com", "qa@example.com"},
+	}}
+	if err := deliver(sink, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := <-got
+	if m.from != "diago@example.com" || strings.Join(m.to, ",") != "ops@example.com,qa@example.com" {
+		t.Errorf("envelope from %q to %v", m.from, m.to)
+	}
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(m.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Get("To") != "ops@example.com, qa@example.com" || msg.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("headers = %v", msg)
+	}
+	if !strings.HasPrefix(msg.Get("Subject"), "=?utf-8?q?") {
+		t.Errorf("Subject = %q, want it Q-encoded", msg.Get("Subject"))
+	}
+	for _, want := range []string{"Run 20261019-120000", "🔴 betway Login.LoginButton (critical): no match for #login"} {
+		if !strings.Contains(m.data, want) {
+			t.Errorf("body is missing %q:\n%s", want, m.data)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package report
+
+import "fmt"
+
+// Alerts returns the selectors that regressed and the bookies that became
+// unreachable, to gate on or notify about. With alertAfter of 1 these are
+// the changes in the diff; with more, a check only alerts in the run where
+// it has failed alertAfter times in a row, so flaky checks stay quiet.
+func Alerts(r FullReport, alertAfter int) (regressions, unreachable []Change) {
+	if alertAfter <= 1 {
+		if r.Diff == nil {
+			return nil, nil
+		}
+		return r.Diff.Regressions, r.Diff.Unreachable
+	}
+
+	for _, b := range r.Details {
+		for _, v := range b.WithVariants() {
+			if v.DownStreak == alertAfter {
+				unreachable = append(unreachable, Change{Bookie: v.Key(), Message: fmt.Sprintf("%s (%d runs in a row)", fetchError(v), v.DownStreak)})
+				continue
+			}
+			for _, res := range v.Results {
+				if res.Status == "❌" && res.FailStreak == alertAfter {
+					regressions = append(regressions, Change{
+						Bookie:   v.Key(),
+						Label:    res.Label,
+						After:    res.Status,
+						Severity: res.Severity,
+						Message:  fmt.Sprintf("%s (%d runs in a row)", res.Message, res.FailStreak),
+					})
+				}
+			}
+		}
+	}
+	return regressions, unreachable
+}
@@ -0,0 +1,269 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FetchErrorLabel is the result label of a bookie whose page could not be fetched
+const FetchErrorLabel = "Fetch error"
+
+// Selectors are the configured alternatives of every selector, by bookie
+// key and label, as they were when a run was made
+type Selectors map[string]map[string][]string
+
+// Change is one difference between two runs
+type Change struct {
+	Bookie   string `json:"bookie"`
+	Label    string `json:"label,omitempty"`
+	Before   string `json:"before,omitempty"`
+	After    string `json:"after,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Diff is what changed between a base run and the current one
+type Diff struct {
+	BaseRunID     string `json:"base_run_id"`
+	BaseStartedAt string `json:"base_started_at,omitempty"`
+	RunID         string `json:"run_id,omitempty"`
+
+	// Regressions went from ✅ or ⚠️ to ❌; Fixes the other way round
+	Regressions []Change `json:"regressions,omitempty"`
+	Fixes       []Change `json:"fixes,omitempty"`
+
+	// Added and Removed are selectors checked in only one of the runs
+	Added   []Change `json:"added,omitempty"`
+	Removed []Change `json:"removed,omitempty"`
+
+	// Unreachable bookies could be fetched in the base run but not now;
+	// Recovered ones the other way round
+	Unreachable []Change `json:"unreachable,omitempty"`
+	Recovered   []Change `json:"recovered,omitempty"`
+
+	// ConfigChanges are selectors whose alternatives or bookies whose URL changed
+	ConfigChanges []Change `json:"config_changes,omitempty"`
+
+	NewBookies     []string `json:"new_bookies,omitempty"`
+	RemovedBookies []string `json:"removed_bookies,omitempty"`
+}
+
+// IsZero reports whether nothing changed
+func (d Diff) IsZero() bool {
+	return len(d.Regressions) == 0 && len(d.Fixes) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 &&
+		len(d.Unreachable) == 0 && len(d.Recovered) == 0 && len(d.ConfigChanges) == 0 &&
+		len(d.NewBookies) == 0 && len(d.RemovedBookies) == 0
+}
+
+// Regressed reports whether a selector broke or a bookie became unreachable
+func (d Diff) Regressed() bool {
+	return len(d.Regressions) > 0 || len(d.Unreachable) > 0
+}
+
+// Compare diffs the current report against a base run. The selectors of
+// each run are optional; without them config changes are not listed.
+func Compare(base, current FullReport, baseSelectors, currentSelectors Selectors) Diff {
+	d := Diff{BaseRunID: base.RunID, BaseStartedAt: base.StartedAt, RunID: current.RunID}
+	before := bookiesByKey(base)
+	after := bookiesByKey(current)
+
+	for _, key := range sortedKeys(after) {
+		cur := after[key]
+		old, ok := before[key]
+		if !ok {
+			d.NewBookies = append(d.NewBookies, key)
+			continue
+		}
+
+		if old.URL != cur.URL {
+			d.ConfigChanges = append(d.ConfigChanges, Change{Bookie: key, Label: "url", Before: old.URL, After: cur.URL})
+		}
+		d.ConfigChanges = append(d.ConfigChanges, selectorChanges(key, baseSelectors[key], currentSelectors[key])...)
+
+		oldErr, curErr := fetchError(old), fetchError(cur)
+		switch {
+		case curErr != "" && oldErr == "":
+			d.Unreachable = append(d.Unreachable, Change{Bookie: key, Message: curErr})
+			continue
+		case curErr == "" && oldErr != "":
+			d.Recovered = append(d.Recovered, Change{Bookie: key, Message: oldErr})
+			continue
+		case curErr != "":
+			continue
+		}
+		compareResults(&d, key, old, cur)
+	}
+
+	for _, key := range sortedKeys(before) {
+		if _, ok := after[key]; !ok {
+			d.RemovedBookies = append(d.RemovedBookies, key)
+		}
+	}
+	return d
+}
+
+// compareResults adds the selector changes of one reachable bookie
+func compareResults(d *Diff, key string, old, cur BookieReport) {
+	oldResults := map[string]SelectorResult{}
+	for _, r := range old.Results {
+		oldResults[r.Label] = r
+	}
+	seen := map[string]bool{}
+
+	for _, r := range cur.Results {
+		seen[r.Label] = true
+		prev, ok := oldResults[r.Label]
+		c := Change{Bookie: key, Label: r.Label, Before: prev.Status, After: r.Status, Severity: r.Severity, Message: r.Message}
+		switch {
+		case !ok:
+			c.Before = ""
+			d.Added = append(d.Added, c)
+		case prev.Healthy() && r.Status == "❌":
+			d.Regressions = append(d.Regressions, c)
+		case prev.Status == "❌" && r.Healthy():
+			c.Message = r.Matched
+			d.Fixes = append(d.Fixes, c)
+		}
+	}
+	for _, r := range old.Results {
+		if !seen[r.Label] {
+			d.Removed = append(d.Removed, Change{Bookie: key, Label: r.Label, Before: r.Status, Severity: r.Severity})
+		}
+	}
+}
+
+// selectorChanges lists labels whose configured alternatives differ
+func selectorChanges(key string, before, after map[string][]string) []Change {
+	if before == nil || after == nil {
+		return nil
+	}
+	var changes []Change
+	for _, label := range sortedKeys(after) {
+		old, ok := before[label]
+		now := after[label]
+		if ok && strings.Join(old, "\n") != strings.Join(now, "\n") {
+			changes = append(changes, Change{Bookie: key, Label: label, Before: strings.Join(old, " || "), After: strings.Join(now, " || ")})
+		}
+	}
+	return changes
+}
+
+// bookiesByKey indexes a report's bookies and device variants
+func bookiesByKey(r FullReport) map[string]BookieReport {
+	out := map[string]BookieReport{}
+	for _, b := range r.Details {
+		for _, v := range b.WithVariants() {
+			out[v.Key()] = v
+		}
+	}
+	return out
+}
+
+// fetchError returns why a bookie's page could not be fetched, if it could not
+func fetchError(b BookieReport) string {
+	for _, r := range b.Results {
+		if r.Label == FetchErrorLabel {
+			return r.Status
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SaveDiff writes the diff to JSON
+func SaveDiff(d Diff, filename string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode diff: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write diff file: %w", err)
+	}
+	return nil
+}
+
+// writeDiff renders the changes since the base run, for the top of report.md
+func writeDiff(w io.Writer, d *Diff) {
+	if d == nil {
+		return
+	}
+	fmt.Fprintf(w, "## 🔀 Changes since run `%s`\n\n", d.BaseRunID)
+	if d.IsZero() {
+		fmt.Fprintf(w, "No changes.\n\n")
+		return
+	}
+
+	var counts []string
+	for _, c := range []struct {
+		n    int
+		name string
+	}{
+		{len(d.Regressions), "regression(s)"}, {len(d.Fixes), "fix(es)"},
+		{len(d.Unreachable), "unreachable"}, {len(d.Recovered), "recovered"},
+		{len(d.Added), "new selector(s)"}, {len(d.Removed), "removed selector(s)"},
+		{len(d.ConfigChanges), "config change(s)"},
+		{len(d.NewBookies), "new bookie(s)"}, {len(d.RemovedBookies), "removed bookie(s)"},
+	} {
+		if c.n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", c.n, c.name))
+		}
+	}
+	fmt.Fprintf(w, "> %s\n\n", strings.Join(counts, " · "))
+
+	writeChanges(w, "🔴 Regressions", d.Regressions, "| Bookie | Selector | Severity | Before | Now | Message |", func(c Change) string {
+		return fmt.Sprintf("| %s | %s | %s | %s | %s | %s |", c.Bookie, c.Label, c.Severity, c.Before, c.After, cell(c.Message))
+	})
+	writeChanges(w, "📵 Unreachable", d.Unreachable, "| Bookie | Error |", func(c Change) string {
+		return fmt.Sprintf("| %s | %s |", c.Bookie, cell(c.Message))
+	})
+	writeChanges(w, "🟢 Fixes", d.Fixes, "| Bookie | Selector | Before | Now | Matched |", func(c Change) string {
+		return fmt.Sprintf("| %s | %s | %s | %s | `%s` |", c.Bookie, c.Label, c.Before, c.After, cell(c.Message))
+	})
+	writeChanges(w, "📶 Recovered", d.Recovered, "| Bookie | Previous error |", func(c Change) string {
+		return fmt.Sprintf("| %s | %s |", c.Bookie, cell(c.Message))
+	})
+	writeChanges(w, "➕ New selectors", d.Added, "| Bookie | Selector | Status |", func(c Change) string {
+		return fmt.Sprintf("| %s | %s | %s |", c.Bookie, c.Label, c.After)
+	})
+	writeChanges(w, "➖ Removed selectors", d.Removed, "| Bookie | Selector | Last status |", func(c Change) string {
+		return fmt.Sprintf("| %s | %s | %s |", c.Bookie, c.Label, c.Before)
+	})
+	writeChanges(w, "🛠️ Config changes", d.ConfigChanges, "| Bookie | Field | Before | Now |", func(c Change) string {
+		return fmt.Sprintf("| %s | %s | `%s` | `%s` |", c.Bookie, c.Label, cell(c.Before), cell(c.After))
+	})
+	if len(d.NewBookies) > 0 {
+		fmt.Fprintf(w, "**New bookies:** %s\n\n", strings.Join(d.NewBookies, ", "))
+	}
+	if len(d.RemovedBookies) > 0 {
+		fmt.Fprintf(w, "**Removed bookies:** %s\n\n", strings.Join(d.RemovedBookies, ", "))
+	}
+}
+
+// writeChanges renders one group of changes as a table
+func writeChanges(w io.Writer, title string, changes []Change, header string, row func(Change) string) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "### %s\n\n%s\n|%s\n", title, header, strings.Repeat("---|", strings.Count(header, "|")-1))
+	for _, c := range changes {
+		fmt.Fprintln(w, row(c))
+	}
+	fmt.Fprintln(w)
+}
+
+// cell escapes pipes so selectors like "a || b" stay in their table cell
+func cell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
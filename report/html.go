@@ -0,0 +1,216 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboard = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(dashboardHTML))
+
+// dashboardView is what the HTML template renders
+type dashboardView struct {
+	RunID     string
+	StartedAt string
+	Offline   bool
+	Sections  []string
+	Tags      []string
+	Rows      []dashboardRow
+	Counts    map[string]int
+}
+
+// dashboardRow is one bookie or device variant
+type dashboardRow struct {
+	Key     string
+	URL     string
+	Device  string
+	Score   float64
+	AllPass bool
+	Drift   *Drift
+	Frames  []Frame
+	Cells   []dashboardCell
+	Results []dashboardResult
+}
+
+// dashboardCell is one bookie × section square of the heatmap
+type dashboardCell struct {
+	Section string
+	Present bool
+	Score   float64
+	Scored  bool
+	Passed  int
+	Failed  int
+	Class   string
+}
+
+// dashboardResult is a selector result with the fields the filters use
+type dashboardResult struct {
+	SelectorResult
+	Section string
+	Class   string
+	Tags    []string
+	Search  string
+}
+
+// statusClasses maps result statuses to CSS classes; anything else is an error
+var statusClasses = map[string]string{"✅": "pass", "⚠️": "warn", "❌": "fail", "➖": "na"}
+
+// SaveHTML writes the report as a single HTML file with a bookies × section
+// heatmap, filters, search and collapsible evidence. CSS and scripts are
+// inlined so the file can be opened offline or attached to a CI run.
+func SaveHTML(report FullReport, filename string) error {
+	var buf bytes.Buffer
+	if err := dashboard.Execute(&buf, buildDashboard(report)); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write HTML file: %w", err)
+	}
+	return nil
+}
+
+// buildDashboard flattens bookies and their variants into heatmap rows
+func buildDashboard(report FullReport) dashboardView {
+	view := dashboardView{
+		RunID:     report.RunID,
+		StartedAt: report.StartedAt,
+		Offline:   report.Offline,
+		Counts:    map[string]int{},
+	}
+
+	seenSection := map[string]bool{}
+	seenTag := map[string]bool{}
+	var reports []BookieReport
+	for _, b := range report.Details {
+		reports = append(reports, b.WithVariants()...)
+	}
+	for _, b := range reports {
+		for _, r := range b.Results {
+			if s := sectionOf(r.Label); !seenSection[s] {
+				seenSection[s] = true
+				view.Sections = append(view.Sections, s)
+			}
+		}
+	}
+
+	for _, b := range reports {
+		row := dashboardRow{
+			Key:     b.Key(),
+			URL:     b.URL,
+			Device:  b.Device,
+			Score:   b.Score,
+			AllPass: b.AllPass,
+			Drift:   b.Drift,
+			Frames:  b.Frames,
+		}
+
+		scores := map[string]SectionScore{}
+		for _, s := range b.Sections {
+			scores[s.Name] = s
+		}
+		present := map[string]bool{}
+
+		for _, r := range b.Results {
+			res := dashboardResult{
+				SelectorResult: r,
+				Section:        sectionOf(r.Label),
+				Class:          statusClass(r.Status),
+				Tags:           resultTags(b, r),
+			}
+			res.Search = strings.ToLower(strings.Join([]string{row.Key, r.Label, r.Matched, r.Message, r.Frame}, " "))
+			for _, t := range res.Tags {
+				seenTag[t] = true
+			}
+			present[res.Section] = true
+			view.Counts[res.Class]++
+			row.Results = append(row.Results, res)
+		}
+
+		for _, name := range view.Sections {
+			s, scored := scores[name]
+			cell := dashboardCell{Section: name, Present: present[name], Scored: scored, Score: s.Score, Passed: s.Passed, Failed: s.Failed}
+			cell.Class = heatClass(cell)
+			row.Cells = append(row.Cells, cell)
+		}
+		view.Rows = append(view.Rows, row)
+	}
+
+	for t := range seenTag {
+		view.Tags = append(view.Tags, t)
+	}
+	sort.Strings(view.Tags)
+	return view
+}
+
+// sectionOf returns the first segment of a label, e.g. "Login" for "Login.UsernameInput"
+func sectionOf(label string) string {
+	return strings.SplitN(label, ".", 2)[0]
+}
+
+func statusClass(status string) string {
+	if c, ok := statusClasses[status]; ok {
+		return c
+	}
+	return "error"
+}
+
+// resultTags are the filterable properties of a result: its severity,
+// locator type, device, whether it matched a fallback, in a frame, is an
+// API probe or has suggestions, and whether it is flaky or broken
+func resultTags(b BookieReport, r SelectorResult) []string {
+	var tags []string
+	if r.Severity != "" {
+		tags = append(tags, r.Severity)
+	}
+	if r.Locator != "" {
+		tags = append(tags, r.Locator)
+	}
+	if b.Device != "" {
+		tags = append(tags, b.Device)
+	}
+	if r.Alternative > 1 {
+		tags = append(tags, "fallback")
+	}
+	if r.Frame != "" {
+		tags = append(tags, "frame")
+	}
+	if strings.HasPrefix(r.Label, "API.") {
+		tags = append(tags, "api")
+	}
+	if len(r.Suggestions) > 0 {
+		tags = append(tags, "suggested")
+	}
+	if r.Stability == "flaky" || r.Stability == "broken" {
+		tags = append(tags, r.Stability)
+	}
+	return tags
+}
+
+// heatClass buckets a section score into a heatmap colour
+func heatClass(c dashboardCell) string {
+	switch {
+	case !c.Present:
+		return "none"
+	case !c.Scored:
+		return "na"
+	case c.Score >= 100:
+		return "h100"
+	case c.Score >= 75:
+		return "h75"
+	case c.Score >= 50:
+		return "h50"
+	case c.Score > 0:
+		return "h25"
+	default:
+		return "h0"
+	}
+}
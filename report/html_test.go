@@ -0,0 +1,76 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := SaveHTML(testReport(), path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+
+	for _, want := range []string{
+		"<title>Diago report · 20261019-120000.000</title>",
+		// Counts by status over both bookies and the variant
+		"✅ 2 passed", "⚠️ 1 fallback", "❌ 1 failed", "➖ 1 n/a", "💥 1 errors",
+		// Heatmap rows and cells
+		`<tr data-bookie="betway@mobile">`,
+		`data-bookie="sportpesa" data-section="Login"`,
+		"📐 Layout drift: similarity 0.42 below 0.85",
+		// Filterable result rows, with a fallback matched in a frame
+		`data-status="warn" data-section="Login" data-tags=" critical desktop fallback frame "`,
+		"🖼️ in frame <code>#sportsbook</code>",
+		// Messages, evidence and URLs from the page are escaped
+		"no match for &lt;script&gt;alert(&#34;otp&#34;)&lt;/script&gt;",
+		"“Tom &amp; &#34;Jerry&#34;”",
+		"<pre>&lt;b onclick=&#34;steal()&#34;&gt;Tom &amp;amp; &#34;Jerry&#34;&lt;/b&gt;</pre>",
+		`<code>input[name=&#34;otp&#34;]</code>`,
+		`<a href="https://betway.test/?a=1&amp;b=2">`,
+		`<a href="#ZgotmplZ">`,
+		"received HTTP 503 for &#34;https://sportpesa.test&#34;",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML is missing %s", want)
+		}
+	}
+	for _, unsafe := range []string{`<script>alert("otp")`, `<b onclick="steal()">`, `href="javascript:`} {
+		if strings.Contains(page, unsafe) {
+			t.Errorf("HTML contains unescaped %s", unsafe)
+		}
+	}
+}
+
+func TestBuildDashboardHeatmap(t *testing.T) {
+	view := buildDashboard(testReport())
+
+	if strings.Join(view.Sections, ",") != "Login,LiveBetting,"+FetchErrorLabel {
+		t.Fatalf("sections = %v", view.Sections)
+	}
+	cells := map[string]string{}
+	for _, row := range view.Rows {
+		for _, c := range row.Cells {
+			cells[row.Key+" "+c.Section] = c.Class
+		}
+	}
+	want := map[string]string{
+		"betway Login":              "h75",
+		"betway LiveBetting":        "na",
+		"betway@mobile Login":       "h100",
+		"betway@mobile LiveBetting": "none",
+		"sportpesa Login":           "none",
+	}
+	for key, class := range want {
+		if cells[key] != class {
+			t.Errorf("%s cell = %q, want %q", key, cells[key], class)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// JUnit XML elements, in the schema understood by Jenkins, GitLab and GitHub test reporters
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitProblem `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// SaveJUnit writes the report as JUnit XML: one testsuite per bookie and
+// device, one testcase per selector or API probe. ❌ is a failure, ➖ is
+// skipped, ✅ and ⚠️ pass, and anything else (a fetch error) is an error.
+func SaveJUnit(report FullReport, filename string) error {
+	suites := junitSuites{Name: "diago"}
+	var total float64
+	for _, b := range report.Details {
+		for _, v := range b.WithVariants() {
+			s := junitSuiteOf(v, report.StartedAt)
+			suites.Tests += s.Tests
+			suites.Failures += s.Failures
+			suites.Errors += s.Errors
+			suites.Skipped += s.Skipped
+			total += v.DurationMS
+			suites.Suites = append(suites.Suites, s)
+		}
+	}
+	suites.Time = seconds(total)
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JUnit XML: %w", err)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit file: %w", err)
+	}
+	return nil
+}
+
+// junitSuiteOf converts one bookie report, without its variants
+func junitSuiteOf(b BookieReport, timestamp string) junitSuite {
+	s := junitSuite{
+		Name:      b.Key(),
+		Time:      seconds(b.DurationMS),
+		Timestamp: timestamp,
+		Properties: []junitProperty{
+			{Name: "url", Value: b.URL},
+			{Name: "score", Value: fmt.Sprintf("%.1f", b.Score)},
+		},
+	}
+	if b.Device != "" {
+		s.Properties = append(s.Properties, junitProperty{Name: "device", Value: b.Device})
+	}
+	if b.Drift != nil && b.Drift.Alert {
+		s.Properties = append(s.Properties, junitProperty{Name: "layout_drift", Value: fmt.Sprintf("%.2f", b.Drift.Similarity)})
+	}
+
+	for _, r := range b.Results {
+		c := junitCase{
+			Name:      r.Label,
+			Classname: b.Key() + "." + strings.SplitN(r.Label, ".", 2)[0],
+			Time:      seconds(r.DurationMS),
+		}
+		switch r.Status {
+		case "✅":
+			c.SystemOut = junitDetail(r)
+		case "⚠️":
+			c.SystemOut = strings.TrimSpace(r.Message + "\n" + junitDetail(r))
+		case "❌":
+			c.Failure = &junitProblem{Message: r.Message, Type: r.Severity, Text: junitDetail(r)}
+			s.Failures++
+		case "➖":
+			c.Skipped = &junitProblem{Message: r.Message}
+			s.Skipped++
+		default:
+			c.Error = &junitProblem{Message: r.Status, Type: r.Label}
+			s.Errors++
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	s.Tests = len(s.Cases)
+	return s
+}
+
+// junitDetail describes what a selector matched, for the test output
+func junitDetail(r SelectorResult) string {
+	var lines []string
+	if r.Matched != "" {
+		lines = append(lines, "matched: "+r.Matched)
+	}
+	if r.Frame != "" {
+		lines = append(lines, "frame: "+r.Frame)
+	}
+	if r.Evidence != nil {
+		lines = append(lines, fmt.Sprintf("element: %s (%d match(es))", r.Evidence.Path, r.Evidence.Count))
+	}
+	for _, sug := range r.Suggestions {
+		lines = append(lines, fmt.Sprintf("suggestion: %s (confidence %.2f)", sug.Selector, sug.Confidence))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// seconds renders milliseconds as JUnit's decimal seconds
+func seconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testReport has a bookie with every result status, a device variant with
+// layout drift, and an unreachable bookie
+func testReport() FullReport {
+	betway := BookieReport{
+		Name:       "betway",
+		URL:        "https://betway.test/?a=1&b=2",
+		Device:     "desktop",
+		DurationMS: 1250,
+		Results: []SelectorResult{
+			{Label: "Login.UsernameInput", Status: "✅", Severity: "critical", Matched: "#user", Alternative: 1, DurationMS: 2.5,
+				Evidence: &Evidence{Count: 1, Tag: "input", ID: "user", Path: "html > body > input#user", OuterHTML: `<input id="user">`}},
+			{Label: "Login.LoginButton", Status: "⚠️", Severity: "critical", Matched: "button.m-login", Alternative: 2, Frame: "#sportsbook",
+				Message: "only fallback 2/2 matched"},
+			{Label: "Login.OtpInput", Status: "❌", Severity: "major", Message: `no match for <script>alert("otp")</script>`,
+				Evidence: &Evidence{Count: 2, Tag: "b", Text: `Tom & "Jerry"`, Path: "html > body > b", OuterHTML: `<b onclick="steal()">Tom &amp; "Jerry"</b>`},
+				Suggestions: []Suggestion{{Selector: `input[name="otp"]`, Confidence: 0.8, Reason: "name~\"otp\""}}},
+			{Label: "LiveBetting.LiveScore", Status: "➖", Severity: "n/a", Message: "not applicable"},
+		},
+	}
+	betway.ApplyScores()
+
+	mobile := BookieReport{
+		Name:   "betway",
+		URL:    "https://m.betway.test",
+		Device: "mobile",
+		Drift:  &Drift{Hash: "b", PreviousHash: "a", Similarity: 0.42, Threshold: 0.85, Alert: true},
+		Results: []SelectorResult{
+			{Label: "Login.UsernameInput", Status: "✅", Severity: "critical", Matched: "#phone"},
+		},
+	}
+	mobile.ApplyScores()
+	betway.Variants = []BookieReport{mobile}
+
+	down := BookieReport{
+		Name:    "sportpesa",
+		URL:     "javascript:alert(1)",
+		Results: []SelectorResult{{Label: FetchErrorLabel, Status: "received HTTP 503 for \"https://sportpesa.test\""}},
+	}
+	down.ApplyScores()
+
+	return FullReport{
+		RunID:     "20261019-120000.000",
+		StartedAt: "2026-10-19T12:00:00Z",
+		Details:   []BookieReport{betway, down},
+		Summary:   []BookieReport{betway, down},
+	}
+}
+
+func TestSaveJUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.xml")
+	if err := SaveJUnit(testReport(), path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Errorf("missing XML header:\n%s", data)
+	}
+
+	var got junitSuites
+	if err := xml.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "diago" || got.Tests != 6 || got.Failures != 1 || got.Errors != 1 || got.Skipped != 1 || got.Time != "1.250" {
+		t.Errorf("testsuites = %s %d tests, %d failures, %d errors, %d skipped in %s",
+			got.Name, got.Tests, got.Failures, got.Errors, got.Skipped, got.Time)
+	}
+
+	var names []string
+	for _, s := range got.Suites {
+		names = append(names, s.Name)
+	}
+	if want := []string{"betway", "betway@mobile", "sportpesa"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("suites = %q, want %q", names, want)
+	}
+
+	desktop := got.Suites[0]
+	if desktop.Tests != 4 || desktop.Failures != 1 || desktop.Skipped != 1 || desktop.Timestamp != "2026-10-19T12:00:00Z" {
+		t.Errorf("desktop suite = %+v", desktop)
+	}
+	props := map[string]string{}
+	for _, p := range desktop.Properties {
+		props[p.Name] = p.Value
+	}
+	if props["url"] != "https://betway.test/?a=1&b=2" || props["device"] != "desktop" || props["score"] == "" {
+		t.Errorf("desktop properties = %v", props)
+	}
+
+	cases := desktop.Cases
+	if c := cases[0]; c.Name != "Login.UsernameInput" || c.Classname != "betway.Login" || c.Time != "0.003" ||
+		c.Failure != nil || c.SystemOut != "matched: #user\nelement: html > body > input#user (1 match(es))" {
+		t.Errorf("passing case = %+v", c)
+	}
+	if c := cases[1]; c.Failure != nil || c.SystemOut != "only fallback 2/2 matched\nmatched: button.m-login\nframe: #sportsbook" {
+		t.Errorf("fallback case = %+v", c)
+	}
+	if c := cases[2]; c.Failure == nil || c.Failure.Message != `no match for <script>alert("otp")</script>` || c.Failure.Type != "major" ||
+		!strings.Contains(c.Failure.Text, `suggestion: input[name="otp"] (confidence 0.80)`) {
+		t.Errorf("failing case = %+v", c)
+	}
+	if c := cases[3]; c.Skipped == nil || c.Skipped.Message != "not applicable" {
+		t.Errorf("skipped case = %+v", c)
+	}
+
+	var drift string
+	for _, p := range got.Suites[1].Properties {
+		if p.Name == "layout_drift" {
+			drift = p.Value
+		}
+	}
+	if drift != "0.42" {
+		t.Errorf("mobile layout_drift = %q, want 0.42", drift)
+	}
+
+	down := got.Suites[2]
+	if down.Errors != 1 || down.Cases[0].Error == nil || down.Cases[0].Error.Message != `received HTTP 503 for "https://sportpesa.test"` ||
+		down.Cases[0].Error.Type != FetchErrorLabel {
+		t.Errorf("unreachable suite = %+v", down)
+	}
+}
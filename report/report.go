@@ -1,15 +1,68 @@
 package report
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"os"
+	"strings"
+
+	"diago/fingerprint"
 )
 
 // SelectorResult = result for a single selector check
 type SelectorResult struct {
-	Label  string `json:"label"`
-	Status string `json:"status"`
+	Label    string `json:"label"`
+	Status   string `json:"status"`
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message,omitempty"`
+
+	// Matched is the alternative that passed and Alternative its 1-based
+	// position in the selector's fallback chain
+	Matched     string `json:"matched,omitempty"`
+	Alternative int    `json:"alternative,omitempty"`
+
+	// Frame is the path of the iframe the selector matched in; empty for the top document
+	Frame string `json:"frame,omitempty"`
+
+	// Locator is how the selector finds elements: css, xpath, text or role
+	Locator string `json:"locator,omitempty"`
+
+	// Evidence describes what the selector matched, to spot false positives
+	Evidence *Evidence `json:"evidence,omitempty"`
+
+	// Suggestions are replacement candidates for a failing selector
+	Suggestions []Suggestion `json:"suggestions,omitempty"`
+
+	// Schema is the field/type map of an API probe's response
+	Schema map[string]string `json:"-"`
+
+	// DurationMS is how long the check took, in milliseconds
+	DurationMS float64 `json:"duration_ms,omitempty"`
+
+	// Stability is stable, flaky or broken over the recent runs, and
+	// FailStreak the number of runs in a row it has failed
+	Stability  string `json:"stability,omitempty"`
+	FailStreak int    `json:"fail_streak,omitempty"`
+}
+
+// Evidence is a bounded description of the first element a selector matched
+type Evidence struct {
+	Count     int      `json:"count"`
+	Tag       string   `json:"tag"`
+	ID        string   `json:"id,omitempty"`
+	Classes   []string `json:"classes,omitempty"`
+	Text      string   `json:"text,omitempty"`
+	OuterHTML string   `json:"outer_html,omitempty"`
+	Path      string   `json:"path"`
+}
+
+// Suggestion is a candidate selector found in the fetched page
+type Suggestion struct {
+	Selector   string  `json:"selector"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
 }
 
 // BookieReport = detailed verification for one bookie
@@ -18,27 +71,311 @@ type BookieReport struct {
 	URL     string           `json:"url"`
 	Results []SelectorResult `json:"results"`
 	AllPass bool             `json:"all_pass"`
+
+	// Score is the severity-weighted health (0-100) used to gate CI
+	Score    float64        `json:"score"`
+	Sections []SectionScore `json:"sections,omitempty"`
+
+	// Fingerprint is the structure of the fetched page; Drift compares it
+	// with the previous run
+	Fingerprint *fingerprint.Fingerprint `json:"-"`
+	Drift       *Drift                   `json:"drift,omitempty"`
+
+	// Frames are the iframes that were loaded and searched
+	Frames []Frame `json:"frames,omitempty"`
+
+	// SchemaDrift lists API probes whose response fields changed since the previous run
+	SchemaDrift []SchemaDrift `json:"schema_drift,omitempty"`
+
+	// Device is the profile the bookie was verified as; Variants holds the
+	// reports of its other devices
+	Device   string         `json:"device,omitempty"`
+	Variants []BookieReport `json:"variants,omitempty"`
+
+	// HTTPStatus is the status the page answered with; 0 when it did not answer
+	HTTPStatus int `json:"http_status,omitempty"`
+
+	// DurationMS is the time spent fetching and verifying the bookie, in milliseconds
+	DurationMS float64 `json:"duration_ms,omitempty"`
+
+	// Stability, FailStreak and DownStreak (runs in a row it could not be
+	// fetched) are computed from the recent runs
+	Stability  string `json:"stability,omitempty"`
+	FailStreak int    `json:"fail_streak,omitempty"`
+	DownStreak int    `json:"down_streak,omitempty"`
+}
+
+// Key identifies a bookie report across runs, e.g. "betway" or "betway@mobile".
+// The default desktop profile shares the bookie's name.
+func (b BookieReport) Key() string {
+	if b.Device == "" || b.Device == "desktop" {
+		return b.Name
+	}
+	return b.Name + "@" + b.Device
+}
+
+// WithVariants returns the report followed by its device variants
+func (b BookieReport) WithVariants() []BookieReport {
+	return append([]BookieReport{b}, b.Variants...)
+}
+
+// SchemaDrift is how an API probe's response schema changed
+type SchemaDrift struct {
+	Probe   string   `json:"probe"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// IsZero reports whether the schema is unchanged
+func (d SchemaDrift) IsZero() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Frame is a loaded iframe, addressable in selectors as "frame:<path> >> ..."
+type Frame struct {
+	Path string `json:"path"`
+	URL  string `json:"url"`
+}
+
+// Drift is the layout change of a bookie's page since the previous run
+type Drift struct {
+	Hash         string  `json:"hash"`
+	PreviousHash string  `json:"previous_hash,omitempty"`
+	Similarity   float64 `json:"similarity"`
+	Threshold    float64 `json:"threshold"`
+	Alert        bool    `json:"alert"`
 }
 
 // FullReport = JSON structure with summary + details
 type FullReport struct {
-	Summary []BookieReport `json:"summary"`
-	Details []BookieReport `json:"details"`
+	// RunID is the snapshot run the pages were stored under or replayed from
+	RunID   string `json:"run_id,omitempty"`
+	Offline bool   `json:"offline,omitempty"`
+
+	// Partial marks a scheduled cycle that only checked the bookies that were due
+	Partial bool `json:"partial,omitempty"`
+
+	// StartedAt is when the run began, in RFC 3339
+	StartedAt string         `json:"started_at,omitempty"`
+	Summary   []BookieReport `json:"summary"`
+	Details   []BookieReport `json:"details"`
+
+	// Diff is what changed since the base run; it is saved as diff.json
+	Diff *Diff `json:"-"`
 }
 
 // SaveJSON writes the full report to JSON
 func SaveJSON(report FullReport, filename string) error {
-	data, err := json.MarshalIndent(report, "", "  ")
-	if err != nil {
+	// Keep evidence HTML readable instead of escaping < and >
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
 		return fmt.Errorf("failed to write JSON file: %w", err)
 	}
-	fmt.Printf("📄 Saved JSON report: %s\n", filename)
 	return nil
 }
 
+// writeDriftAlerts lists bookies whose page structure changed beyond the threshold
+func writeDriftAlerts(f *os.File, report FullReport) {
+	var alerts []BookieReport
+	for _, s := range report.Summary {
+		for _, v := range s.WithVariants() {
+			if v.Drift != nil && v.Drift.Alert {
+				alerts = append(alerts, v)
+			}
+		}
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(f, "\n## 📐 Layout drift\n")
+	fmt.Fprintf(f, "| Bookie | Similarity | Threshold | Previous | Current |\n")
+	fmt.Fprintf(f, "|--------|------------|-----------|----------|---------|\n")
+	for _, a := range alerts {
+		fmt.Fprintf(f, "| %s | %.2f | %.2f | %s | %s |\n", a.Key(), a.Drift.Similarity, a.Drift.Threshold, a.Drift.PreviousHash, a.Drift.Hash)
+	}
+}
+
+// writeSchemaDrift lists API probes whose response fields changed
+func writeSchemaDrift(f *os.File, report FullReport) {
+	var lines []string
+	for _, b := range report.Summary {
+		for _, s := range b.WithVariants() {
+			lines = append(lines, schemaDriftLines(s)...)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(f, "\n## 🧬 API schema drift\n")
+	fmt.Fprintf(f, "| Bookie | Probe | Change | Field |\n")
+	fmt.Fprintf(f, "|--------|-------|--------|-------|\n")
+	fmt.Fprintf(f, "%s\n", strings.Join(lines, "\n"))
+}
+
+// schemaDriftLines renders the schema changes of one report as table rows
+func schemaDriftLines(s BookieReport) []string {
+	var lines []string
+	for _, d := range s.SchemaDrift {
+		for _, p := range d.Removed {
+			lines = append(lines, fmt.Sprintf("| %s | %s | ➖ removed | `%s` |", s.Key(), d.Probe, p))
+		}
+		for _, p := range d.Changed {
+			lines = append(lines, fmt.Sprintf("| %s | %s | 🔀 type changed | `%s` |", s.Key(), d.Probe, p))
+		}
+		for _, p := range d.Added {
+			lines = append(lines, fmt.Sprintf("| %s | %s | ➕ added | `%s` |", s.Key(), d.Probe, p))
+		}
+	}
+	return lines
+}
+
+// stabilityTag marks flaky and broken checks in result lines
+func stabilityTag(stability string, streak int) string {
+	switch stability {
+	case "flaky":
+		return " 🎲 flaky"
+	case "broken":
+		return fmt.Sprintf(" 🧱 broken for %d runs", streak)
+	}
+	return ""
+}
+
+// writeFlaky lists the bookies and checks that flip between passing and
+// failing across recent runs, so their failures are read with care
+func writeFlaky(f *os.File, report FullReport) {
+	var lines []string
+	for _, b := range report.Details {
+		for _, v := range b.WithVariants() {
+			if v.Stability == "flaky" {
+				lines = append(lines, fmt.Sprintf("| %s | (whole bookie) | %s |", v.Key(), overallStatus(v)))
+			}
+			for _, res := range v.Results {
+				if res.Stability == "flaky" {
+					lines = append(lines, fmt.Sprintf("| %s | %s | %s |", v.Key(), res.Label, res.Status))
+				}
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(f, "\n## 🎲 Flaky checks\n")
+	fmt.Fprintf(f, "| Bookie | Check | Now |\n")
+	fmt.Fprintf(f, "|--------|-------|-----|\n")
+	for _, l := range lines {
+		fmt.Fprintln(f, l)
+	}
+}
+
+// overallStatus is ✅ when every check of a bookie passed and ❌ otherwise
+func overallStatus(b BookieReport) string {
+	if b.AllPass {
+		return "✅"
+	}
+	return "❌"
+}
+
+// ScoreCell renders a bookie's score, followed by its device variants' scores
+func ScoreCell(b BookieReport) string {
+	cell := fmt.Sprintf("%.1f", b.Score)
+	for _, v := range b.Variants {
+		cell += fmt.Sprintf(" · %s %.1f", v.Device, v.Score)
+	}
+	return cell
+}
+
+// writeDeviceMatrix shows every selector's status side by side per device
+func writeDeviceMatrix(f *os.File, b BookieReport) {
+	reports := b.WithVariants()
+	var labels []string
+	status := map[string]map[string]string{}
+	for _, r := range reports {
+		for _, res := range r.Results {
+			if status[res.Label] == nil {
+				status[res.Label] = map[string]string{}
+				labels = append(labels, res.Label)
+			}
+			status[res.Label][r.Device] = res.Status
+		}
+	}
+
+	fmt.Fprintf(f, "\n#### 📱 Device matrix\n")
+	header, sep := "| Selector |", "|----------|"
+	for _, r := range reports {
+		header += fmt.Sprintf(" %s |", r.Device)
+		sep += "-----|"
+	}
+	fmt.Fprintf(f, "%s\n%s\n", header, sep)
+	for _, label := range labels {
+		row := "| " + label + " |"
+		for _, r := range reports {
+			st := status[label][r.Device]
+			if st == "" {
+				st = "–"
+			}
+			row += " " + st + " |"
+		}
+		fmt.Fprintf(f, "%s\n", row)
+	}
+	score := "| **Score** |"
+	for _, r := range reports {
+		score += fmt.Sprintf(" **%.1f** |", r.Score)
+	}
+	fmt.Fprintf(f, "%s\n\n", score)
+}
+
+// writeEvidence renders the matched element as a collapsible block below a result
+func writeEvidence(f *os.File, ev *Evidence) {
+	if ev == nil {
+		return
+	}
+
+	element := ev.Tag
+	if ev.ID != "" {
+		element += "#" + ev.ID
+	}
+	for _, c := range ev.Classes {
+		element += "." + c
+	}
+
+	fmt.Fprintf(f, "  <details><summary>%d × <code>%s</code></summary>\n\n", ev.Count, html.EscapeString(element))
+	fmt.Fprintf(f, "  - Path: `%s`\n", noTicks(ev.Path))
+	if ev.Text != "" {
+		fmt.Fprintf(f, "  - Text: `%s`\n", noTicks(ev.Text))
+	}
+	if ev.OuterHTML != "" {
+		fmt.Fprintf(f, "\n  ```html\n  %s\n  ```\n", strings.ReplaceAll(noTicks(ev.OuterHTML), "\n", "\n  "))
+	}
+	fmt.Fprintf(f, "  </details>\n\n")
+}
+
+// noTicks keeps page content from closing markdown code spans
+func noTicks(s string) string {
+	return strings.ReplaceAll(s, "`", "'")
+}
+
+// LoadJSON reads a full report previously written by SaveJSON
+func LoadJSON(filename string) (FullReport, error) {
+	var report FullReport
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	return report, nil
+}
+
 // SaveMarkdown writes the full report to Markdown
 func SaveMarkdown(report FullReport, filename string) error {
 	f, err := os.Create(filename)
@@ -49,17 +386,28 @@ func SaveMarkdown(report FullReport, filename string) error {
 
 	// Summary Table
 	fmt.Fprintf(f, "# Verification Report\n\n")
+	if report.Offline {
+		fmt.Fprintf(f, "> 📼 Replayed offline from snapshot run `%s`\n\n", report.RunID)
+	}
+	if report.Partial {
+		fmt.Fprintf(f, "> 🕒 Scheduled cycle: only the %d bookie(s) that were due were checked\n\n", len(report.Details))
+	}
+	writeDiff(f, report.Diff)
 	fmt.Fprintf(f, "## 📊 Summary\n")
-	fmt.Fprintf(f, "| Bookie | URL | Status |\n")
-	fmt.Fprintf(f, "|--------|-----|--------|\n")
+	fmt.Fprintf(f, "| Bookie | URL | Status | Score |\n")
+	fmt.Fprintf(f, "|--------|-----|--------|-------|\n")
 	for _, s := range report.Summary {
 		status := "✅"
 		if !s.AllPass {
 			status = "❌"
 		}
-		fmt.Fprintf(f, "| %s | %s | %s |\n", s.Name, s.URL, status)
+		fmt.Fprintf(f, "| %s | %s | %s | %s |\n", s.Name, s.URL, status, ScoreCell(s))
 	}
 
+	writeDriftAlerts(f, report)
+	writeSchemaDrift(f, report)
+	writeFlaky(f, report)
+
 	// Details
 	fmt.Fprintf(f, "\n---\n\n")
 	for _, d := range report.Details {
@@ -69,12 +417,45 @@ func SaveMarkdown(report FullReport, filename string) error {
 		}
 		fmt.Fprintf(f, "## %s (%s)\n", d.Name, d.URL)
 		for _, res := range d.Results {
-			fmt.Fprintf(f, "- %s: %s\n", res.Label, res.Status)
+			line := fmt.Sprintf("- %s: %s", res.Label, res.Status)
+			if res.Locator != "" && res.Locator != "css" {
+				line += fmt.Sprintf(" [%s]", res.Locator)
+			}
+			if res.Message != "" {
+				line += fmt.Sprintf(" (%s)", res.Message)
+			}
+			if res.Frame != "" {
+				line += fmt.Sprintf(" 🖼️ in frame `%s`", res.Frame)
+			}
+			line += stabilityTag(res.Stability, res.FailStreak)
+			fmt.Fprintf(f, "%s\n", line)
+			writeEvidence(f, res.Evidence)
+			for _, sg := range res.Suggestions {
+				fmt.Fprintf(f, "  - 💡 `%s` (confidence %.2f: %s)\n", sg.Selector, sg.Confidence, sg.Reason)
+			}
+		}
+		for _, fr := range d.Frames {
+			fmt.Fprintf(f, "- 🖼️ Frame `%s`: %s\n", fr.Path, fr.URL)
+		}
+		fmt.Fprintf(f, "Overall: %s\n", overall)
+		fmt.Fprintf(f, "Health score: %.1f\n", d.Score)
+		if d.Drift != nil {
+			fmt.Fprintf(f, "Layout: %s (similarity %.2f to previous run)\n", d.Drift.Hash, d.Drift.Similarity)
+		}
+		fmt.Fprintf(f, "\n")
+		if len(d.Variants) > 0 {
+			writeDeviceMatrix(f, d)
+		}
+		if len(d.Sections) > 0 {
+			fmt.Fprintf(f, "| Section | Score | Passed | Failed |\n")
+			fmt.Fprintf(f, "|---------|-------|--------|--------|\n")
+			for _, sec := range d.Sections {
+				fmt.Fprintf(f, "| %s | %.1f | %d | %d |\n", sec.Name, sec.Score, sec.Passed, sec.Failed)
+			}
+			fmt.Fprintf(f, "\n")
 		}
-		fmt.Fprintf(f, "Overall: %s\n\n", overall)
 	}
 
-	fmt.Printf("📄 Saved Markdown report: %s\n", filename)
 	return nil
 }
 
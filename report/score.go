@@ -0,0 +1,88 @@
+package report
+
+import (
+	"math"
+	"strings"
+)
+
+// SeverityWeights is how much a selector of each severity counts towards a
+// bookie's health score. Selectors with unknown or n/a severity are ignored.
+var SeverityWeights = map[string]float64{
+	"critical": 5,
+	"major":    3,
+	"minor":    1,
+}
+
+// SectionScore is the weighted health of one selector section
+type SectionScore struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Passed int     `json:"passed"`
+	Failed int     `json:"failed"`
+}
+
+// ApplyScores computes the weighted health score (0-100) of a bookie and of
+// each of its sections from the selector results. Passing and warning
+// results count as healthy. A bookie whose page failed to load scores 0,
+// whatever its API probes did.
+func (b *BookieReport) ApplyScores() {
+	var earned, total float64
+	unreachable := false
+	var sections []SectionScore
+	index := map[string]int{}
+	sectionWeights := map[string][2]float64{}
+
+	for _, r := range b.Results {
+		if r.Label == FetchErrorLabel {
+			unreachable = true
+		}
+		w := SeverityWeights[r.Severity]
+		if w == 0 {
+			continue
+		}
+		name := strings.SplitN(r.Label, ".", 2)[0]
+		i, ok := index[name]
+		if !ok {
+			i = len(sections)
+			index[name] = i
+			sections = append(sections, SectionScore{Name: name})
+		}
+
+		sw := sectionWeights[name]
+		sw[1] += w
+		total += w
+		if r.Healthy() {
+			sw[0] += w
+			earned += w
+			sections[i].Passed++
+		} else {
+			sections[i].Failed++
+		}
+		sectionWeights[name] = sw
+	}
+
+	for i := range sections {
+		sw := sectionWeights[sections[i].Name]
+		sections[i].Score = percent(sw[0], sw[1])
+	}
+
+	b.Score = percent(earned, total)
+	if unreachable {
+		b.Score = 0
+	}
+	b.Sections = sections
+}
+
+// Healthy reports whether a selector result counts as passing
+func (r SelectorResult) Healthy() bool {
+	return r.Status == "✅" || r.Status == "⚠️"
+}
+
+// percent returns earned/total as a percentage rounded to one decimal;
+// nothing to check counts as fully healthy
+func percent(earned, total float64) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Round(earned/total*1000) / 10
+}
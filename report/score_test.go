@@ -0,0 +1,63 @@
+package report
+
+import "testing"
+
+func TestApplyScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []SelectorResult
+		want    float64
+	}{
+		{"nothing to check", nil, 100},
+		{"all pass", []SelectorResult{
+			{Label: "Login.UsernameInput", Status: "✅", Severity: "critical"},
+			{Label: "Login.LoginButton", Status: "⚠️", Severity: "major"},
+		}, 100},
+		{"weighted", []SelectorResult{
+			{Label: "Login.UsernameInput", Status: "✅", Severity: "critical"},
+			{Label: "Login.LoginButton", Status: "❌", Severity: "major"},
+			{Label: "Footer.Logo", Status: "❌", Severity: "minor"},
+			{Label: "Footer.Links", Status: "➖", Severity: "n/a"},
+		}, 55.6},
+		// Regression: the fetch error carries no severity, which left nothing
+		// weighted to check and scored an unreachable bookie 100
+		{"unreachable", []SelectorResult{
+			{Label: FetchErrorLabel, Status: "received HTTP 503"},
+		}, 0},
+		{"unreachable with passing probes", []SelectorResult{
+			{Label: FetchErrorLabel, Status: "received HTTP 503"},
+			{Label: "API.events", Status: "✅", Severity: "major"},
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := BookieReport{Name: "betway", Results: tt.results}
+			b.ApplyScores()
+			if b.Score != tt.want {
+				t.Errorf("Score = %v, want %v", b.Score, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyScoresSections(t *testing.T) {
+	b := BookieReport{Results: []SelectorResult{
+		{Label: "Login.UsernameInput", Status: "✅", Severity: "critical"},
+		{Label: "Login.LoginButton", Status: "❌", Severity: "major"},
+		{Label: "API.events", Status: "✅", Severity: "major"},
+	}}
+	b.ApplyScores()
+
+	want := []SectionScore{
+		{Name: "Login", Score: 62.5, Passed: 1, Failed: 1},
+		{Name: "API", Score: 100, Passed: 1},
+	}
+	if len(b.Sections) != len(want) {
+		t.Fatalf("Sections = %+v", b.Sections)
+	}
+	for i, s := range want {
+		if b.Sections[i] != s {
+			t.Errorf("section %d = %+v, want %+v", i, b.Sections[i], s)
+		}
+	}
+}
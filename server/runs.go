@@ -0,0 +1,164 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"diago/report"
+)
+
+// Event types streamed while a run verifies
+const (
+	EventRunStarted     = "run_started"
+	EventBookieStarted  = "bookie_started"
+	EventSelectorResult = "selector_result"
+	EventBookieFinished = "bookie_finished"
+	EventRunFinished    = "run_finished"
+	EventRunFailed      = "run_failed"
+)
+
+// Run statuses
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Event is one progress update of a run. Bookies are named by their report
+// key, e.g. betway@mobile.
+type Event struct {
+	Type    string                 `json:"type"`
+	RunID   string                 `json:"run_id"`
+	At      time.Time              `json:"at"`
+	Bookie  string                 `json:"bookie,omitempty"`
+	Result  *report.SelectorResult `json:"result,omitempty"`
+	Summary *BookieSummary         `json:"summary,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// BookieSummary is the outcome of a bookie sent when it finishes
+type BookieSummary struct {
+	AllPass    bool    `json:"all_pass"`
+	Score      float64 `json:"score"`
+	HTTPStatus int     `json:"http_status,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Summarize builds the summary of a finished bookie
+func Summarize(b report.BookieReport) *BookieSummary {
+	return &BookieSummary{AllPass: b.AllPass, Score: b.Score, HTTPStatus: b.HTTPStatus, DurationMS: b.DurationMS}
+}
+
+// run is a verification triggered through the API, with the events it
+// published so far for late subscribers
+type run struct {
+	mu         sync.Mutex
+	id         string
+	status     string
+	bookies    []string
+	startedAt  time.Time
+	finishedAt time.Time
+	err        string
+	report     *report.FullReport
+	events     []Event
+	subs       map[chan Event]bool
+}
+
+// runView is how a run is rendered in responses
+type runView struct {
+	ID         string             `json:"id"`
+	Status     string             `json:"status"`
+	Bookies    []string           `json:"bookies"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Events     string             `json:"events,omitempty"`
+	Report     *report.FullReport `json:"report,omitempty"`
+	Diff       *report.Diff       `json:"diff,omitempty"`
+}
+
+func newRun(id string, bookies []string) *run {
+	return &run{id: id, status: StatusRunning, bookies: bookies, startedAt: time.Now().UTC(), subs: map[chan Event]bool{}}
+}
+
+func (r *run) view(withReport bool) runView {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := runView{ID: r.id, Status: r.status, Bookies: r.bookies, StartedAt: r.startedAt, Error: r.err, Events: "/api/runs/" + r.id + "/events"}
+	if !r.finishedAt.IsZero() {
+		at := r.finishedAt
+		v.FinishedAt = &at
+	}
+	if withReport && r.report != nil {
+		v.Report, v.Diff = r.report, r.report.Diff
+	}
+	return v
+}
+
+// publish records an event and sends it to the subscribers. A subscriber
+// too slow to keep up is dropped rather than holding up the run.
+func (r *run) publish(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.RunID, e.At = r.id, time.Now().UTC()
+	r.events = append(r.events, e)
+	for ch := range r.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(r.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the events so far and, while the run is going, a
+// channel of the ones to come that is closed when it ends
+func (r *run) subscribe() ([]Event, chan Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	backlog := append([]Event{}, r.events...)
+	if r.status != StatusRunning {
+		return backlog, nil
+	}
+	ch := make(chan Event, 256)
+	r.subs[ch] = true
+	return backlog, ch
+}
+
+func (r *run) unsubscribe(ch chan Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.subs[ch] {
+		delete(r.subs, ch)
+		close(ch)
+	}
+}
+
+// finish records the outcome, publishes the last event and ends the streams
+func (r *run) finish(rep *report.FullReport, err error) {
+	if err != nil {
+		r.publish(Event{Type: EventRunFailed, Error: err.Error()})
+	} else {
+		r.publish(Event{Type: EventRunFinished})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finishedAt = time.Now().UTC()
+	r.report = rep
+	r.status = StatusDone
+	if err != nil {
+		r.status, r.err = StatusFailed, err.Error()
+	}
+	for ch := range r.subs {
+		close(ch)
+	}
+	r.subs = map[chan Event]bool{}
+}
+
+func (r *run) running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status == StatusRunning
+}
@@ -0,0 +1,538 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"diago/config"
+	"diago/history"
+	"diago/report"
+	"diago/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunFunc verifies the given bookies with the same engine as the CLI,
+// publishing progress as it goes, and returns the report
+type RunFunc func(ctx context.Context, bookies []string, progress func(Event)) (report.FullReport, error)
+
+// Options configure a server
+type Options struct {
+	Settings  config.ServerSettings
+	OutputDir string
+	// Bookies reads the enabled bookies from the manifest
+	Bookies func() ([]utils.Bookie, error)
+	Run     RunFunc
+}
+
+// Server is the HTTP API: bookies, configs, runs with their progress
+// streamed over Server-Sent Events, and the run history
+type Server struct {
+	opts   Options
+	tokens [][]byte
+
+	mu    sync.Mutex
+	runs  map[string]*run
+	order []string
+	seq   int
+
+	// ctx is cancelled on shutdown, which also ends the event streams
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// errorBody is the JSON of every error response
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// New creates a server. It refuses to run without a token, since anyone
+// who can reach it could otherwise trigger runs.
+func New(opts Options) (*Server, error) {
+	s := &Server{opts: opts, runs: map[string]*run{}}
+	for _, t := range opts.Settings.Tokens {
+		if t = os.ExpandEnv(t); t != "" {
+			s.tokens = append(s.tokens, []byte(t))
+		}
+	}
+	if len(s.tokens) == 0 {
+		return nil, errors.New("no API tokens configured (server.tokens)")
+	}
+	if s.opts.Settings.MaxRuns <= 0 {
+		s.opts.Settings.MaxRuns = 1
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+// Handler routes the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte("ok\n")) })
+	mux.Handle("GET /api/bookies", s.auth(s.listBookies))
+	mux.Handle("GET /api/bookies/{name}/config", s.auth(s.getConfig))
+	mux.Handle("POST /api/runs", s.auth(s.startRun))
+	mux.Handle("GET /api/runs", s.auth(s.listRuns))
+	mux.Handle("GET /api/runs/{id}", s.auth(s.getRun))
+	mux.Handle("GET /api/runs/{id}/events", s.auth(s.streamEvents))
+	mux.Handle("GET /api/history", s.auth(s.getHistory))
+	return mux
+}
+
+// ListenAndServe serves until ctx is done, then stops accepting requests,
+// ends the event streams and waits for running verifications to finish
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{Addr: s.opts.Settings.Listen, Handler: s.Handler()}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	s.cancel()
+	shutdown, done := context.WithTimeout(context.Background(), 10*time.Second)
+	defer done()
+	err := srv.Shutdown(shutdown)
+	s.wg.Wait()
+	return err
+}
+
+// auth accepts a bearer token, or a token query parameter for browsers'
+// EventSource, which cannot set headers
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = req.URL.Query().Get("token")
+		}
+		for _, t := range s.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+				next(w, req)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="diago"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+	})
+}
+
+// bookieView is an enabled bookie and whether it has a config
+type bookieView struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Config bool   `json:"config"`
+}
+
+func (s *Server) listBookies(w http.ResponseWriter, _ *http.Request) {
+	bookies, err := s.opts.Bookies()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	views := []bookieView{}
+	for _, b := range bookies {
+		v := bookieView{Name: b.Name(), URL: b.URL()}
+		if cfg, err := config.Load(s.configPath(b.Name())); err == nil {
+			v.Config = true
+			if cfg.BaseURL != "" {
+				v.URL = cfg.BaseURL
+			}
+		}
+		views = append(views, v)
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// getConfig serves a bookie's config with credentials, headers and probe bodies redacted
+func (s *Server) getConfig(w http.ResponseWriter, req *http.Request) {
+	name, ok := s.resolve(req.PathValue("name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("bookie %s is not enabled", req.PathValue("name")))
+		return
+	}
+	data, err := os.ReadFile(s.configPath(name))
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no config for %s", name))
+		return
+	}
+	var cfg any
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, redact(cfg))
+}
+
+// runRequest is the body of POST /api/runs; no bookies runs all of them
+type runRequest struct {
+	Bookies []string `json:"bookies"`
+}
+
+func (s *Server) startRun(w http.ResponseWriter, req *http.Request) {
+	var body runRequest
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+	}
+
+	var names []string
+	if len(body.Bookies) == 0 {
+		bookies, err := s.opts.Bookies()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, b := range bookies {
+			names = append(names, b.Name())
+		}
+	}
+	for _, requested := range body.Bookies {
+		name, ok := s.resolve(requested)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("bookie %s is not enabled", requested))
+			return
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	s.mu.Lock()
+	active := 0
+	for _, r := range s.runs {
+		if !r.running() {
+			continue
+		}
+		active++
+		for _, name := range names {
+			if slices.Contains(r.bookies, name) {
+				s.mu.Unlock()
+				writeError(w, http.StatusConflict, fmt.Sprintf("%s is already being verified by run %s", name, r.id))
+				return
+			}
+		}
+	}
+	if active >= s.opts.Settings.MaxRuns {
+		s.mu.Unlock()
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%d run(s) already in progress", active))
+		return
+	}
+	s.seq++
+	r := newRun(fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102-150405"), s.seq), names)
+	s.runs[r.id] = r
+	s.order = append(s.order, r.id)
+	s.prune()
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.execute(r)
+
+	w.Header().Set("Location", "/api/runs/"+r.id)
+	writeJSON(w, http.StatusAccepted, r.view(false))
+}
+
+// execute verifies a run's bookies; a panic fails the run instead of the server
+func (s *Server) execute(r *run) {
+	defer s.wg.Done()
+	var rep report.FullReport
+	var err error
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("run panicked: %v", p)
+		}
+		if err != nil {
+			r.finish(nil, err)
+			return
+		}
+		r.finish(&rep, nil)
+	}()
+
+	r.publish(Event{Type: EventRunStarted})
+	rep, err = s.opts.Run(s.ctx, r.bookies, r.publish)
+}
+
+// prune drops the oldest finished runs beyond KeepRuns
+func (s *Server) prune() {
+	keep := s.opts.Settings.KeepRuns
+	for i := 0; len(s.order) > keep && keep > 0 && i < len(s.order); {
+		id := s.order[i]
+		if s.runs[id].running() {
+			i++
+			continue
+		}
+		delete(s.runs, id)
+		s.order = append(s.order[:i], s.order[i+1:]...)
+	}
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	views := []runView{}
+	for i := len(s.order) - 1; i >= 0; i-- {
+		views = append(views, s.runs[s.order[i]].view(false))
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, views)
+}
+
+// getRun serves a run started through the API, or else a run of the history
+func (s *Server) getRun(w http.ResponseWriter, req *http.Request) {
+	id := req.PathValue("id")
+	if r := s.lookup(id); r != nil {
+		writeJSON(w, http.StatusOK, r.view(true))
+		return
+	}
+
+	runs, err := history.Load(history.Path(s.opts.OutputDir))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if past, ok := history.Find(runs, id); ok {
+		writeJSON(w, http.StatusOK, runView{ID: past.ID, Status: StatusDone, Bookies: keys(past), StartedAt: past.StartedAt, Report: &past.Report})
+		return
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("run %s not found", id))
+}
+
+// streamEvents sends a run's events as Server-Sent Events: the ones so far,
+// then each as it happens, until the run ends
+func (s *Server) streamEvents(w http.ResponseWriter, req *http.Request) {
+	r := s.lookup(req.PathValue("id"))
+	if r == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %s not found", req.PathValue("id")))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	backlog, live := r.subscribe()
+	if live != nil {
+		defer r.unsubscribe(live)
+	}
+	for _, e := range backlog {
+		writeEvent(w, e)
+	}
+	flusher.Flush()
+	if live == nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e, ok := <-live:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+}
+
+// historyRun is one run in the history listing, with each bookie's status
+type historyRun struct {
+	ID        string            `json:"id"`
+	StartedAt time.Time         `json:"started_at"`
+	Commit    string            `json:"commit,omitempty"`
+	Partial   bool              `json:"partial,omitempty"`
+	Bookies   map[string]string `json:"bookies"`
+}
+
+// timeline is the history of one bookie or selector
+type timeline struct {
+	Bookie   string          `json:"bookie"`
+	Selector string          `json:"selector,omitempty"`
+	Stats    history.Stats   `json:"stats"`
+	Points   []history.Point `json:"points"`
+}
+
+// getHistory lists the stored runs, or with ?bookie= (and &selector=) the
+// timeline of one bookie or selector; ?days= limits it to recent runs
+func (s *Server) getHistory(w http.ResponseWriter, req *http.Request) {
+	runs, err := history.Load(history.Path(s.opts.OutputDir))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	q := req.URL.Query()
+	if days := q.Get("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "days must be a positive number")
+			return
+		}
+		runs = history.Since(runs, time.Now().AddDate(0, 0, -n))
+	}
+
+	bookie, selector := q.Get("bookie"), q.Get("selector")
+	if bookie == "" {
+		list := []historyRun{}
+		for i := len(runs) - 1; i >= 0; i-- {
+			h := historyRun{ID: runs[i].ID, StartedAt: runs[i].StartedAt, Commit: runs[i].Commit, Partial: runs[i].Report.Partial, Bookies: map[string]string{}}
+			for key, b := range history.Bookies(runs[i]) {
+				h.Bookies[key] = history.Status(b)
+			}
+			list = append(list, h)
+		}
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	t := timeline{Bookie: bookie, Selector: selector}
+	if selector == "" {
+		t.Points = history.BookieTimeline(runs, bookie)
+	} else {
+		t.Points = history.SelectorTimeline(runs, bookie, selector)
+	}
+	if len(t.Points) == 0 {
+		writeError(w, http.StatusNotFound, "no runs checked "+strings.TrimSpace(bookie+" "+selector))
+		return
+	}
+	t.Stats = history.Summarize(t.Points)
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) lookup(id string) *run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[id]
+}
+
+// resolve finds an enabled bookie by name, ignoring case
+func (s *Server) resolve(name string) (string, bool) {
+	bookies, err := s.opts.Bookies()
+	if err != nil {
+		return "", false
+	}
+	for _, b := range bookies {
+		if strings.EqualFold(b.Name(), name) {
+			return b.Name(), true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) configPath(name string) string {
+	return filepath.Join(s.opts.OutputDir, strings.ToLower(name), "config.yaml")
+}
+
+func keys(run history.Run) []string {
+	var out []string
+	for key := range history.Bookies(run) {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sensitiveKeys are config keys whose values are never served, matched
+// case-insensitively anywhere in the key
+var sensitiveKeys = []string{"password", "secret", "token", "auth", "cookie", "key"}
+
+// opaqueKeys hold values that are redacted whole: header maps carry
+// credentials under any name, and probe bodies may embed them
+var opaqueKeys = map[string]bool{"headers": true, "body": true}
+
+// selectorKeys hold CSS selectors, which are served as they are even
+// under names like password_input
+var selectorKeys = map[string]bool{"selectors": true, "bet_button": true, "bet_history": true}
+
+const redacted = "********"
+
+// redact replaces the values of sensitive keys anywhere in a decoded config
+func redact(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			switch {
+			case selectorKeys[strings.ToLower(k)]:
+				continue
+			case val == nil || val == "":
+			case opaqueKeys[strings.ToLower(k)]:
+				val = redactAll(val)
+			case sensitive(k):
+				val = redacted
+			}
+			t[k] = redact(val)
+		}
+	case []any:
+		for i := range t {
+			t[i] = redact(t[i])
+		}
+	}
+	return v
+}
+
+// redactAll keeps the keys of a map and replaces every value
+func redactAll(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return redacted
+	}
+	for k := range m {
+		m[k] = redacted
+	}
+	return m
+}
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorBody{Error: msg})
+}
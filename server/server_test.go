@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"diago/config"
+	"diago/utils"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// bookie is an enabled bookie that is never verified
+type bookie string
+
+func (b bookie) Name() string                               { return string(b) }
+func (b bookie) URL() string                                { return "https://" + string(b) + ".test" }
+func (b bookie) SetURL(string)                              {}
+func (b bookie) Verify(*goquery.Document) map[string]string { return nil }
+
+const credentialsConfig = `name: Betway
+base_url: https://betway.test
+username: qa@example.com
+password: hunter2
+user_credentials:
+  username: qa@example.com
+  Password: hunter2
+selectors:
+  login:
+    username_input: "#user"
+    password_input: "input[type=password]"
+    login_button:
+      css: "#login"
+      fallbacks: ["[data-testid=auth-submit]"]
+api_probes:
+  - name: events
+    url: "{{base_url}}/api/events"
+    headers: {Authorization: "Bearer xyz", Cookie: "sid=1", X-Api-Key: "abc", Accept: application/json}
+    body: '{"user":"qa","password":"hunter2"}'
+devices:
+  mobile:
+    user_agent: Mozilla/5.0 (iPhone)
+    headers: {X-Auth: "s3cret"}
+`
+
+func testServer(t *testing.T, outputDir string) *Server {
+	t.Helper()
+	s, err := New(Options{
+		Settings:  config.ServerSettings{Tokens: []string{"t0ken"}},
+		OutputDir: outputDir,
+		Bookies:   func() ([]utils.Bookie, error) { return []utils.Bookie{bookie("Betway")}, nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestGetConfigRedactsCredentials(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "betway"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "betway", "config.yaml"), []byte(credentialsConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/bookies/betway/config", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	rec := httptest.NewRecorder()
+	testServer(t, dir).Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	var cfg struct {
+		BaseURL     string            `json:"base_url"`
+		Username    string            `json:"username"`
+		Password    string            `json:"password"`
+		Credentials map[string]string `json:"user_credentials"`
+		Probes      []struct {
+			URL     string            `json:"url"`
+			Headers map[string]string `json:"headers"`
+			Body    string            `json:"body"`
+		} `json:"api_probes"`
+		Devices map[string]struct {
+			UserAgent string            `json:"user_agent"`
+			Headers   map[string]string `json:"headers"`
+		} `json:"devices"`
+		Selectors struct {
+			Login struct {
+				PasswordInput string `json:"password_input"`
+				LoginButton   struct {
+					Fallbacks []string `json:"fallbacks"`
+				} `json:"login_button"`
+			} `json:"login"`
+		} `json:"selectors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	redactedValues := map[string]string{
+		"password":                         cfg.Password,
+		"user_credentials.Password":        cfg.Credentials["Password"],
+		"api_probes.headers.Authorization": cfg.Probes[0].Headers["Authorization"],
+		"api_probes.headers.Cookie":        cfg.Probes[0].Headers["Cookie"],
+		"api_probes.headers.X-Api-Key":     cfg.Probes[0].Headers["X-Api-Key"],
+		"api_probes.headers.Accept":        cfg.Probes[0].Headers["Accept"],
+		"api_probes.body":                  cfg.Probes[0].Body,
+		"devices.mobile.headers.X-Auth":    cfg.Devices["mobile"].Headers["X-Auth"],
+	}
+	for key, v := range redactedValues {
+		if v != redacted {
+			t.Errorf("%s = %q, want it redacted", key, v)
+		}
+	}
+
+	kept := map[string]string{
+		"base_url":                               cfg.BaseURL,
+		"username":                               cfg.Username,
+		"user_credentials.username":              cfg.Credentials["username"],
+		"api_probes.url":                         cfg.Probes[0].URL,
+		"devices.mobile.user_agent":              cfg.Devices["mobile"].UserAgent,
+		"selectors.login.password_input":         cfg.Selectors.Login.PasswordInput,
+		"selectors.login.login_button.fallbacks": strings.Join(cfg.Selectors.Login.LoginButton.Fallbacks, ","),
+	}
+	for key, v := range kept {
+		if v == "" || v == redacted {
+			t.Errorf("%s = %q, want it served", key, v)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"DB_PASSWORD", true},
+		{"client_secret", true},
+		{"Token", true},
+		{"Authorization", true},
+		{"Set-Cookie", true},
+		{"x-api-key", true},
+		{"auth", true},
+		{"username", false},
+		{"base_url", false},
+		// Selectors are served even when named like a credential
+		{"selectors.login.password_input", false},
+		{"devices.mobile.overrides.selectors.login.password_input", false},
+		{"bet_button", false},
+	}
+	for _, tt := range tests {
+		// Nest dotted keys the way they appear in a config
+		path := strings.Split(tt.key, ".")
+		m := map[string]any{path[len(path)-1]: "value"}
+		leaf := m
+		for i := len(path) - 2; i >= 0; i-- {
+			m = map[string]any{path[i]: m}
+		}
+		redact(m)
+		if got := leaf[path[len(path)-1]] == redacted; got != tt.want {
+			t.Errorf("%s redacted = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
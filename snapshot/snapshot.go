@@ -0,0 +1,166 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"diago/fetch"
+)
+
+// RunIDFormat names snapshot runs so they sort chronologically. The
+// milliseconds keep runs started in the same second apart; ids parse with
+// or without them.
+const RunIDFormat = "20060102-150405.000"
+
+// Meta is the response information stored next to a page's HTML
+type Meta struct {
+	URL       string      `json:"url"`
+	FinalURL  string      `json:"final_url"`
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	FetchedAt time.Time   `json:"fetched_at"`
+}
+
+// Dir returns where snapshots are stored below the output dir
+func Dir(outputDir string) string {
+	return filepath.Join(outputDir, "snapshots")
+}
+
+var (
+	runIDMu   sync.Mutex
+	lastRunAt time.Time
+)
+
+// NewRunID returns the id of a run started now, unique within the process
+func NewRunID() string {
+	runIDMu.Lock()
+	defer runIDMu.Unlock()
+	at := time.Now().UTC().Truncate(time.Millisecond)
+	if !at.After(lastRunAt) {
+		at = lastRunAt.Add(time.Millisecond)
+	}
+	lastRunAt = at
+	return at.Format(RunIDFormat)
+}
+
+// runTime parses when a run started from its id
+func runTime(runID string) (time.Time, error) {
+	// The seconds layout also accepts the milliseconds of newer ids
+	return time.Parse("20060102-150405", runID)
+}
+
+// bookieDir is <dir>/<run>/<bookie>
+func bookieDir(dir, runID, bookie string) string {
+	return filepath.Join(dir, runID, strings.ToLower(bookie))
+}
+
+// Save writes a page's HTML and response metadata for one bookie of a run
+func Save(dir, runID, bookie string, page *fetch.Page) error {
+	target := bookieDir(dir, runID, bookie)
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(target, "page.html"), page.HTML, 0644); err != nil {
+		return err
+	}
+
+	meta := Meta{
+		URL:       page.URL,
+		FinalURL:  page.FinalURL,
+		Status:    page.Status,
+		Header:    page.Header,
+		FetchedAt: time.Now().UTC(),
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(target, "meta.json"), data, 0644)
+}
+
+// Load reads a stored page of one bookie of a run
+func Load(dir, runID, bookie string) (*fetch.Page, error) {
+	target := bookieDir(dir, runID, bookie)
+	body, err := os.ReadFile(filepath.Join(target, "page.html"))
+	if err != nil {
+		return nil, fmt.Errorf("no snapshot of %s in run %s: %w", bookie, runID, err)
+	}
+
+	var meta Meta
+	data, err := os.ReadFile(filepath.Join(target, "meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("no snapshot metadata of %s in run %s: %w", bookie, runID, err)
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot metadata of %s: %w", bookie, err)
+	}
+
+	return fetch.NewPage(meta.URL, meta.FinalURL, meta.Status, meta.Header, body)
+}
+
+// Runs lists the stored run ids, oldest first
+func Runs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			runs = append(runs, e.Name())
+		}
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Latest returns the id of the most recent run
+func Latest(dir string) (string, error) {
+	runs, err := Runs(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(runs) == 0 {
+		return "", fmt.Errorf("no snapshots in %s", dir)
+	}
+	return runs[len(runs)-1], nil
+}
+
+// Prune deletes runs beyond the newest keep and runs older than maxAge.
+// Zero disables either limit. It returns the deleted run ids.
+func Prune(dir string, keep int, maxAge time.Duration) ([]string, error) {
+	runs, err := Runs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for i, run := range runs {
+		expired := false
+		if keep > 0 && i < len(runs)-keep {
+			expired = true
+		}
+		if t, err := runTime(run); err == nil && maxAge > 0 && time.Since(t) > maxAge {
+			expired = true
+		}
+		if !expired {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, run)); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, run)
+	}
+	return deleted, nil
+}
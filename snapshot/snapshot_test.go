@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Runs started in the same second must not share a snapshot dir or history id
+func TestNewRunIDUnique(t *testing.T) {
+	seen := map[string]bool{}
+	var ids []string
+	for range 500 {
+		id := NewRunID()
+		if seen[id] {
+			t.Fatalf("duplicate run id %s", id)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("ids do not sort in the order they were issued: %v", ids)
+	}
+	if _, err := runTime(ids[0]); err != nil {
+		t.Errorf("run id %s does not parse: %v", ids[0], err)
+	}
+}
+
+// Ids from before milliseconds were added sort and expire with the new ones
+func TestPruneMixedIDs(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	old := now.Add(-30 * 24 * time.Hour)
+	runs := []string{
+		old.Format("20060102-150405"),
+		old.Add(time.Second).Format(RunIDFormat),
+		now.Add(-time.Minute).Format("20060102-150405"),
+		now.Format(RunIDFormat),
+	}
+	for _, run := range runs {
+		if err := os.MkdirAll(filepath.Join(dir, run), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := Prune(dir, 0, 14*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deleted, runs[:2]) {
+		t.Errorf("deleted %v, want %v", deleted, runs[:2])
+	}
+	if latest, _ := Latest(dir); latest != runs[3] {
+		t.Errorf("Latest = %s, want %s", latest, runs[3])
+	}
+}
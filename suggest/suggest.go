@@ -0,0 +1,464 @@
+package suggest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"diago/lint"
+	"diago/locator"
+	"diago/report"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// MaxSuggestions is the number of candidates returned per selector
+const MaxSuggestions = 3
+
+// minConfidence drops candidates that are barely better than noise
+const minConfidence = 0.25
+
+// synonyms expands label words into the terms bookies actually use,
+// including Swahili labels common on Kenyan sites
+var synonyms = map[string][]string{
+	"login":        {"login", "log in", "sign in", "signin", "ingia"},
+	"logout":       {"logout", "log out", "sign out", "signout", "toka", "ondoka"},
+	"username":     {"username", "user", "phone", "mobile", "msisdn", "email", "login", "namba ya simu"},
+	"password":     {"password", "pass", "pwd", "pin", "nenosiri"},
+	"otp":          {"otp", "code", "verification", "verify", "token"},
+	"email":        {"email", "e-mail", "mail"},
+	"search":       {"search", "find", "tafuta"},
+	"sport":        {"sport", "sports", "michezo"},
+	"date":         {"date", "day", "tarehe"},
+	"event":        {"event", "match", "game", "fixture", "mechi"},
+	"team":         {"team", "competitor", "participant", "timu"},
+	"stake":        {"stake", "amount", "kiasi", "wager"},
+	"payout":       {"payout", "win", "return", "possible", "potential", "ushindi"},
+	"bet":          {"bet", "place bet", "bet now", "weka bet", "betslip", "slip"},
+	"balance":      {"balance", "salio", "wallet", "funds"},
+	"deposit":      {"deposit", "weka pesa", "topup", "top up"},
+	"withdrawal":   {"withdrawal", "withdraw", "toa pesa"},
+	"promo":        {"promo", "promotion", "bonus", "offer", "code", "voucher"},
+	"cash":         {"cashout", "cash out", "cash-out"},
+	"live":         {"live", "in-play", "inplay", "moja kwa moja"},
+	"odds":         {"odds", "odd", "price", "market", "outcome"},
+	"history":      {"history", "my bets", "bets", "historia"},
+	"notification": {"notification", "alert", "toast", "message"},
+	"account":      {"account", "profile", "my account", "akaunti"},
+	"save":         {"save", "update", "submit", "hifadhi"},
+	"confirm":      {"confirm", "accept", "place", "thibitisha"},
+	"close":        {"close", "dismiss", "funga", "×"},
+	"clear":        {"clear", "remove all", "reset", "futa"},
+	"filter":       {"filter", "sort", "chuja"},
+	"submit":       {"submit", "continue", "verify", "send", "tuma"},
+}
+
+// expectation is what a selector label implies about the element it targets
+type expectation struct {
+	tags      []string
+	inputType string
+
+	// keywords come from the field name, context from its section; a
+	// context match alone counts for half
+	keywords []string
+	context  []string
+
+	// many is set for containers and list items, which may legitimately
+	// match more than one element
+	many bool
+}
+
+// Candidates searches the document for elements that could replace the
+// selector behind label and returns the best ones, most confident first.
+// Siblings are elements already matched by other selectors of the same
+// section; candidates close to them rank higher.
+func Candidates(doc *goquery.Document, label string, siblings []*html.Node) []report.Suggestion {
+	exp := expect(label)
+
+	type scored struct {
+		node    *html.Node
+		score   float64
+		reasons []string
+	}
+	var found []scored
+
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		n := s.Get(0)
+		if skipElement(n) {
+			return
+		}
+		score, reasons := scoreElement(s, exp, siblings)
+		if score >= minConfidence {
+			found = append(found, scored{n, score, reasons})
+		}
+	})
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+
+	var out []report.Suggestion
+	seen := map[string]bool{}
+	for _, c := range found {
+		sel := buildSelector(doc, c.node, exp.many)
+		if sel == "" || seen[sel] {
+			continue
+		}
+		seen[sel] = true
+		out = append(out, report.Suggestion{
+			Selector:   sel,
+			Confidence: math.Round(c.score*100) / 100,
+			Reason:     strings.Join(c.reasons, ", "),
+		})
+		if len(out) == MaxSuggestions {
+			break
+		}
+	}
+	return out
+}
+
+// expect derives the expected element kind and keywords from a label such
+// as "Login.PasswordInput"
+func expect(label string) expectation {
+	parts := strings.Split(label, ".")
+	field := parts[len(parts)-1]
+
+	var exp expectation
+	switch {
+	case strings.HasSuffix(field, "Input"):
+		exp.tags = []string{"input", "textarea"}
+	case strings.HasSuffix(field, "Button"):
+		exp.tags = []string{"button", "a", "input"}
+	case strings.HasSuffix(field, "Link"):
+		exp.tags = []string{"a"}
+	case strings.HasSuffix(field, "Dropdown"), strings.HasPrefix(field, "FilterBy"):
+		exp.tags = []string{"select"}
+	case strings.HasSuffix(field, "Picker"):
+		exp.tags = []string{"input"}
+	default:
+		exp.many = true
+	}
+
+	lower := strings.ToLower(field)
+	switch {
+	case strings.Contains(lower, "password"):
+		exp.inputType = "password"
+	case strings.Contains(lower, "email"):
+		exp.inputType = "email"
+	case strings.Contains(lower, "date"):
+		exp.inputType = "date"
+	}
+
+	exp.keywords = expandWords(splitWords(field), nil)
+	if label == "BetButton" {
+		exp.keywords = append(exp.keywords, "place bet")
+	}
+	if len(parts) > 1 {
+		exp.context = expandWords(splitWords(parts[0]), exp.keywords)
+	}
+	return exp
+}
+
+// expandWords adds synonyms to the meaningful words of a label, skipping
+// generic suffixes and anything in exclude
+func expandWords(words, exclude []string) []string {
+	set := map[string]bool{}
+	for _, w := range words {
+		switch w {
+		case "input", "button", "field", "selector", "column", "item", "link", "container", "page", "indicator":
+			continue
+		}
+		set[w] = true
+		for _, syn := range synonyms[w] {
+			set[syn] = true
+		}
+	}
+
+	var out []string
+	for w := range set {
+		if !contains(exclude, w) {
+			out = append(out, w)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// scoreElement rates how well an element fits the expectation (0-1)
+func scoreElement(s *goquery.Selection, exp expectation, siblings []*html.Node) (float64, []string) {
+	tag := goquery.NodeName(s)
+	inputType := strings.ToLower(s.AttrOr("type", "text"))
+
+	var score float64
+	var reasons []string
+
+	if len(exp.tags) > 0 {
+		if !contains(exp.tags, tag) {
+			return 0, nil
+		}
+		if tag == "input" && contains(exp.tags, "button") && inputType != "submit" && inputType != "button" {
+			return 0, nil
+		}
+		score += 0.2
+		reasons = append(reasons, "<"+tag+">")
+	}
+
+	if exp.inputType != "" && tag == "input" {
+		if inputType == exp.inputType {
+			score += 0.3
+			reasons = append(reasons, "type="+inputType)
+		} else if exp.inputType == "password" {
+			return 0, nil
+		}
+	}
+
+	// Attribute similarity: ids, names and test hooks are the strongest hints
+	attrWeights := []struct {
+		name   string
+		weight float64
+	}{
+		{"id", 0.25}, {"name", 0.25}, {"data-testid", 0.25}, {"data-test", 0.2}, {"data-qa", 0.2},
+		{"aria-label", 0.25}, {"placeholder", 0.2}, {"title", 0.15}, {"class", 0.15}, {"href", 0.1},
+	}
+	var attrScore float64
+	for _, a := range attrWeights {
+		value, ok := s.Attr(a.name)
+		if !ok || value == "" {
+			continue
+		}
+		if kw := matchKeyword(value, exp.keywords); kw != "" {
+			attrScore += a.weight
+			reasons = append(reasons, fmt.Sprintf("%s~%q", a.name, kw))
+		} else if kw := matchKeyword(value, exp.context); kw != "" {
+			attrScore += a.weight / 2
+			reasons = append(reasons, fmt.Sprintf("%s~%q (section)", a.name, kw))
+		}
+	}
+	score += math.Min(attrScore, 0.4)
+
+	// Visible text matters for buttons and links ("Login", "Ingia", "Place Bet")
+	if tag == "button" || tag == "a" || s.AttrOr("role", "") == "button" {
+		text := strings.ToLower(strings.Join(strings.Fields(s.Text()), " "))
+		if text != "" && len(text) <= 40 {
+			if contains(exp.keywords, text) {
+				score += 0.35
+				reasons = append(reasons, fmt.Sprintf("text=%q", text))
+			} else if kw := matchKeyword(text, exp.keywords); kw != "" {
+				score += 0.2
+				reasons = append(reasons, fmt.Sprintf("text~%q", kw))
+			} else if kw := matchKeyword(text, exp.context); kw != "" {
+				score += 0.1
+				reasons = append(reasons, fmt.Sprintf("text~%q (section)", kw))
+			}
+		}
+	}
+
+	// The tag alone is not evidence; require a type, attribute or text hint
+	if len(reasons) <= 1 && len(exp.tags) > 0 {
+		return 0, nil
+	}
+
+	if len(siblings) > 0 {
+		if near(s.Get(0), siblings) {
+			score += 0.15
+			reasons = append(reasons, "near sibling fields")
+		}
+	}
+
+	return math.Min(score, 1), reasons
+}
+
+// matchKeyword returns the first keyword contained in value's words
+func matchKeyword(value string, keywords []string) string {
+	normalized := " " + strings.Join(splitWords(value), " ") + " "
+	for _, kw := range keywords {
+		if strings.Contains(normalized, " "+strings.Join(splitWords(kw), " ")+" ") {
+			return kw
+		}
+	}
+	return ""
+}
+
+// near reports whether n shares a form, or an ancestor at most three levels
+// up, with any of the sibling elements
+func near(n *html.Node, siblings []*html.Node) bool {
+	form := closest(n, "form")
+	local := map[*html.Node]bool{}
+	for _, p := range ancestors(n, 3) {
+		local[p] = true
+	}
+
+	for _, sib := range siblings {
+		if form != nil && closest(sib, "form") == form {
+			return true
+		}
+		for _, p := range ancestors(sib, 3) {
+			if local[p] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ancestors returns up to depth element ancestors of n, nearest first
+func ancestors(n *html.Node, depth int) []*html.Node {
+	var out []*html.Node
+	for p := n.Parent; p != nil && len(out) < depth; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data != "body" && p.Data != "html" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// closest returns the nearest ancestor element with the given tag
+func closest(n *html.Node, tag string) *html.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == tag {
+			return p
+		}
+	}
+	return nil
+}
+
+// skipElement drops elements that can never be a useful target
+func skipElement(n *html.Node) bool {
+	switch n.Data {
+	case "html", "head", "body", "script", "style", "noscript", "template", "meta", "link", "svg", "path":
+		return true
+	}
+	for _, a := range n.Attr {
+		if a.Key == "type" && strings.EqualFold(a.Val, "hidden") {
+			return true
+		}
+	}
+	return false
+}
+
+// stable reports whether an id or class looks hand-written rather than generated
+func stable(token string) bool {
+	return token != "" && !lint.Hashed(token)
+}
+
+// BuildSelector returns a CSS selector that uniquely identifies n, preferring
+// ids, names and test attributes over classes and structure. It returns an
+// empty string when no unique selector can be found.
+func BuildSelector(doc *goquery.Document, n *html.Node) string {
+	return buildSelector(doc, n, false)
+}
+
+// maxListMatches caps how many elements a list selector may match
+const maxListMatches = 200
+
+// buildSelector is BuildSelector with an option to accept selectors that
+// match n among other elements, as needed for list items like event rows
+func buildSelector(doc *goquery.Document, n *html.Node, many bool) string {
+	s := goquery.NewDocumentFromNode(n).Selection
+	tag := n.Data
+
+	var candidates []string
+	if id := s.AttrOr("id", ""); stable(id) {
+		candidates = append(candidates, "#"+locator.CSSIdent(id), tag+"#"+locator.CSSIdent(id))
+	}
+	for _, attr := range []string{"name", "data-testid", "data-test", "data-qa", "aria-label", "placeholder"} {
+		if v, ok := s.Attr(attr); ok && v != "" {
+			candidates = append(candidates, tag+"["+attr+"="+locator.CSSString(v)+"]")
+		}
+	}
+	if t, ok := s.Attr("type"); ok && tag == "input" {
+		candidates = append(candidates, "input[type="+locator.CSSString(t)+"]")
+	}
+	var classes []string
+	for _, c := range strings.Fields(s.AttrOr("class", "")) {
+		if stable(c) {
+			classes = append(classes, "."+locator.CSSIdent(c))
+		}
+	}
+	if len(classes) > 0 {
+		candidates = append(candidates, tag+classes[0], tag+strings.Join(classes, ""))
+	}
+
+	for _, c := range candidates {
+		if unique(doc, c, n) {
+			return c
+		}
+	}
+	if many {
+		for _, c := range candidates {
+			if m := doc.Find(c); m.Length() <= maxListMatches && m.IndexOfNode(n) >= 0 {
+				return c
+			}
+		}
+	}
+
+	// Scope by the closest ancestor with a stable id
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type != html.ElementNode {
+			continue
+		}
+		id := attr(p, "id")
+		if !stable(id) {
+			continue
+		}
+		for _, c := range append(candidates, tag) {
+			scoped := "#" + locator.CSSIdent(id) + " " + c
+			if unique(doc, scoped, n) {
+				return scoped
+			}
+		}
+		break
+	}
+	return ""
+}
+
+// unique reports whether sel matches exactly the node n
+func unique(doc *goquery.Document, sel string, n *html.Node) bool {
+	m := doc.Find(sel)
+	return m.Length() == 1 && m.Get(0) == n
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// splitWords splits camelCase, kebab-case and snake_case into lowercase words
+func splitWords(s string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	return words
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package suggest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+const loginPage = `<html><body>
+<header id="header">
+  <a href="/register" class="btn">Register</a>
+  <button class="sc-bdVaJa">Log in now</button>
+</header>
+<form id="login">
+  <input id="q" name="search" placeholder="Search events">
+  <input id="phone" name="msisdn" type="tel" placeholder="Namba ya simu">
+  <input class="a1b2c3 pin-field" type="password">
+  <button class="css-1q2w3e" type="submit">Ingia</button>
+  <a href="/forgot">Forgot password?</a>
+</form>
+<div class="betslip">
+  <input class="stake" name="stake" type="number">
+  <button data-testid="place-bet">Place Bet</button>
+</div>
+</body></html>`
+
+func parse(t *testing.T, page string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestCandidatesRanking(t *testing.T) {
+	doc := parse(t, loginPage)
+
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"Login.UsernameInput", []string{"#phone"}},
+		{"Login.PasswordInput", []string{`input[type="password"]`}},
+		// The exact "Ingia" text outranks the header's "Log in now"; both
+		// only have hashed classes and are scoped by a stable ancestor id
+		{"Login.LoginButton", []string{"#login button", "#header button"}},
+		{"BetSlip.StakeInput", []string{`input[name="stake"]`}},
+		{"BetButton", []string{`button[data-testid="place-bet"]`}},
+		{"Session.LogoutButton", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range Candidates(doc, tt.label, nil) {
+			got = append(got, c.Selector)
+			// Hashed classes never end up in a suggestion
+			for _, hashed := range []string{"sc-bdVaJa", "a1b2c3", "css-1q2w3e"} {
+				if strings.Contains(c.Selector, hashed) {
+					t.Errorf("%s: candidate %s uses a hashed class", tt.label, c.Selector)
+				}
+			}
+			if c.Confidence <= 0 || c.Confidence > 1 || c.Reason == "" {
+				t.Errorf("%s: candidate %+v", tt.label, c)
+			}
+		}
+		if len(got) < len(tt.want) || (len(tt.want) == 0 && len(got) > 0) {
+			t.Errorf("%s: candidates %q, want %q first", tt.label, got, tt.want)
+			continue
+		}
+		for i, want := range tt.want {
+			if got[i] != want {
+				t.Errorf("%s: candidates %q, want %q first", tt.label, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// The password field must be a password input, and the confidence orders
+// the candidates
+func TestCandidatesScoreOrder(t *testing.T) {
+	cands := Candidates(parse(t, loginPage), "Login.PasswordInput", nil)
+	if len(cands) != 1 {
+		t.Fatalf("candidates = %+v, want only the password input", cands)
+	}
+
+	cands = Candidates(parse(t, loginPage), "Login.LoginButton", nil)
+	for i := 1; i < len(cands); i++ {
+		if cands[i].Confidence > cands[i-1].Confidence {
+			t.Errorf("candidates out of order: %+v", cands)
+		}
+	}
+}
+
+func TestSiblingsAnchorCandidates(t *testing.T) {
+	const page = `<html><body>
+<div id="promo"><button>Log in</button></div>
+<div id="login"><div class="row"><input id="user" name="phone"></div><div class="row"><button>Log in</button></div></div>
+</body></html>`
+	doc := parse(t, page)
+
+	// Without siblings the two buttons tie and keep document order
+	cands := Candidates(doc, "Login.LoginButton", nil)
+	if len(cands) != 2 || cands[0].Selector != "#promo button" || cands[0].Confidence != cands[1].Confidence {
+		t.Fatalf("candidates = %+v", cands)
+	}
+
+	user := doc.Find("#user").Get(0)
+	cands = Candidates(doc, "Login.LoginButton", []*html.Node{user})
+	if len(cands) != 2 || cands[0].Selector != "#login button" || !strings.Contains(cands[0].Reason, "near sibling fields") {
+		t.Fatalf("candidates = %+v, want the button next to the username first", cands)
+	}
+	if cands[0].Confidence <= cands[1].Confidence {
+		t.Errorf("anchored confidence %.2f, want above %.2f", cands[0].Confidence, cands[1].Confidence)
+	}
+}
+
+func TestStable(t *testing.T) {
+	for token, want := range map[string]bool{
+		"":           false,
+		"login":      true,
+		"team1":      true,
+		"market1x2":  true,
+		"sc-bdVaJa":  false,
+		"a1b2c3":     false,
+		"css-1q2w3e": false,
+	} {
+		if got := stable(token); got != want {
+			t.Errorf("stable(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
+
+// Built selectors escape ids and attribute values the way CSS reads them
+func TestBuildSelectorEscapes(t *testing.T) {
+	const page = `<html><body>
+<input id="1x2" class="odds">
+<input name="user&quot;name" class="odds">
+<input placeholder="Parié	sasa" class="odds">
+<input id="market1x2" class="odds">
+</body></html>`
+	doc := parse(t, page)
+
+	want := []string{`#\31 x2`, `input[name="user\"name"]`, `input[placeholder="Parié\9 sasa"]`, "#market1x2"}
+	doc.Find("input").Each(func(i int, s *goquery.Selection) {
+		sel := BuildSelector(doc, s.Get(0))
+		if sel != want[i] {
+			t.Errorf("input %d: selector %s, want %s", i, sel, want[i])
+		}
+		if m := doc.Find(sel); m.Length() != 1 || m.Get(0) != s.Get(0) {
+			t.Errorf("input %d: %s matches %d elements", i, sel, m.Length())
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"diago/config"
+	"diago/report"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPath is where the config of a bookie lives in the output directory
+func ConfigPath(outputDir, bookie string) string {
+	return filepath.Join(outputDir, strings.ToLower(bookie), "config.yaml")
+}
+
+// LoadConfig reads the config of a bookie from the output directory
+func LoadConfig(outputDir, bookie string) (*config.Sportsbook, error) {
+	return config.Load(ConfigPath(outputDir, bookie))
+}
+
+// path resolves a file in the output directory
+func (v *Verifier) path(name string) string {
+	return filepath.Join(v.outputDir, name)
+}
+
+// WriteLatestSnippet writes latest_report.md, the summary table of a run
+// for embedding in a README, and returns its path
+func WriteLatestSnippet(fullReport report.FullReport, outputDir string) (string, error) {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	latestMD := filepath.Join(outputDir, "latest_report.md")
+
+	f, err := os.Create(latestMD)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "## 📊 Summary\n")
+	fmt.Fprintf(f, "| Bookie | URL | Status | Score |\n")
+	fmt.Fprintf(f, "|--------|-----|--------|-------|\n")
+	for _, s := range fullReport.Summary {
+		status := "✅"
+		if !s.AllPass {
+			status = "❌"
+		}
+		fmt.Fprintf(f, "| %s | %s | %s | %s |\n", s.Name, s.URL, status, report.ScoreCell(s))
+	}
+
+	fmt.Fprintf(f, "\n_Updated automatically via GitHub Actions_\n")
+	return latestMD, f.Close()
+}
+
+// BakeOverrides merges the overrides into each bookie's config.yaml and
+// renames the overrides file to overrides.baked.yaml, whose path it
+// returns. Each baked bookie is reported to out, and one whose config
+// cannot be updated is skipped with a warning there.
+func BakeOverrides(outputDir, overridesPath string, out io.Writer) (string, error) {
+	overrideMap, err := config.LoadOverrides(overridesPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load overrides file: %w", err)
+	}
+
+	for bookieName, overridesForBookie := range overrideMap {
+		configPath := ConfigPath(outputDir, bookieName)
+
+		baseConfigData, err := os.ReadFile(configPath)
+		if err != nil {
+			fmt.Fprintf(out, "⚠️ Failed to read config for %s: %v\n", bookieName, err)
+			continue
+		}
+
+		var baseConfig config.Sportsbook
+		if err := yaml.Unmarshal(baseConfigData, &baseConfig); err != nil {
+			fmt.Fprintf(out, "⚠️ Failed to unmarshal config for %s: %v\n", bookieName, err)
+			continue
+		}
+
+		baseConfig.ApplyOverrides(overridesForBookie)
+
+		updatedConfigData, err := yaml.Marshal(baseConfig)
+		if err != nil {
+			fmt.Fprintf(out, "⚠️ Failed to marshal updated config for %s: %v\n", bookieName, err)
+			continue
+		}
+
+		if err := os.WriteFile(configPath, updatedConfigData, 0644); err != nil {
+			fmt.Fprintf(out, "⚠️ Failed to write updated config for %s: %v\n", bookieName, err)
+			continue
+		}
+
+		fmt.Fprintf(out, "✅ Baked overrides into config for %s\n", bookieName)
+	}
+
+	bakedPath := filepath.Join(outputDir, "overrides.baked.yaml")
+	if err := os.Rename(overridesPath, bakedPath); err != nil {
+		return "", fmt.Errorf("failed to rename overrides.yaml: %w", err)
+	}
+	return bakedPath, nil
+}
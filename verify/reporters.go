@@ -0,0 +1,58 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"diago/report"
+)
+
+// Reporter writes a run's report to a file in the output directory
+type Reporter struct {
+	// Name is the --format value selecting the reporter, and Title how
+	// progress output calls it
+	Name  string
+	Title string
+	File  string
+	Save  func(r report.FullReport, path string) error
+}
+
+// The built-in reporters
+var (
+	JSON     = Reporter{"json", "JSON", "report.json", report.SaveJSON}
+	Markdown = Reporter{"markdown", "Markdown", "report.md", report.SaveMarkdown}
+	JUnit    = Reporter{"junit", "JUnit", "junit.xml", report.SaveJUnit}
+	HTML     = Reporter{"html", "HTML", "report.html", report.SaveHTML}
+)
+
+// Reporters lists the built-in reporters, in the order they are written
+var Reporters = []Reporter{JSON, Markdown, JUnit, HTML}
+
+// ParseReporters resolves a comma-separated --format value
+func ParseReporters(value string) ([]Reporter, error) {
+	wanted := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "md" {
+			name = "markdown"
+		}
+		if name != "" {
+			wanted[name] = true
+		}
+	}
+
+	var out []Reporter
+	for _, r := range Reporters {
+		if wanted[r.Name] {
+			out = append(out, r)
+			delete(wanted, r.Name)
+		}
+	}
+	for name := range wanted {
+		return nil, fmt.Errorf("unknown report format %q (want json, markdown, junit or html)", name)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no report format selected")
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package verify
+
+import (
+	"math"
+	"strings"
+
+	"diago/api"
+	"diago/config"
+	"diago/fingerprint"
+	"diago/history"
+	"diago/metrics"
+	"diago/report"
+)
+
+// track records the page fingerprint and probe schemas of a live check.
+// Concurrent runs of a bookie share these files, so they are read and
+// written under saveMu.
+func (v *Verifier) track(r *report.BookieReport) {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	v.trackDrift(r)
+	v.trackSchemas(r)
+}
+
+// trackDrift stores the page fingerprint and flags the bookie when its
+// structure moved too far from the previous run, even if selectors pass
+func (v *Verifier) trackDrift(r *report.BookieReport) {
+	if r.Fingerprint == nil {
+		return
+	}
+
+	settings := v.settings.Drift
+	prev, similarity, err := fingerprint.Record(fingerprint.Path(v.outputDir, r.Key()), *r.Fingerprint, settings.Keep)
+	if err != nil {
+		v.logf("⚠️ Failed to store fingerprint for %s: %v\n", r.Key(), err)
+	}
+
+	r.Drift = &report.Drift{
+		Hash:       r.Fingerprint.Hash,
+		Similarity: math.Round(similarity*1000) / 1000,
+		Threshold:  settings.Threshold,
+	}
+	if prev != nil {
+		r.Drift.PreviousHash = prev.Hash
+		r.Drift.Alert = similarity < settings.Threshold
+	}
+	if r.Drift.Alert {
+		v.logf("📐 Layout drift for %s: similarity %.2f is below %.2f\n", r.Key(), similarity, settings.Threshold)
+	}
+}
+
+// trackSchemas stores the response schema of every API probe and records
+// the fields that appeared, disappeared or changed type since the last run
+func (v *Verifier) trackSchemas(r *report.BookieReport) {
+	for _, res := range r.Results {
+		if res.Schema == nil {
+			continue
+		}
+		probe := strings.TrimPrefix(res.Label, "API.")
+		prev, err := api.RecordSchema(api.SchemaPath(v.outputDir, r.Key(), probe), res.Schema)
+		if err != nil {
+			v.logf("⚠️ Failed to store schema of %s %s: %v\n", r.Key(), res.Label, err)
+		}
+		if prev == nil {
+			continue
+		}
+		if d := api.CompareSchemas(probe, prev, res.Schema); !d.IsZero() {
+			v.logf("🧬 Schema drift for %s %s: %d added, %d removed, %d changed\n", r.Key(), res.Label, len(d.Added), len(d.Removed), len(d.Changed))
+			r.SchemaDrift = append(r.SchemaDrift, d)
+		}
+	}
+}
+
+// recordSelectors stores the alternatives a bookie and each of its devices
+// were checked with, under the same keys as their reports
+func recordSelectors(selectors report.Selectors, cfg *config.Sportsbook) {
+	selectors[cfg.Name] = cfg.SelectorMap()
+	for _, device := range cfg.DeviceNames() {
+		if device == config.DefaultDevice {
+			continue
+		}
+		if v, err := cfg.Variant(device); err == nil {
+			selectors[cfg.Name+"@"+device] = v.SelectorMap()
+		}
+	}
+}
+
+// recordHistory appends the run to the history and prunes old runs
+func (v *Verifier) recordHistory(fullReport report.FullReport, selectors report.Selectors) {
+	path := history.Path(v.outputDir)
+	if err := history.Append(path, history.NewRun(fullReport, selectors)); err != nil {
+		v.logf("⚠️ Failed to record run history: %v\n", err)
+		return
+	}
+	pruned, err := history.Prune(path, v.settings.History.KeepRuns)
+	if err != nil {
+		v.logf("⚠️ Failed to prune run history: %v\n", err)
+	}
+	if pruned > 0 {
+		v.logf("🧹 Pruned %d old run(s) from history\n", pruned)
+	}
+}
+
+// writeMetricsTextfile writes the run's Prometheus metrics for node-exporter,
+// with the last success timestamps seeded from the history
+func (v *Verifier) writeMetricsTextfile(fullReport report.FullReport, runs []history.Run) {
+	settings := v.settings.Metrics
+	exporter := metrics.NewExporter(settings)
+	for _, past := range runs {
+		exporter.Observe(past.Report)
+	}
+	exporter.Observe(fullReport)
+	if err := exporter.WriteTextfile(settings.Textfile); err != nil {
+		v.logf("⚠️ Failed to write metrics: %v\n", err)
+		return
+	}
+	v.logf("📏 Saved metrics: %s\n", settings.Textfile)
+}
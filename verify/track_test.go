@@ -0,0 +1,39 @@
+package verify
+
+import (
+	"testing"
+
+	"diago/config"
+	"diago/fingerprint"
+	"diago/report"
+)
+
+func TestTrackDriftAlertsBelowThreshold(t *testing.T) {
+	settings := config.DefaultSettings()
+	settings.Drift.Threshold = 0.8
+	v := New(WithOutputDir(t.TempDir()), WithSettings(settings))
+
+	page := fingerprint.Fingerprint{Hash: "a", Paths: map[string]int{"body>main>ul>li": 8, "body>main>form": 2}}
+	grown := fingerprint.Fingerprint{Hash: "b", Paths: map[string]int{"body>main>ul>li": 10, "body>main>form": 2}}
+	moved := fingerprint.Fingerprint{Hash: "c", Paths: map[string]int{"body>div>div": 10, "body>main>form": 2}}
+
+	steps := []struct {
+		fp         fingerprint.Fingerprint
+		previous   string
+		similarity float64
+		alert      bool
+	}{
+		{page, "", 1, false},
+		{page, "a", 1, false},
+		{grown, "a", 0.833, false},
+		{moved, "b", 0.091, true},
+	}
+	for i, s := range steps {
+		r := report.BookieReport{Name: "Betway", Fingerprint: &s.fp}
+		v.trackDrift(&r)
+		d := r.Drift
+		if d == nil || d.Hash != s.fp.Hash || d.PreviousHash != s.previous || d.Similarity != s.similarity || d.Alert != s.alert || d.Threshold != 0.8 {
+			t.Errorf("step %d: drift = %+v, want previous %q, similarity %.3f, alert %v", i, d, s.previous, s.similarity, s.alert)
+		}
+	}
+}
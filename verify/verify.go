@@ -0,0 +1,417 @@
+// Package verify checks bookies against their generated configs and writes
+// the reports, history, snapshots and metrics of each run. It is what the
+// diago CLI runs, for embedding in other Go services:
+//
+//	v := verify.New(verify.WithOutputDir("EMC"), verify.WithConcurrency(4))
+//	fullReport, err := v.Run(ctx, []string{"Betway", "Bet365"})
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"diago/config"
+	"diago/fetch"
+	"diago/history"
+	"diago/report"
+	"diago/snapshot"
+)
+
+// Renderer loads a live page, e.g. through a headless browser, in place of
+// a plain GET
+type Renderer func(ctx context.Context, url string, header http.Header) (*fetch.Page, error)
+
+// Hooks are called as a run progresses. With a concurrency above one they
+// are called from several goroutines at once. Bookies are named by their
+// report key, e.g. betway@mobile.
+type Hooks struct {
+	OnBookieStart    func(bookie string)
+	OnSelectorResult func(bookie string, r report.SelectorResult)
+	OnBookieDone     func(r report.BookieReport)
+}
+
+// Verifier runs verifications. Its zero value is not usable; create one
+// with New.
+type Verifier struct {
+	outputDir   string
+	settings    config.Settings
+	client      *http.Client
+	render      Renderer
+	concurrency int
+	reporters   []Reporter
+	hooks       Hooks
+	suggest     bool
+	offline     bool
+	snapshotID  string
+	diffAgainst string
+	partial     bool
+	out         io.Writer
+}
+
+// Option configures a Verifier
+type Option func(*Verifier)
+
+// New returns a Verifier writing JSON and Markdown reports to EMC with the
+// default settings, one bookie at a time and silently, unless options say
+// otherwise
+func New(opts ...Option) *Verifier {
+	v := &Verifier{
+		outputDir:   "EMC",
+		settings:    config.DefaultSettings(),
+		concurrency: 1,
+		reporters:   []Reporter{JSON, Markdown},
+		out:         io.Discard,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// WithOutputDir sets the directory holding the configs, reports and history
+func WithOutputDir(dir string) Option {
+	return func(v *Verifier) { v.outputDir = dir }
+}
+
+// WithSettings sets the run-wide settings, as loaded from diago.yaml
+func WithSettings(s config.Settings) Option {
+	return func(v *Verifier) { v.settings = s }
+}
+
+// WithClient sends the page, frame and probe requests through client
+func WithClient(client *http.Client) Option {
+	return func(v *Verifier) { v.client = client }
+}
+
+// WithRenderer loads live pages and frames through r
+func WithRenderer(r Renderer) Option {
+	return func(v *Verifier) { v.render = r }
+}
+
+// WithOutput writes progress and warnings to w, as the CLI does to stdout
+func WithOutput(w io.Writer) Option {
+	return func(v *Verifier) {
+		if w != nil {
+			v.out = w
+		}
+	}
+}
+
+// WithConcurrency verifies up to n bookies at the same time. Reports keep
+// the order the bookies were given in.
+func WithConcurrency(n int) Option {
+	return func(v *Verifier) {
+		if n > 0 {
+			v.concurrency = n
+		}
+	}
+}
+
+// WithReporters sets the report files written after each run; none writes no files
+func WithReporters(reporters ...Reporter) Option {
+	return func(v *Verifier) { v.reporters = reporters }
+}
+
+// WithHooks sets every progress hook at once
+func WithHooks(h Hooks) Option {
+	return func(v *Verifier) { v.hooks = h }
+}
+
+// OnBookieStart is called when a bookie, or one of its devices, is fetched
+func OnBookieStart(fn func(bookie string)) Option {
+	return func(v *Verifier) { v.hooks.OnBookieStart = fn }
+}
+
+// OnSelectorResult is called with every selector and probe result
+func OnSelectorResult(fn func(bookie string, r report.SelectorResult)) Option {
+	return func(v *Verifier) { v.hooks.OnSelectorResult = fn }
+}
+
+// OnBookieDone is called when a bookie, or one of its devices, is verified
+func OnBookieDone(fn func(r report.BookieReport)) Option {
+	return func(v *Verifier) { v.hooks.OnBookieDone = fn }
+}
+
+// WithSuggestions searches fetched pages for replacement candidates of failing selectors
+func WithSuggestions(enabled bool) Option {
+	return func(v *Verifier) { v.suggest = enabled }
+}
+
+// Offline verifies against a stored snapshot run instead of live pages; an
+// empty run id replays the latest one
+func Offline(runID string) Option {
+	return func(v *Verifier) { v.offline, v.snapshotID = true, runID }
+}
+
+// WithDiffAgainst diffs runs against this run id from the history instead of the previous run
+func WithDiffAgainst(runID string) Option {
+	return func(v *Verifier) { v.diffAgainst = runID }
+}
+
+// Partial marks runs as covering only some bookies, e.g. a scheduled
+// cycle. They are diffed against the last check of each of their bookies.
+func Partial(partial bool) Option {
+	return func(v *Verifier) { v.partial = partial }
+}
+
+// logf writes progress to the output
+func (v *Verifier) logf(format string, args ...any) {
+	fmt.Fprintf(v.out, format, args...)
+}
+
+// OutputDir is where the verifier reads configs and writes reports
+func (v *Verifier) OutputDir() string {
+	return v.outputDir
+}
+
+// Result is one update of a streamed run. Every verified bookie is sent as
+// Bookie; the last result carries the saved Report, or the Err that ended the run.
+type Result struct {
+	Bookie *report.BookieReport
+	Report *report.FullReport
+	Err    error
+}
+
+// Stream runs in the background, sending each bookie as soon as it is
+// verified. The channel is closed when the run is done.
+func (v *Verifier) Stream(ctx context.Context, bookies []string) <-chan Result {
+	results := make(chan Result, len(bookies)+1)
+	go func() {
+		defer close(results)
+		fullReport, err := v.run(ctx, bookies, func(r report.BookieReport) {
+			results <- Result{Bookie: &r}
+		})
+		if err != nil {
+			results <- Result{Err: err}
+			return
+		}
+		results <- Result{Report: &fullReport}
+	}()
+	return results
+}
+
+// Run verifies the bookies, by manifest name, and saves the run: reports,
+// diff, history, snapshots and the metrics textfile as the settings ask.
+// Bookies without a config are skipped with a warning. A cancelled ctx
+// stops the run without saving it.
+func (v *Verifier) Run(ctx context.Context, bookies []string) (report.FullReport, error) {
+	return v.run(ctx, bookies, nil)
+}
+
+func (v *Verifier) run(ctx context.Context, bookies []string, onReport func(report.BookieReport)) (report.FullReport, error) {
+	opts := fetch.Options{
+		Suggest:       v.suggest,
+		Context:       ctx,
+		Client:        v.client,
+		Render:        v.render,
+		Output:        v.out,
+		OnBookieStart: v.hooks.OnBookieStart,
+		OnResult:      v.hooks.OnSelectorResult,
+		OnBookieDone:  v.hooks.OnBookieDone,
+	}
+	runID, err := v.setupSnapshots(&opts)
+	if err != nil {
+		return report.FullReport{}, err
+	}
+
+	// Check the diff base before fetching anything
+	if v.diffAgainst != "" {
+		runs, _ := history.Load(history.Path(v.outputDir))
+		if _, err := diffBase(runs, v.diffAgainst); err != nil {
+			return report.FullReport{}, err
+		}
+	}
+
+	v.logf("🌐 Fetching and verifying bookies...\n")
+	startedAt := time.Now()
+	selectors := report.Selectors{}
+	verified := v.verifyAll(ctx, bookies, opts, selectors, onReport)
+	if err := ctx.Err(); err != nil {
+		return report.FullReport{}, err
+	}
+
+	fullReport := report.FullReport{
+		RunID:     runID,
+		Offline:   v.offline,
+		Partial:   v.partial,
+		StartedAt: startedAt.Format(time.RFC3339),
+		Summary:   verified,
+		Details:   verified,
+	}
+	err = v.save(&fullReport, bookies, selectors)
+	return fullReport, err
+}
+
+// saveMu serialises saving runs, so runs that finish together, e.g. through
+// the API, neither interleave their files nor miss each other in the
+// history they are diffed and annotated against
+var saveMu sync.Mutex
+
+// save diffs and annotates a run against the history, then writes its
+// reports, history entry and metrics
+func (v *Verifier) save(fullReport *report.FullReport, bookies []string, selectors report.Selectors) error {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	settings := v.settings
+	if !v.offline && !settings.Snapshots.Disabled {
+		maxAge := time.Duration(settings.Snapshots.MaxAgeDays) * 24 * time.Hour
+		pruned, err := snapshot.Prune(snapshot.Dir(v.outputDir), settings.Snapshots.KeepRuns, maxAge)
+		if err != nil {
+			v.logf("⚠️ Failed to prune snapshots: %v\n", err)
+		}
+		if len(pruned) > 0 {
+			v.logf("🧹 Pruned %d old snapshot run(s)\n", len(pruned))
+		}
+	}
+
+	runs, err := history.Load(history.Path(v.outputDir))
+	if err != nil {
+		v.logf("⚠️ Failed to load run history: %v\n", err)
+	}
+	var base *history.Run
+	if v.partial && v.diffAgainst == "" {
+		base = history.LatestOf(runs, bookies)
+	} else if base, err = diffBase(runs, v.diffAgainst); err != nil {
+		return err
+	}
+
+	history.Annotate(fullReport, runs, history.Window{
+		Runs:        settings.Flaky.Window,
+		MinFlips:    settings.Flaky.MinFlips,
+		BrokenAfter: settings.Flaky.BrokenAfter,
+	})
+
+	if base != nil {
+		d := report.Compare(base.Report, *fullReport, base.Selectors, selectors)
+		fullReport.Diff = &d
+		v.logf("🔀 Since run %s: %d regression(s), %d fix(es), %d unreachable\n", d.BaseRunID, len(d.Regressions), len(d.Fixes), len(d.Unreachable))
+		if err := report.SaveDiff(d, v.path("diff.json")); err != nil {
+			return fmt.Errorf("failed to save diff: %w", err)
+		}
+		v.logf("📄 Saved diff: %s\n", v.path("diff.json"))
+	}
+
+	for _, r := range v.reporters {
+		if err := r.Save(*fullReport, v.path(r.File)); err != nil {
+			return fmt.Errorf("failed to save %s report: %w", r.Name, err)
+		}
+		v.logf("📄 Saved %s report: %s\n", r.Title, v.path(r.File))
+	}
+
+	// Offline replays re-check old pages, so they would skew the trends
+	if !v.offline && !settings.History.Disabled {
+		v.recordHistory(*fullReport, selectors)
+	}
+	if !v.offline && settings.Metrics.Textfile != "" {
+		v.writeMetricsTextfile(*fullReport, runs)
+	}
+	return nil
+}
+
+// verifyAll verifies the bookies up to the concurrency limit, returning
+// their reports in the given order. No new bookie starts once ctx is done.
+func (v *Verifier) verifyAll(ctx context.Context, bookies []string, opts fetch.Options, selectors report.Selectors, onReport func(report.BookieReport)) []report.BookieReport {
+	reports := make([]*report.BookieReport, len(bookies))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, v.concurrency)
+
+	for i, name := range bookies {
+		cfg, err := LoadConfig(v.outputDir, name)
+		if err != nil {
+			v.logf("⚠️ Skipping %s, failed to load config: %v\n", name, err)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case slots <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			r := fetch.VerifyBookieVariants(cfg.Name, cfg, opts)
+			if !v.offline {
+				v.track(&r)
+				for j := range r.Variants {
+					v.track(&r.Variants[j])
+				}
+			}
+
+			mu.Lock()
+			recordSelectors(selectors, cfg)
+			reports[i] = &r
+			if onReport != nil {
+				onReport(r)
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	var out []report.BookieReport
+	for _, r := range reports {
+		if r != nil {
+			out = append(out, *r)
+		}
+	}
+	return out
+}
+
+// setupSnapshots makes fetch replay a stored run when offline, or store
+// every fetched page under a new run otherwise, and returns the run id
+func (v *Verifier) setupSnapshots(opts *fetch.Options) (string, error) {
+	dir := snapshot.Dir(v.outputDir)
+
+	if v.offline {
+		runID := v.snapshotID
+		if runID == "" {
+			latest, err := snapshot.Latest(dir)
+			if err != nil {
+				return "", fmt.Errorf("cannot run offline: %w", err)
+			}
+			runID = latest
+		}
+		v.logf("📼 Replaying snapshot run %s\n", runID)
+		opts.Source = func(bookie, _ string) (*fetch.Page, error) {
+			return snapshot.Load(dir, runID, bookie)
+		}
+		return runID, nil
+	}
+
+	if v.settings.Snapshots.Disabled {
+		return "", nil
+	}
+	runID := snapshot.NewRunID()
+	opts.OnPage = func(bookie string, page *fetch.Page) {
+		if err := snapshot.Save(dir, runID, bookie, page); err != nil {
+			v.logf("⚠️ Failed to store snapshot for %s: %v\n", bookie, err)
+		}
+	}
+	return runID, nil
+}
+
+// diffBase picks the run to diff against: the given run id, or the latest
+// run when none is given
+func diffBase(runs []history.Run, runID string) (*history.Run, error) {
+	if runID == "" {
+		if len(runs) == 0 {
+			return nil, nil
+		}
+		return &runs[len(runs)-1], nil
+	}
+	run, ok := history.Find(runs, runID)
+	if !ok {
+		return nil, fmt.Errorf("run %s is not in the history", runID)
+	}
+	return &run, nil
+}
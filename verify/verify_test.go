@@ -0,0 +1,225 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"diago/config"
+	"diago/fingerprint"
+	"diago/history"
+	"diago/report"
+	"diago/snapshot"
+)
+
+// writeConfig stores a bookie config checking one input on baseURL
+func writeConfig(t *testing.T, outputDir, name, baseURL string) {
+	t.Helper()
+	path := ConfigPath(outputDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := fmt.Sprintf("name: %s\nbase_url: %s\nselectors:\n  login:\n    username_input: \"#user\"\n", name, baseURL)
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Runs started together, as the API allows, each get their own run id,
+// snapshot dir and history entry
+func TestConcurrentRunsSaveSeparately(t *testing.T) {
+	// Hold every page until both runs are fetching, so they finish together
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		arrived.Done()
+		arrived.Wait()
+		fmt.Fprint(w, `<input id="user">`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	bookies := []string{"Alpha", "Beta"}
+	for _, name := range bookies {
+		writeConfig(t, dir, name, srv.URL)
+	}
+
+	reports := make([]report.FullReport, len(bookies))
+	errs := make([]error, len(bookies))
+	var wg sync.WaitGroup
+	for i, name := range bookies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := New(WithOutputDir(dir), WithClient(srv.Client()), Partial(true))
+			reports[i], errs[i] = v.Run(context.Background(), []string{name})
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("run %s: %v", bookies[i], err)
+		}
+	}
+	if reports[0].RunID == reports[1].RunID {
+		t.Fatalf("both runs got id %s", reports[0].RunID)
+	}
+
+	runs, err := history.Load(history.Path(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("history has %d runs, want 2", len(runs))
+	}
+	for i, r := range reports {
+		run, ok := history.Find(runs, r.RunID)
+		if !ok || len(run.Report.Details) != 1 || run.Report.Details[0].Name != bookies[i] {
+			t.Errorf("history entry of %s = %+v", r.RunID, run.Report.Details)
+		}
+		if _, err := snapshot.Load(snapshot.Dir(dir), r.RunID, bookies[i]); err != nil {
+			t.Errorf("snapshot of %s: %v", bookies[i], err)
+		}
+	}
+
+	// report.json is one of the two runs, whole
+	data, err := os.ReadFile(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved report.FullReport
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("report.json: %v", err)
+	}
+	if saved.RunID != reports[0].RunID && saved.RunID != reports[1].RunID {
+		t.Errorf("report.json is of run %s", saved.RunID)
+	}
+}
+
+// Concurrent runs of one bookie each add their fingerprint to its history
+func TestConcurrentRunsKeepEveryFingerprint(t *testing.T) {
+	const n = 6
+	var arrived sync.WaitGroup
+	arrived.Add(n)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		arrived.Done()
+		arrived.Wait()
+		fmt.Fprint(w, `<input id="user">`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeConfig(t, dir, "Alpha", srv.URL)
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := New(WithOutputDir(dir), WithClient(srv.Client()), WithSettings(config.DefaultSettings()), Partial(true))
+			if _, err := v.Run(context.Background(), []string{"Alpha"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(fingerprint.Path(dir, "Alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store fingerprint.Store
+	if err := json.Unmarshal(data, &store); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Runs) != n {
+		t.Errorf("fingerprint history has %d runs, want %d", len(store.Runs), n)
+	}
+}
+
+// A run saved after another sees it in the history it is diffed against
+func TestRunDiffsAgainstPreviousRun(t *testing.T) {
+	page := `<input id="user">`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeConfig(t, dir, "Alpha", srv.URL)
+	v := New(WithOutputDir(dir), WithClient(srv.Client()), WithSettings(config.DefaultSettings()))
+
+	first, err := v.Run(context.Background(), []string{"Alpha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page = `<p>gone</p>`
+	second, err := v.Run(context.Background(), []string{"Alpha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Diff == nil || second.Diff.BaseRunID != first.RunID || len(second.Diff.Regressions) != 1 {
+		t.Errorf("diff = %+v, want one regression since %s", second.Diff, first.RunID)
+	}
+}
+
+// captureStdout returns what fn printed to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	printed := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		printed <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-printed
+}
+
+func TestRunIsSilentByDefault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<input id="user">`)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	writeConfig(t, dir, "Alpha", srv.URL)
+
+	// The missing config is warned about
+	bookies := []string{"Alpha", "Missing"}
+	printed := captureStdout(t, func() {
+		if _, err := New(WithOutputDir(dir), WithClient(srv.Client())).Run(context.Background(), bookies); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if printed != "" {
+		t.Errorf("printed to stdout:\n%s", printed)
+	}
+
+	var out bytes.Buffer
+	if _, err := New(WithOutputDir(dir), WithClient(srv.Client()), WithOutput(&out)).Run(context.Background(), bookies); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"🔍 Checking Alpha", "⚠️ Skipping Missing", "📄 Saved JSON report"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}